@@ -0,0 +1,287 @@
+package mongostore
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListSort is the order of ListSessions.
+type ListSort int
+
+const (
+	// ListNewest lists the newest sessions first, the default.
+	ListNewest ListSort = iota
+
+	// ListOldest lists the oldest sessions first.
+	ListOldest
+)
+
+// ListOptions selects the sessions returned by ListSessions.
+type ListOptions struct {
+	// UserID only lists the sessions of a user.
+	UserID string
+
+	// Skip and Limit page through the sessions. A zero Limit means no
+	// limit. Skipping scans the skipped sessions, use After to page
+	// through large collections.
+	Skip  int64
+	Limit int64
+
+	// After lists the sessions following the one of the page token, as
+	// returned by PageToken for the last session of the previous page.
+	After string
+
+	// Sort is the order of the sessions, by creation time.
+	Sort ListSort
+}
+
+// ListSessions returns the sessions of the collection, newest first unless
+// opts.Sort is set. It returns ErrInvalidPageToken if opts.After was not
+// returned by PageToken.
+func (s *Store) ListSessions(ctx context.Context, opts ListOptions) ([]*MongoSession, error) {
+	filter := bson.M{}
+	if opts.UserID != "" {
+		filter["user_id"] = opts.UserID
+	}
+
+	order, after := -1, "$lt"
+	if opts.Sort == ListOldest {
+		order, after = 1, "$gt"
+	}
+
+	// the sessions following the last one of the previous page, an index
+	// range instead of scanning the skipped sessions
+	if opts.After != "" {
+		last, err := ParsePageToken(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		filter["$or"] = bson.A{
+			bson.M{"created_at": bson.M{after: last.Created}},
+			bson.M{"created_at": last.Created, "_id": bson.M{after: last.ID}},
+		}
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: order}, {Key: "_id", Value: order}}).
+		SetSkip(opts.Skip)
+	if opts.Limit > 0 {
+		findOptions.SetLimit(opts.Limit)
+	}
+
+	cursor, err := s.readCollection().Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: listing sessions: %w", err)
+	}
+
+	var mongoSessions []*MongoSession
+	err = cursor.All(ctx, &mongoSessions)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: decoding sessions: %w", err)
+	}
+
+	return mongoSessions, nil
+}
+
+// pageToken is the position of a session in the listing, encoded in the
+// page tokens.
+type pageToken struct {
+	Created primitive.DateTime `bson:"c"`
+	ID      interface{}        `bson:"i"`
+}
+
+// PageToken returns the page token of a session, to list the sessions
+// following it with ListOptions.After.
+func PageToken(last *MongoSession) string {
+	b, err := bson.Marshal(pageToken{Created: last.Created, ID: last.ID})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParsePageToken returns the position encoded in a page token, the session
+// has only its ID and Created set. It returns ErrInvalidPageToken if the
+// token was not returned by PageToken.
+func ParsePageToken(token string) (*MongoSession, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, wrapError(ErrInvalidPageToken, err)
+	}
+
+	var t pageToken
+	err = bson.Unmarshal(b, &t)
+	if err != nil {
+		return nil, wrapError(ErrInvalidPageToken, err)
+	}
+	if t.ID == nil {
+		return nil, ErrInvalidPageToken
+	}
+
+	return &MongoSession{ID: t.ID, Created: t.Created}, nil
+}
+
+// idFilter returns the filter of the session with the given id, as stored in
+// the cookie.
+func (s *Store) idFilter(id string) (bson.M, error) {
+	if s.MongoStore.OpaqueTokens {
+		return bson.M{"token_hash": hashToken(id)}, nil
+	}
+
+	documentID, err := s.documentID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.M{"_id": documentID}, nil
+}
+
+// FindSession returns the session with the given id, as stored in the
+// cookie. It returns ErrSessionNotFound if there is no such session.
+func (s *Store) FindSession(ctx context.Context, id string) (*MongoSession, error) {
+	filter, err := s.idFilter(id)
+	if err != nil {
+		return nil, wrapError(ErrSessionNotFound, err)
+	}
+
+	mongoSession := &MongoSession{}
+	err = s.readCollection().FindOne(ctx, filter).Decode(mongoSession)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, wrapError(ErrSessionNotFound, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: finding session: %w", err)
+	}
+
+	return mongoSession, nil
+}
+
+// DeleteSession deletes the session with the given id, as stored in the
+// cookie. It returns ErrSessionNotFound if there is no such session.
+func (s *Store) DeleteSession(ctx context.Context, id string) error {
+	filter, err := s.idFilter(id)
+	if err != nil {
+		return wrapError(ErrSessionNotFound, err)
+	}
+
+	s.archive(ctx, filter)
+	res, err := s.deleteCollection().DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("mongostore: deleting session: %w", err)
+	}
+
+	s.replicate(func(col *mongo.Collection) error {
+		_, err := col.DeleteOne(ctx, filter)
+		return err
+	})
+
+	if res.DeletedCount == 0 {
+		return wrapError(ErrSessionNotFound, mongo.ErrNoDocuments)
+	}
+
+	return nil
+}
+
+// PurgeExpired deletes the sessions past their expiry that the time to live
+// index did not remove yet, and returns how many were deleted.
+func (s *Store) PurgeExpired(ctx context.Context) (int64, error) {
+	filter := bson.M{
+		"expires_at": bson.M{"$lt": primitive.NewDateTimeFromTime(s.now())},
+	}
+
+	s.archive(ctx, filter)
+	res, err := s.deleteCollection().DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("mongostore: purging sessions: %w", err)
+	}
+
+	s.replicate(func(col *mongo.Collection) error {
+		_, err := col.DeleteMany(ctx, filter)
+		return err
+	})
+
+	return res.DeletedCount, nil
+}
+
+// CountSessions returns the number of sessions in the collection, leaving
+// out the sessions tombstoned with Options.SoftDelete.
+func (s *Store) CountSessions(ctx context.Context) (int64, error) {
+	count, err := s.readCollection().CountDocuments(ctx, live(bson.M{}))
+	if err != nil {
+		return 0, fmt.Errorf("mongostore: counting sessions: %w", err)
+	}
+
+	return count, nil
+}
+
+// DecodeCookie returns the session id held by a cookie value sent by a
+// client, to look the session up with FindSession. It returns
+// ErrCookieDecode if the value was not encoded with the keys of the store.
+func (s *Store) DecodeCookie(name string, value string) (string, error) {
+	id, _, err := s.decodeID(name, value)
+	if err != nil {
+		return "", wrapError(ErrCookieDecode, err)
+	}
+
+	return id, nil
+}
+
+// DeleteUserSessions deletes every session of a user, and returns how many
+// were deleted.
+func (s *Store) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	result, err := s.eraseUser(ctx, userID, EraseDelete)
+	return result.Deleted, err
+}
+
+// Stats describes the sessions of the store and its state.
+type Stats struct {
+	// Sessions is the number of live sessions in the collection, Owned the
+	// number with an owner, Persistent the number of "remember me" sessions
+	// and Expired the number past their expiry not removed yet.
+	Sessions   int64 `json:"sessions"`
+	Owned      int64 `json:"owned"`
+	Persistent int64 `json:"persistent"`
+	Expired    int64 `json:"expired"`
+
+	// Queued is the number of sessions saved to the fallback while mongo
+	// was unavailable, BreakerOpen reports if the circuit breaker is open.
+	Queued      int  `json:"queued"`
+	BreakerOpen bool `json:"breaker_open"`
+}
+
+// Stats counts the sessions of the collection.
+func (s *Store) Stats(ctx context.Context) (Stats, error) {
+	var stats Stats
+
+	counts := []struct {
+		count  *int64
+		filter bson.M
+	}{
+		{&stats.Sessions, bson.M{}},
+		{&stats.Owned, bson.M{"user_id": bson.M{"$exists": true}}},
+		{&stats.Persistent, bson.M{"persistent": true}},
+		{&stats.Expired, bson.M{"expires_at": bson.M{"$lt": primitive.NewDateTimeFromTime(s.now())}}},
+	}
+	for _, c := range counts {
+		count, err := s.readCollection().CountDocuments(ctx, live(c.filter))
+		if err != nil {
+			return stats, fmt.Errorf("mongostore: counting sessions: %w", err)
+		}
+		*c.count = count
+	}
+
+	s.fallbackMu.Lock()
+	stats.Queued = len(s.fallbackPending)
+	s.fallbackMu.Unlock()
+
+	stats.BreakerOpen = s.breaker.open()
+
+	return stats, nil
+}
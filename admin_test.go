@@ -0,0 +1,123 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestAdminOperations(t *testing.T) {
+	store := newTestStore(t, "sessions_admin_test")
+
+	for i := 0; i < 3; i++ {
+		err := login(t, store, "admin-user")
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	count, err := store.CountSessions(context.TODO())
+	if err != nil || count != 4 {
+		t.Fatalf("expected 4 sessions, got %d %v", count, err)
+	}
+
+	mongoSessions, err := store.ListSessions(context.TODO(), mongostore.ListOptions{UserID: "admin-user", Limit: 2})
+	if err != nil || len(mongoSessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d %v", len(mongoSessions), err)
+	}
+
+	// find the session of a captured cookie
+	value := strings.TrimPrefix(strings.Split(res.Header().Get("Set-Cookie"), ";")[0], "test-session=")
+	id, err := store.DecodeCookie("test-session", value)
+	if err != nil || id != session.ID {
+		t.Fatalf("expected %s, got %s %v", session.ID, id, err)
+	}
+
+	_, err = store.FindSession(context.TODO(), id)
+	if err != nil {
+		t.Fatalf("failed to find session: %v\n", err)
+	}
+
+	err = store.DeleteSession(context.TODO(), id)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v\n", err)
+	}
+	_, err = store.FindSession(context.TODO(), id)
+	if !errors.Is(err, mongostore.ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+
+	// purge the sessions expired before the TTL monitor ran
+	_, err = store.MongoStore.Collection.UpdateMany(
+		context.TODO(),
+		bson.M{},
+		bson.M{"$set": bson.M{"expires_at": primitive.NewDateTimeFromTime(time.Now().Add(-time.Minute))}},
+	)
+	if err != nil {
+		t.Fatalf("failed to expire sessions: %v\n", err)
+	}
+	purged, err := store.PurgeExpired(context.TODO())
+	if err != nil || purged != 3 {
+		t.Fatalf("expected 3 purged sessions, got %d %v", purged, err)
+	}
+}
+
+func TestListSessionsPages(t *testing.T) {
+	store := newTestStore(t, "sessions_pages_test")
+
+	for i := 0; i < 5; i++ {
+		err := login(t, store, "paged-user")
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	for _, sort := range []mongostore.ListSort{mongostore.ListNewest, mongostore.ListOldest} {
+		seen := make(map[interface{}]bool)
+		opts := mongostore.ListOptions{Limit: 2, Sort: sort}
+		for page := 0; ; page++ {
+			mongoSessions, err := store.ListSessions(context.TODO(), opts)
+			if err != nil {
+				t.Fatalf("failed to list sessions: %v\n", err)
+			}
+			for _, mongoSession := range mongoSessions {
+				if seen[mongoSession.ID] {
+					t.Fatalf("session %v listed twice", mongoSession.ID)
+				}
+				seen[mongoSession.ID] = true
+			}
+			if len(mongoSessions) < 2 {
+				break
+			}
+			opts.After = mongostore.PageToken(mongoSessions[len(mongoSessions)-1])
+		}
+		if len(seen) != 5 {
+			t.Fatalf("expected 5 sessions, got %d", len(seen))
+		}
+	}
+
+	_, err := store.ListSessions(context.TODO(), mongostore.ListOptions{After: "not a token"})
+	if !errors.Is(err, mongostore.ErrInvalidPageToken) {
+		t.Fatalf("expected ErrInvalidPageToken, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultArchiveTTL is how long archived sessions are kept when
+// Options.ArchiveTTL is zero.
+const defaultArchiveTTL = 365 * 24 * time.Hour
+
+// ArchivedSession is the record of a deleted session in
+// Options.ArchiveCollection, without its data.
+type ArchivedSession struct {
+	ID         interface{}        `bson:"_id"`
+	UserID     string             `bson:"user_id,omitempty"`
+	TenantID   string             `bson:"tenant_id,omitempty"`
+	Persistent bool               `bson:"persistent"`
+	Created    primitive.DateTime `bson:"created_at,omitempty"`
+	Modified   primitive.DateTime `bson:"modified_at,omitempty"`
+	LastSeen   primitive.DateTime `bson:"last_seen,omitempty"`
+	Expires    primitive.DateTime `bson:"expires_at,omitempty"`
+	Deleted    primitive.DateTime `bson:"deleted_at,omitempty"`
+
+	// Archived is when the session was copied to the archive, the archive
+	// removes it Options.ArchiveTTL later
+	Archived primitive.DateTime `bson:"archived_at"`
+}
+
+// archivedFields are the fields of a session copied to the archive.
+var archivedFields = bson.M{
+	"user_id":     1,
+	"tenant_id":   1,
+	"persistent":  1,
+	"created_at":  1,
+	"modified_at": 1,
+	"last_seen":   1,
+	"expires_at":  1,
+	"deleted_at":  1,
+}
+
+// archive copies the sessions matching the filter to
+// Options.ArchiveCollection before they are deleted, in a single $merge run
+// by the server. Archiving is best effort, a failure is logged and the
+// sessions are deleted anyway.
+func (s *Store) archive(ctx context.Context, filter interface{}) {
+	archive := s.MongoStore.ArchiveCollection
+	if archive == nil {
+		return
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$project", Value: archivedFields}},
+		{{Key: "$addFields", Value: bson.M{"archived_at": primitive.NewDateTimeFromTime(s.now())}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           bson.M{"db": archive.Database().Name(), "coll": archive.Name()},
+			"on":             "_id",
+			"whenMatched":    "replace",
+			"whenNotMatched": "insert",
+		}}},
+	}
+
+	cursor, err := s.MongoStore.Collection.Aggregate(ctx, pipeline)
+	if err == nil {
+		err = cursor.Close(ctx)
+	}
+	if err != nil {
+		s.log(ctx, slog.LevelWarn, "archiving sessions", slog.String("collection", archive.Name()), errorAttr(err))
+	}
+}
+
+// archiveExpiring archives the sessions the time to live index removes
+// before the next run of the janitor.
+func (s *Store) archiveExpiring(ctx context.Context, interval time.Duration) {
+	// the time of the last write kept by Cosmos DB is not a date, the
+	// janitor purges the expired sessions there
+	if s.ttlField() == cosmosTTLField || !s.capabilities.TTLIndexes {
+		return
+	}
+
+	// the index removes a session expireAfterSeconds after its ttl field
+	removedBy := s.now().Add(2*interval - time.Duration(s.indexExpireAfterSeconds())*time.Second)
+	s.archive(ctx, bson.M{s.ttlField(): bson.M{"$lt": primitive.NewDateTimeFromTime(removedBy)}})
+}
+
+// archiveTTL returns how long archived sessions are kept.
+func (s *Store) archiveTTL() time.Duration {
+	if s.MongoStore.ArchiveTTL > 0 {
+		return s.MongoStore.ArchiveTTL
+	}
+	return defaultArchiveTTL
+}
+
+// insertArchiveIndex adds the time to live index of the archive, or updates
+// its expireAfterSeconds when Options.ArchiveTTL changed.
+func (s *Store) insertArchiveIndex(ctx context.Context) error {
+	archive := s.MongoStore.ArchiveCollection
+	seconds := int32(s.archiveTTL() / time.Second)
+
+	found, expireAfterSeconds, err := findTTLIndex(ctx, archive, "archived_at")
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		_, err = archive.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "archived_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(seconds),
+		})
+		return err
+	}
+
+	if expireAfterSeconds != int64(seconds) {
+		return archive.Database().RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: archive.Name()},
+			{Key: "index", Value: bson.D{
+				{Key: "keyPattern", Value: bson.D{{Key: "archived_at", Value: 1}}},
+				{Key: "expireAfterSeconds", Value: seconds},
+			}},
+		}).Err()
+	}
+
+	return nil
+}
+
+// eraseArchived deletes the archived sessions of a user, erased with the
+// sessions.
+func (s *Store) eraseArchived(ctx context.Context, userID string) error {
+	if s.MongoStore.ArchiveCollection == nil {
+		return nil
+	}
+
+	_, err := s.MongoStore.ArchiveCollection.DeleteMany(ctx, bson.M{"user_id": userID})
+	if err != nil {
+		return fmt.Errorf("mongostore: deleting archived sessions of %s: %w", userID, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestArchiveCollection(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_archival_test")
+	archive := mongoclient.Database("test-database").Collection("sessions_archival_test_archive")
+	for _, c := range []interface{ Drop(context.Context) error }{col, archive} {
+		err := c.Drop(context.TODO())
+		if err != nil {
+			t.Fatalf("failed to drop test collection: %v\n", err)
+		}
+	}
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:        col,
+			ArchiveCollection: archive,
+			ArchiveTTL:        30 * 24 * time.Hour,
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+	defer store.Close(context.TODO())
+
+	if seconds := ttlExpireAfterSeconds(t, archive); seconds != 30*24*3600 {
+		t.Fatalf("expected the archive to keep sessions 30 days, got %d seconds", seconds)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(session, "archived-user")
+	session.Values["secret"] = "not archived"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	session.Options.MaxAge = -1
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v\n", err)
+	}
+
+	var archived bson.M
+	err = archive.FindOne(context.TODO(), bson.M{}).Decode(&archived)
+	if err != nil {
+		t.Fatalf("failed to find archived session: %v\n", err)
+	}
+	if archived["user_id"] != "archived-user" || archived["created_at"] == nil || archived["archived_at"] == nil {
+		t.Fatalf("expected the owner and times to be archived, got %v", archived)
+	}
+	if _, ok := archived["data"]; ok {
+		t.Fatal("expected the data not to be archived")
+	}
+
+	// erasing the user erases the archive
+	_, err = store.EraseUser(context.TODO(), "archived-user")
+	if err != nil {
+		t.Fatalf("failed to erase user: %v\n", err)
+	}
+	count, err := archive.CountDocuments(context.TODO(), bson.M{})
+	if err != nil || count != 0 {
+		t.Fatalf("expected the archived session to be erased, got %d %v", count, err)
+	}
+}
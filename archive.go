@@ -0,0 +1,202 @@
+package mongostore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ArchiveFormat is the format of the archives written by Export and read by
+// Import.
+type ArchiveFormat int
+
+const (
+	// NDJSON writes one session per line in canonical extended JSON, which
+	// keeps the BSON types.
+	NDJSON ArchiveFormat = iota
+
+	// BSONArchive writes the sessions as consecutive BSON documents, like
+	// mongodump.
+	BSONArchive
+)
+
+// importBatchSize is the number of sessions written per bulk write by Import.
+const importBatchSize = 500
+
+// Export writes every session of the collection to w, with the expiry fields
+// so the imported sessions expire at the same time. The data of sessions
+// moved to the overflow collection is written inline.
+func (s *Store) Export(ctx context.Context, w io.Writer, format ArchiveFormat) error {
+	cursor, err := s.readCollection().Find(ctx, bson.M{})
+	if err != nil {
+		return fmt.Errorf("mongostore: finding sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc bson.D
+		err = cursor.Decode(&doc)
+		if err != nil {
+			return fmt.Errorf("mongostore: decoding session: %w", err)
+		}
+
+		doc, err = s.inlineOverflow(doc)
+		if err != nil {
+			return fmt.Errorf("mongostore: reading session overflow: %w", err)
+		}
+
+		var b []byte
+		switch format {
+		case BSONArchive:
+			b, err = bson.Marshal(doc)
+		default:
+			b, err = bson.MarshalExtJSON(doc, true, false)
+			b = append(b, '\n')
+		}
+		if err != nil {
+			return fmt.Errorf("mongostore: encoding session: %w", err)
+		}
+
+		_, err = w.Write(b)
+		if err != nil {
+			return fmt.Errorf("mongostore: writing session: %w", err)
+		}
+	}
+
+	return cursor.Err()
+}
+
+// inlineOverflow replaces the overflow field of a session with its data.
+func (s *Store) inlineOverflow(doc bson.D) (bson.D, error) {
+	for i, e := range doc {
+		if e.Key != "overflow" {
+			continue
+		}
+
+		id, ok := e.Value.(primitive.ObjectID)
+		if !ok {
+			return doc, nil
+		}
+
+		// the chunks of an expired session can be gone already
+		data, err := s.readOverflow(id)
+		if errors.Is(err, ErrSessionExpired) {
+			return doc, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		doc[i] = bson.E{Key: "data", Value: data}
+		return doc, nil
+	}
+
+	return doc, nil
+}
+
+// Import reads an archive written by Export and writes its sessions to the
+// collection, replacing the sessions with the same _id. It returns the
+// number of sessions imported.
+func (s *Store) Import(ctx context.Context, r io.Reader, format ArchiveFormat) (int64, error) {
+	br := bufio.NewReader(r)
+
+	var imported int64
+	var models []mongo.WriteModel
+
+	flush := func() error {
+		if len(models) == 0 {
+			return nil
+		}
+
+		res, err := s.writeCollection().BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return fmt.Errorf("mongostore: importing sessions: %w", err)
+		}
+		imported += res.UpsertedCount + res.MatchedCount
+		models = models[:0]
+
+		return nil
+	}
+
+	for {
+		doc, err := readArchiveDocument(br, format)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imported, fmt.Errorf("mongostore: reading archive: %w", err)
+		}
+		if doc == nil {
+			continue
+		}
+
+		models = append(models, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": doc.Lookup("_id")}).
+			SetReplacement(doc).
+			SetUpsert(true))
+
+		if len(models) == importBatchSize {
+			err = flush()
+			if err != nil {
+				return imported, err
+			}
+		}
+	}
+
+	return imported, flush()
+}
+
+// readArchiveDocument returns the next session of the archive, or nil for an
+// empty line.
+func readArchiveDocument(br *bufio.Reader, format ArchiveFormat) (bson.Raw, error) {
+	switch format {
+	case BSONArchive:
+		header, err := br.Peek(4)
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(header) == 0 {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+
+		length := binary.LittleEndian.Uint32(header)
+		if length < 5 {
+			return nil, fmt.Errorf("invalid document length %d", length)
+		}
+
+		doc := make([]byte, length)
+		_, err = io.ReadFull(br, doc)
+		if err != nil {
+			return nil, err
+		}
+
+		return bson.Raw(doc), bson.Raw(doc).Validate()
+
+	default:
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(line) == 0 {
+			return nil, io.EOF
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			return nil, nil
+		}
+
+		var doc bson.Raw
+		err = bson.UnmarshalExtJSON(line, true, &doc)
+		return doc, err
+	}
+}
@@ -0,0 +1,71 @@
+package mongostore_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []mongostore.ArchiveFormat{mongostore.NDJSON, mongostore.BSONArchive} {
+		store := newTestStore(t, "sessions_archive_test")
+
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		res := httptest.NewRecorder()
+
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to create new session: %v\n", err)
+		}
+		session.Values["answer"] = 42
+		err = store.Save(req, res, session)
+		if err != nil {
+			t.Fatalf("failed to insert session: %v\n", err)
+		}
+
+		var buf bytes.Buffer
+		err = store.Export(context.TODO(), &buf, format)
+		if err != nil {
+			t.Fatalf("failed to export sessions: %v\n", err)
+		}
+
+		// restore into an empty collection
+		err = store.MongoStore.Collection.Drop(context.TODO())
+		if err != nil {
+			t.Fatalf("failed to drop test collection: %v\n", err)
+		}
+
+		imported, err := store.Import(context.TODO(), &buf, format)
+		if err != nil {
+			t.Fatalf("failed to import sessions: %v\n", err)
+		}
+		if imported != 1 {
+			t.Fatalf("expected 1 imported session, got %d", imported)
+		}
+
+		mongoSession := &mongostore.MongoSession{}
+		err = store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Decode(mongoSession)
+		if err != nil {
+			t.Fatalf("failed to find session: %v\n", err)
+		}
+		if mongoSession.Data["answer"] != int32(42) || mongoSession.Expires == 0 {
+			t.Fatalf("expected the session to be restored, got %+v", mongoSession)
+		}
+
+		// the session is valid after the restore
+		req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+		session, err = store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to get session: %v\n", err)
+		}
+		if session.IsNew {
+			t.Fatal("expected the restored session")
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package mongostore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditOp is a session lifecycle event recorded in the audit log.
+type AuditOp string
+
+const (
+	// AuditCreate records a new session.
+	AuditCreate AuditOp = "create"
+
+	// AuditUpdate records a saved existing session.
+	AuditUpdate AuditOp = "update"
+
+	// AuditDelete records a session deleted with a MaxAge of -1.
+	AuditDelete AuditOp = "delete"
+
+	// AuditExpire records a request with an expired session.
+	AuditExpire AuditOp = "expire"
+
+	// AuditEvict records a session evicted by the session limit.
+	AuditEvict AuditOp = "evict"
+)
+
+// AuditRecord is an entry of the audit log.
+//
+// The records written by a store form a chain: each record holds the hash of
+// the previous one, so VerifyAuditLog detects records that were changed or
+// removed.
+type AuditRecord struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Op        AuditOp            `bson:"op"`
+	SessionID string             `bson:"session_id"`
+	UserID    string             `bson:"user_id,omitempty"`
+	TenantID  string             `bson:"tenant_id,omitempty"`
+	IP        string             `bson:"ip,omitempty"`
+	UserAgent string             `bson:"user_agent,omitempty"`
+	RequestID string             `bson:"request_id,omitempty"`
+	At        primitive.DateTime `bson:"at"`
+
+	// Chain identifies the store that wrote the record, Seq orders the
+	// records of the chain.
+	Chain    primitive.ObjectID `bson:"chain"`
+	Seq      int64              `bson:"seq"`
+	PrevHash string             `bson:"prev_hash,omitempty"`
+	Hash     string             `bson:"hash"`
+}
+
+// hash returns the hash of the record, covering the hash of the previous
+// record.
+func (a *AuditRecord) hash() string {
+	fields := []string{
+		string(a.Op),
+		a.SessionID,
+		a.UserID,
+		a.TenantID,
+		a.IP,
+		a.UserAgent,
+		strconv.FormatInt(int64(a.At), 10),
+		a.Chain.Hex(),
+		strconv.FormatInt(a.Seq, 10),
+		a.PrevHash,
+		a.RequestID,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditQuery selects audit records, empty fields match every record.
+type AuditQuery struct {
+	SessionID string
+	UserID    string
+	RequestID string
+	Op        AuditOp
+	Since     time.Time
+	Until     time.Time
+
+	// Limit is the maximum number of records returned, zero means no limit.
+	Limit int64
+}
+
+// auditID returns the session id recorded in the audit log, the hash of the
+// token with Options.OpaqueTokens so the log never holds usable tokens.
+func (s *Store) auditID(session *sessions.Session) string {
+	return s.auditTokenID(session.ID)
+}
+
+// auditTokenID returns the session id recorded in the audit log for the id
+// in the cookie.
+func (s *Store) auditTokenID(id string) string {
+	if s.MongoStore.OpaqueTokens {
+		return hashToken(id)
+	}
+	return id
+}
+
+// auditDocumentID returns the session id recorded in the audit log for a
+// session document.
+func (s *Store) auditDocumentID(id interface{}, tokenHash string) string {
+	if s.MongoStore.OpaqueTokens {
+		return tokenHash
+	}
+	if oid, ok := id.(primitive.ObjectID); ok {
+		return oid.Hex()
+	}
+	return fmt.Sprint(id)
+}
+
+// audit writes a record to the audit log, if Options.AuditCollection is set.
+// A failed write is logged and does not fail the request.
+func (s *Store) audit(r *http.Request, session *sessions.Session, op AuditOp) {
+	s.auditRecord(r, &AuditRecord{
+		Op:        op,
+		SessionID: s.auditID(session),
+		UserID:    s.Owner(session),
+		TenantID:  tenant(session),
+	})
+}
+
+// auditRecord chains and writes a record to the audit log. The chain only
+// moves on once the record is written, so a failed write leaves no gap in
+// it: records are written one at a time, under auditMu.
+func (s *Store) auditRecord(r *http.Request, record *AuditRecord) {
+	if s.MongoStore.AuditCollection == nil {
+		return
+	}
+
+	if r != nil {
+		info := s.clientInfo(r)
+		record.IP = info.IP
+		record.UserAgent = info.UserAgent
+		record.RequestID = info.RequestID
+	}
+	record.At = primitive.NewDateTimeFromTime(s.now())
+
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+
+	if s.auditChain.IsZero() {
+		s.auditChain = primitive.NewObjectID()
+	}
+	record.Chain = s.auditChain
+	record.Seq = s.auditSeq + 1
+	record.PrevHash = s.auditHash
+	record.Hash = record.hash()
+
+	_, err := s.MongoStore.AuditCollection.InsertOne(s.MongoStore.Context, record)
+	if err != nil {
+		s.log(s.logContext(r), slog.LevelError, "writing audit record", errorAttr(err))
+		return
+	}
+
+	s.auditSeq = record.Seq
+	s.auditHash = record.Hash
+}
+
+// AuditLog returns the audit records matching the query, oldest first.
+func (s *Store) AuditLog(ctx context.Context, q AuditQuery) ([]AuditRecord, error) {
+	filter := bson.M{}
+	if q.SessionID != "" {
+		filter["session_id"] = q.SessionID
+	}
+	if q.UserID != "" {
+		filter["user_id"] = q.UserID
+	}
+	if q.RequestID != "" {
+		filter["request_id"] = q.RequestID
+	}
+	if q.Op != "" {
+		filter["op"] = q.Op
+	}
+
+	at := bson.M{}
+	if !q.Since.IsZero() {
+		at["$gte"] = primitive.NewDateTimeFromTime(q.Since)
+	}
+	if !q.Until.IsZero() {
+		at["$lt"] = primitive.NewDateTimeFromTime(q.Until)
+	}
+	if len(at) > 0 {
+		filter["at"] = at
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "at", Value: 1}, {Key: "_id", Value: 1}})
+	if q.Limit > 0 {
+		findOptions.SetLimit(q.Limit)
+	}
+
+	cursor, err := s.MongoStore.AuditCollection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: finding audit records: %w", err)
+	}
+
+	var records []AuditRecord
+	err = cursor.All(ctx, &records)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: decoding audit records: %w", err)
+	}
+
+	return records, nil
+}
+
+// VerifyAuditLog checks the chains of the audit log, it returns
+// ErrAuditTampered if a record was changed, or removed from the middle of a
+// chain. The oldest records of a capped collection can be removed without
+// breaking the chain.
+func (s *Store) VerifyAuditLog(ctx context.Context) error {
+	cursor, err := s.MongoStore.AuditCollection.Find(
+		ctx,
+		bson.M{},
+		options.Find().SetSort(bson.D{{Key: "chain", Value: 1}, {Key: "seq", Value: 1}}),
+	)
+	if err != nil {
+		return fmt.Errorf("mongostore: finding audit records: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var previous *AuditRecord
+	for cursor.Next(ctx) {
+		record := &AuditRecord{}
+		err = cursor.Decode(record)
+		if err != nil {
+			return fmt.Errorf("mongostore: decoding audit record: %w", err)
+		}
+
+		if record.Hash != record.hash() {
+			return wrapError(ErrAuditTampered, fmt.Errorf("record %s was changed", record.ID.Hex()))
+		}
+
+		if previous != nil && previous.Chain == record.Chain {
+			if record.Seq != previous.Seq+1 || record.PrevHash != previous.Hash {
+				return wrapError(ErrAuditTampered, fmt.Errorf("records missing before %s", record.ID.Hex()))
+			}
+		}
+		previous = record
+	}
+
+	return cursor.Err()
+}
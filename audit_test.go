@@ -0,0 +1,127 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestAuditLog(t *testing.T) {
+	store := newTestStore(t, "sessions_audit_test")
+
+	audit := mongoclient.Database("test-database").Collection("sessions_audit_log_test")
+	err := audit.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop audit collection: %v\n", err)
+	}
+	store.MongoStore.AuditCollection = audit
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(session, "audit-user")
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	session.Values["step"] = 2
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v\n", err)
+	}
+
+	session.Options.MaxAge = -1
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v\n", err)
+	}
+
+	records, err := store.AuditLog(context.TODO(), mongostore.AuditQuery{UserID: "audit-user"})
+	if err != nil {
+		t.Fatalf("failed to query audit log: %v\n", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	for i, op := range []mongostore.AuditOp{mongostore.AuditCreate, mongostore.AuditUpdate, mongostore.AuditDelete} {
+		if records[i].Op != op || records[i].SessionID != session.ID || records[i].IP != "192.0.2.1" {
+			t.Fatalf("unexpected record %d: %+v", i, records[i])
+		}
+	}
+
+	err = store.VerifyAuditLog(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to verify audit log: %v\n", err)
+	}
+
+	// removing a record breaks the chain
+	_, err = audit.DeleteOne(context.TODO(), bson.M{"_id": records[1].ID})
+	if err != nil {
+		t.Fatalf("failed to delete audit record: %v\n", err)
+	}
+	err = store.VerifyAuditLog(context.TODO())
+	if !errors.Is(err, mongostore.ErrAuditTampered) {
+		t.Fatalf("expected ErrAuditTampered, got %v", err)
+	}
+}
+
+func TestAuditLogFailedWrite(t *testing.T) {
+	store := newTestStore(t, "sessions_audit_failed_test")
+
+	audit := mongoclient.Database("test-database").Collection("sessions_audit_log_failed_test")
+	err := audit.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop audit collection: %v\n", err)
+	}
+
+	// the records of one user are rejected
+	err = mongoclient.Database("test-database").CreateCollection(
+		context.TODO(),
+		"sessions_audit_log_failed_test",
+		options.CreateCollection().SetValidator(bson.M{"user_id": bson.M{"$ne": "rejected-user"}}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create audit collection: %v\n", err)
+	}
+	store.MongoStore.AuditCollection = audit
+
+	for _, userID := range []string{"audit-user", "rejected-user", "audit-user"} {
+		err = login(t, store, userID)
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	count, err := audit.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to count audit records: %v\n", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 audit records, got %d", count)
+	}
+
+	// the failed write leaves no gap in the chain
+	err = store.VerifyAuditLog(context.TODO())
+	if err != nil {
+		t.Fatalf("expected a valid audit log, got %v", err)
+	}
+}
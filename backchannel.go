@@ -0,0 +1,402 @@
+package mongostore
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// backchannelLogoutEvent is the event of the events claim of a logout
+// token.
+const backchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// defaultLogoutLeeway is the clock skew tolerated on the iat and exp claims
+// when BackchannelLogoutOptions.Leeway is zero.
+const defaultLogoutLeeway = time.Minute
+
+// defaultLogoutMaxAge is how old a logout token can be when
+// BackchannelLogoutOptions.MaxAge is zero.
+const defaultLogoutMaxAge = 5 * time.Minute
+
+// OIDCSessionIndex speeds up the back-channel logouts by sid, add it to
+// Options.Indexes.
+var OIDCSessionIndex = Index{Field: "oidc_sid"}
+
+// SetOIDCSession records the session id of the OpenID Connect provider, the
+// sid claim of the ID token, at login. It is stored in the oidc_sid field of
+// the mongo session, matched by the sid claim of the logout tokens.
+func (s *Store) SetOIDCSession(session *sessions.Session, sid string) {
+	if sid == "" {
+		delete(session.Values, oidcSIDKey)
+		return
+	}
+	session.Values[oidcSIDKey] = sid
+}
+
+// OIDCSession returns the session id of the provider set with
+// SetOIDCSession, or an empty string.
+func (s *Store) OIDCSession(session *sessions.Session) string {
+	sid, _ := session.Values[oidcSIDKey].(string)
+	return sid
+}
+
+// BackchannelLogoutOptions configure the validation of the OpenID Connect
+// back-channel logout tokens of an identity provider, and the sessions they
+// revoke.
+type BackchannelLogoutOptions struct {
+	// Issuer is the iss claim of the tokens, ClientID must be in their aud
+	// claim. Both are required.
+	Issuer   string
+	ClientID string
+
+	// Key returns the public key verifying the tokens signed with the key
+	// id kid, usually from the JWKS of the provider: an *rsa.PublicKey for
+	// RS256 or an *ecdsa.PublicKey for ES256. It is required.
+	Key func(ctx context.Context, kid string) (crypto.PublicKey, error)
+
+	// Claims maps the claims of the token to the fields of the session
+	// documents matching them, by default the sub claim to user_id, the
+	// owner set with SetOwner, and the sid claim to oidc_sid, the session
+	// id of the provider set with SetOIDCSession. A token revokes the
+	// sessions matching all its mapped claims.
+	Claims map[string]string
+
+	// Tenant restricts the logout to the sessions of a tenant when
+	// Options.TenantFunc is set, BackchannelLogoutHandler sets it to the
+	// tenant of the request.
+	Tenant string
+
+	// ReplayCollection remembers the jti claims of the tokens accepted
+	// until they are too old to be valid, so a replayed token is rejected.
+	// The default is the session collection name followed by
+	// ".logout_tokens" in the same database.
+	ReplayCollection *mongo.Collection
+
+	// Leeway is the clock skew tolerated on the times of the tokens, a
+	// minute by default. MaxAge is how old a token can be, five minutes by
+	// default.
+	Leeway time.Duration
+	MaxAge time.Duration
+}
+
+// logoutToken is a decoded logout token.
+type logoutToken struct {
+	header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+		Type      string `json:"typ"`
+	}
+	claims map[string]interface{}
+}
+
+// BackchannelLogoutHandler returns a handler for the back-channel logout
+// requests of an OpenID Connect provider, to register as the
+// backchannel_logout_uri of the client. It deletes the sessions of the
+// logout_token posted by the provider, see BackchannelLogout, and responds
+// 400 when the token is invalid.
+func (s *Store) BackchannelLogoutHandler(opts BackchannelLogoutOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			adminJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "invalid_request"})
+			return
+		}
+
+		if s.MongoStore.TenantFunc != nil {
+			opts.Tenant = s.MongoStore.TenantFunc(r)
+		}
+
+		deleted, err := s.BackchannelLogout(r.Context(), r.PostFormValue("logout_token"), opts)
+		if errors.Is(err, ErrInvalidJWT) {
+			s.log(r.Context(), slog.LevelWarn, "rejecting logout token", errorAttr(err))
+			adminJSON(w, http.StatusBadRequest, map[string]string{
+				"error":             "invalid_request",
+				"error_description": err.Error(),
+			})
+			return
+		}
+		if err != nil {
+			adminError(w, err)
+			return
+		}
+
+		adminJSON(w, http.StatusOK, map[string]int64{"deleted": deleted})
+	})
+}
+
+// BackchannelLogout validates an OpenID Connect back-channel logout token
+// and deletes the sessions matching its claims, see
+// BackchannelLogoutOptions.Claims. It returns ErrInvalidJWT if the token is
+// malformed, has a bad signature, is not a logout token of the provider for
+// the client, has none of the mapped claims, or was already accepted.
+func (s *Store) BackchannelLogout(ctx context.Context, token string, opts BackchannelLogoutOptions) (int64, error) {
+	if opts.Issuer == "" || opts.ClientID == "" || opts.Key == nil {
+		return 0, errors.New("mongostore: back-channel logout needs an issuer, a client id and a key")
+	}
+
+	lt, err := s.verifyLogoutToken(ctx, token, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	claims := opts.Claims
+	if claims == nil {
+		claims = map[string]string{
+			"sub": "user_id",
+			"sid": "oidc_sid",
+		}
+	}
+
+	filter := bson.M{}
+	for claim, field := range claims {
+		if v, ok := lt.claims[claim].(string); ok && v != "" {
+			filter[field] = v
+		}
+	}
+	if len(filter) == 0 {
+		return 0, wrapError(ErrInvalidJWT, errors.New("logout token has no mapped claim"))
+	}
+
+	replays, id, err := s.acceptLogoutToken(ctx, lt, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := s.DeleteWhere(WithTenant(ctx, opts.Tenant), filter)
+	if err != nil {
+		// the provider retries the logout with the same token
+		_, derr := replays.DeleteOne(ctx, bson.M{"_id": id})
+		if derr != nil {
+			s.log(ctx, slog.LevelError, "forgetting logout token", errorAttr(derr))
+		}
+		return 0, err
+	}
+	s.log(ctx, slog.LevelInfo, "back-channel logout", slog.String("issuer", opts.Issuer), slog.Int64("count", deleted))
+
+	return deleted, nil
+}
+
+// acceptLogoutToken records the jti claim of the token in the replay
+// collection, it returns ErrInvalidJWT if the token was already accepted.
+// The records are removed by a time to live index once the token is too old
+// to be valid.
+func (s *Store) acceptLogoutToken(ctx context.Context, lt *logoutToken, opts BackchannelLogoutOptions) (*mongo.Collection, string, error) {
+	replays := opts.ReplayCollection
+	if replays == nil {
+		replays = s.MongoStore.Collection.Database().Collection(s.MongoStore.Collection.Name() + ".logout_tokens")
+	}
+
+	err := s.insertLogoutTokenIndex(ctx, replays)
+	if err != nil {
+		return nil, "", fmt.Errorf("mongostore: adding logout token index: %w", err)
+	}
+
+	jti, _ := lt.claims["jti"].(string)
+	id := opts.Issuer + " " + jti
+	iat, _ := lt.claims["iat"].(float64)
+	expires := time.Unix(int64(iat), 0).Add(logoutMaxAge(opts) + logoutLeeway(opts))
+
+	_, err = replays.InsertOne(ctx, bson.M{
+		"_id":        id,
+		"expires_at": primitive.NewDateTimeFromTime(expires),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, "", wrapError(ErrInvalidJWT, errors.New("logout token replayed"))
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("mongostore: recording logout token: %w", err)
+	}
+
+	return replays, id, nil
+}
+
+// insertLogoutTokenIndex adds the time to live index of the replay
+// collection, once per collection.
+func (s *Store) insertLogoutTokenIndex(ctx context.Context, replays *mongo.Collection) error {
+	name := replays.Database().Name() + "." + replays.Name()
+
+	s.logoutMu.Lock()
+	defer s.logoutMu.Unlock()
+
+	if s.logoutIndexed[name] {
+		return nil
+	}
+
+	// creating an index that already exists is a no-op
+	_, err := replays.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.logoutIndexed == nil {
+		s.logoutIndexed = map[string]bool{}
+	}
+	s.logoutIndexed[name] = true
+
+	return nil
+}
+
+// logoutLeeway returns BackchannelLogoutOptions.Leeway, or its default.
+func logoutLeeway(opts BackchannelLogoutOptions) time.Duration {
+	if opts.Leeway <= 0 {
+		return defaultLogoutLeeway
+	}
+	return opts.Leeway
+}
+
+// logoutMaxAge returns BackchannelLogoutOptions.MaxAge, or its default.
+func logoutMaxAge(opts BackchannelLogoutOptions) time.Duration {
+	if opts.MaxAge <= 0 {
+		return defaultLogoutMaxAge
+	}
+	return opts.MaxAge
+}
+
+// verifyLogoutToken checks the signature and the claims of a logout token,
+// as required by OpenID Connect Back-Channel Logout 1.0.
+func (s *Store) verifyLogoutToken(ctx context.Context, token string, opts BackchannelLogoutOptions) (*logoutToken, error) {
+	invalid := func(reason string) error {
+		return wrapError(ErrInvalidJWT, errors.New(reason))
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("malformed logout token")
+	}
+
+	lt := &logoutToken{}
+	err := decodeJWTPart(parts[0], &lt.header)
+	if err != nil {
+		return nil, invalid("malformed logout token header")
+	}
+	if lt.header.Type != "" && !strings.EqualFold(lt.header.Type, "logout+jwt") && !strings.EqualFold(lt.header.Type, "JWT") {
+		return nil, invalid("not a logout token")
+	}
+
+	// check the signature before looking at the claims
+	key, err := opts.Key(ctx, lt.header.KeyID)
+	if err != nil {
+		return nil, wrapError(ErrInvalidJWT, fmt.Errorf("key %q: %w", lt.header.KeyID, err))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalid("malformed logout token signature")
+	}
+	if !verifyLogoutSignature(lt.header.Algorithm, key, parts[0]+"."+parts[1], signature) {
+		return nil, invalid("bad logout token signature")
+	}
+
+	err = decodeJWTPart(parts[1], &lt.claims)
+	if err != nil {
+		return nil, invalid("malformed logout token claims")
+	}
+
+	if lt.claims["iss"] != opts.Issuer {
+		return nil, invalid("logout token of another issuer")
+	}
+	if !hasAudience(lt.claims["aud"], opts.ClientID) {
+		return nil, invalid("logout token of another client")
+	}
+
+	leeway := logoutLeeway(opts)
+	maxAge := logoutMaxAge(opts)
+	now := s.now()
+	iat, ok := lt.claims["iat"].(float64)
+	if !ok {
+		return nil, invalid("logout token has no iat")
+	}
+	issued := time.Unix(int64(iat), 0)
+	if issued.After(now.Add(leeway)) || now.Sub(issued) > maxAge+leeway {
+		return nil, invalid("logout token issued at an invalid time")
+	}
+	if exp, ok := lt.claims["exp"].(float64); ok && now.After(time.Unix(int64(exp), 0).Add(leeway)) {
+		return nil, invalid("logout token expired")
+	}
+
+	events, _ := lt.claims["events"].(map[string]interface{})
+	if _, ok := events[backchannelLogoutEvent]; !ok {
+		return nil, invalid("logout token has no back-channel logout event")
+	}
+	if _, ok := lt.claims["nonce"]; ok {
+		return nil, invalid("logout token has a nonce")
+	}
+	if lt.claims["sub"] == nil && lt.claims["sid"] == nil {
+		return nil, invalid("logout token has no sub or sid")
+	}
+	if jti, _ := lt.claims["jti"].(string); jti == "" {
+		return nil, invalid("logout token has no jti")
+	}
+
+	return lt, nil
+}
+
+// decodeJWTPart decodes the JSON of the header or the claims of a JWT.
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// verifyLogoutSignature verifies the RS256 or ES256 signature of the signed
+// part of a token.
+func verifyLogoutSignature(alg string, key crypto.PublicKey, signed string, signature []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature) == nil
+
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		sig := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(pub, digest[:], r, sig)
+	}
+
+	return false
+}
+
+// hasAudience reports if the aud claim, a string or an array of strings,
+// holds the client id.
+func hasAudience(aud interface{}, clientID string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		for _, a := range aud {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package mongostore_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glezjose/mongostore"
+)
+
+// signLogoutToken returns an RS256 logout token with the claims.
+func signLogoutToken(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test-key", "typ": "logout+jwt"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("failed to encode claims: %v\n", err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("failed to sign token: %v\n", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestBackchannelLogout(t *testing.T) {
+	store := newTestStore(t, "sessions_backchannel_test")
+	err := mongoclient.Database("test-database").Collection("sessions_backchannel_test.logout_tokens").Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop logout tokens: %v\n", err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v\n", err)
+	}
+	opts := mongostore.BackchannelLogoutOptions{
+		Issuer:   "https://idp.example.com",
+		ClientID: "test-client",
+		Key: func(ctx context.Context, kid string) (crypto.PublicKey, error) {
+			return &key.PublicKey, nil
+		},
+	}
+
+	for _, sid := range []string{"idp-session-1", "idp-session-2"} {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to create new session: %v\n", err)
+		}
+		store.SetOwner(session, "logout-user")
+		store.SetOIDCSession(session, sid)
+		err = store.Save(req, httptest.NewRecorder(), session)
+		if err != nil {
+			t.Fatalf("failed to insert session: %v\n", err)
+		}
+	}
+
+	jti := 0
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		jti++
+		c := map[string]interface{}{
+			"iss":    opts.Issuer,
+			"aud":    opts.ClientID,
+			"iat":    time.Now().Unix(),
+			"jti":    fmt.Sprintf("test-jti-%d", jti),
+			"events": map[string]interface{}{"http://schemas.openid.net/event/backchannel-logout": map[string]interface{}{}},
+		}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	handler := store.BackchannelLogoutHandler(opts)
+	post := func(token string) *httptest.ResponseRecorder {
+		form := url.Values{"logout_token": {token}}
+		req := httptest.NewRequest("POST", "/backchannel-logout", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	// a token of another client is rejected
+	res := post(signLogoutToken(t, key, claims(map[string]interface{}{"aud": "other-client", "sub": "logout-user"})))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", res.Code)
+	}
+
+	// a sid logs out the session of the provider only
+	token := signLogoutToken(t, key, claims(map[string]interface{}{"sid": "idp-session-1"}))
+	res = post(token)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+
+	// a replayed token is rejected
+	res = post(token)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a replayed token, got %d", res.Code)
+	}
+	count, err := store.CountSessions(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 session left, got %d", count)
+	}
+
+	// a sub logs out every session of the user
+	res = post(signLogoutToken(t, key, claims(map[string]interface{}{"sub": "logout-user"})))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+	}
+	count, err = store.CountSessions(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no session left, got %d", count)
+	}
+
+	// a token signed with another key is rejected
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v\n", err)
+	}
+	res = post(signLogoutToken(t, other, claims(map[string]interface{}{"sub": "logout-user"})))
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", res.Code)
+	}
+}
+
+func TestBackchannelLogoutTenant(t *testing.T) {
+	store := newTestStore(t, "sessions_backchannel_tenant_test")
+	err := mongoclient.Database("test-database").Collection("sessions_backchannel_tenant_test.logout_tokens").Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop logout tokens: %v\n", err)
+	}
+	store.MongoStore.TenantFunc = func(r *http.Request) string {
+		return r.Host
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v\n", err)
+	}
+	opts := mongostore.BackchannelLogoutOptions{
+		Issuer:   "https://idp.example.com",
+		ClientID: "test-client",
+		Key: func(ctx context.Context, kid string) (crypto.PublicKey, error) {
+			return &key.PublicKey, nil
+		},
+	}
+
+	for _, host := range []string{"a.example.com", "b.example.com"} {
+		req, _ := http.NewRequest("GET", "http://"+host+"/", nil)
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to create new session: %v\n", err)
+		}
+		store.SetOwner(session, "logout-user")
+		err = store.Save(req, httptest.NewRecorder(), session)
+		if err != nil {
+			t.Fatalf("failed to insert session: %v\n", err)
+		}
+	}
+
+	// the logout of a tenant leaves the sessions of the other tenants
+	opts.Tenant = "a.example.com"
+	deleted, err := store.BackchannelLogout(context.TODO(), signLogoutToken(t, key, map[string]interface{}{
+		"iss":    opts.Issuer,
+		"aud":    opts.ClientID,
+		"iat":    time.Now().Unix(),
+		"jti":    "tenant-jti",
+		"sub":    "logout-user",
+		"events": map[string]interface{}{"http://schemas.openid.net/event/backchannel-logout": map[string]interface{}{}},
+	}), opts)
+	if err != nil {
+		t.Fatalf("failed to log out: %v\n", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 session deleted, got %d", deleted)
+	}
+}
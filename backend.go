@@ -0,0 +1,112 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Backend is the server behind the mongo protocol, it decides how the store
+// creates its indexes and expires sessions.
+type Backend int
+
+const (
+	// BackendAuto detects the backend when the store is created, the
+	// default.
+	BackendAuto Backend = iota
+
+	// BackendMongoDB is MongoDB.
+	BackendMongoDB
+
+	// BackendCosmos is Azure Cosmos DB for MongoDB. Its time to live index
+	// can only be on the _ts field, the time of the last write kept by the
+	// server. The index removes the sessions not written for the longest
+	// session lifetime, and the sessions expiring sooner are purged every
+	// Options.PurgeInterval.
+	BackendCosmos
+
+	// BackendDocumentDB is Amazon DocumentDB. Indexes are created without
+	// Options.IndexCollation, which it does not support, and WatchDeletes
+	// returns ErrUnsupported until change streams are enabled on the
+	// collection with the modifyChangeStreams command.
+	BackendDocumentDB
+
+	// BackendFerretDB is FerretDB, the mongo protocol on PostgreSQL. The
+	// features it lacks are found with the Capabilities probes and replaced
+	// by the store.
+	BackendFerretDB
+)
+
+// featureNotSupportedCode is the error code of DocumentDB for the commands
+// and options it does not support.
+const featureNotSupportedCode = 303
+
+// cosmosTTLField is the field Cosmos DB sets to the time of the last write,
+// the only field its time to live index accepts.
+const cosmosTTLField = "_ts"
+
+// String returns the name of the backend.
+func (b Backend) String() string {
+	switch b {
+	case BackendMongoDB:
+		return "mongodb"
+	case BackendCosmos:
+		return "cosmos"
+	case BackendDocumentDB:
+		return "documentdb"
+	case BackendFerretDB:
+		return "ferretdb"
+	default:
+		return "auto"
+	}
+}
+
+// Backend returns the backend of the store, as set with Options.Backend or
+// detected when the store was created.
+func (s *Store) Backend() Backend {
+	return s.backend
+}
+
+// detectBackend finds the backend serving the database.
+func detectBackend(ctx context.Context, db *mongo.Database) Backend {
+	// only Cosmos DB knows its customAction extension commands
+	err := db.RunCommand(ctx, bson.D{{Key: "customAction", Value: "GetDatabase"}}).Err()
+	if err == nil {
+		return BackendCosmos
+	}
+
+	// FerretDB adds its version to the build info
+	var info bson.M
+	err = db.RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&info)
+	if err == nil {
+		for key := range info {
+			if strings.HasPrefix(key, "ferretdb") {
+				return BackendFerretDB
+			}
+		}
+	}
+
+	// DocumentDB rejects the commands it lacks with its own error code
+	err = db.RunCommand(ctx, bson.D{{Key: "features", Value: 1}}).Err()
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == featureNotSupportedCode {
+		return BackendDocumentDB
+	}
+
+	return BackendMongoDB
+}
+
+// longestServerMaxAge returns the longest lifetime in seconds of a session
+// in mongo, of the default or the persistent tier.
+func (s *Store) longestServerMaxAge() int {
+	longest := s.ttlSeconds()
+	if s.MongoStore.PersistentMaxAge > 0 {
+		if persistent := s.serverMaxAge(s.MongoStore.PersistentMaxAge); persistent > longest {
+			longest = persistent
+		}
+	}
+	return longest
+}
@@ -0,0 +1,147 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestDetectBackend(t *testing.T) {
+	store := newTestStore(t, "sessions_backend_test")
+
+	if backend := store.Backend().String(); backend != testBackend() {
+		t.Fatalf("expected the %s backend, got %s", testBackend(), backend)
+	}
+}
+
+func TestCosmosBackend(t *testing.T) {
+	// the _ts field is only set by Cosmos DB, others keep the documents
+	skipOnBackends(t, "documentdb")
+
+	col := mongoclient.Database("test-database").Collection("sessions_cosmos_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:       col,
+			Backend:          mongostore.BackendCosmos,
+			PersistentMaxAge: 3600,
+			PurgeInterval:    50 * time.Millisecond,
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+	defer store.Close(context.TODO())
+
+	// the index keeps the sessions of the longest tier
+	cursor, err := col.Indexes().List(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to list indexes: %v\n", err)
+	}
+	var indexes []struct {
+		Key                bson.M `bson:"key"`
+		ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+	}
+	err = cursor.All(context.TODO(), &indexes)
+	if err != nil {
+		t.Fatalf("failed to decode indexes: %v\n", err)
+	}
+	found := false
+	for _, index := range indexes {
+		if index.Key["_ts"] != nil && index.ExpireAfterSeconds != nil && *index.ExpireAfterSeconds == 3600 {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected a time to live index on _ts, got %+v", indexes)
+	}
+
+	// the janitor purges the sessions expiring sooner
+	_, err = col.InsertOne(context.TODO(), bson.M{
+		"_id":        primitive.NewObjectID(),
+		"expires_at": primitive.NewDateTimeFromTime(time.Now().Add(-time.Minute)),
+	})
+	if err != nil {
+		t.Fatalf("failed to insert expired session: %v\n", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		count, err := col.CountDocuments(context.TODO(), bson.M{})
+		if err != nil {
+			t.Fatalf("failed to count sessions: %v\n", err)
+		}
+		if count == 0 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("expected the expired session to be purged")
+}
+
+func TestDocumentDBBackend(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_documentdb_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:     col,
+			Backend:        mongostore.BackendDocumentDB,
+			IndexCollation: &options.Collation{Locale: "en", Strength: 2},
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	// the indexes are created without the collation
+	cursor, err := col.Indexes().List(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to list indexes: %v\n", err)
+	}
+	var indexes []bson.M
+	err = cursor.All(context.TODO(), &indexes)
+	if err != nil {
+		t.Fatalf("failed to decode indexes: %v\n", err)
+	}
+	for _, index := range indexes {
+		if index["collation"] != nil {
+			t.Fatalf("expected no collation, got %v", index)
+		}
+	}
+
+	// without change streams WatchDeletes reports the feature unsupported
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err = store.WatchDeletes(ctx, nil)
+	if err != nil && !errors.Is(err, mongostore.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
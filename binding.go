@@ -0,0 +1,158 @@
+package mongostore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// BindingAction decides what happens to a session loaded by a client that
+// does not match the client that created it.
+type BindingAction int
+
+const (
+	// RejectMismatch does not load the session, New returns a new one, the
+	// default.
+	RejectMismatch BindingAction = iota
+
+	// FlagMismatch loads the session, Store.ClientMismatch reports the
+	// mismatch so the application can ask the user to log in again.
+	FlagMismatch
+
+	// AllowMismatch loads the session as if the client matched.
+	AllowMismatch
+)
+
+// ClientBinding binds sessions to the client that created them, the store
+// keeps hashes of the client IP prefix and User-Agent in the session
+// document and compares them with the clients loading the session. The IP
+// and User-Agent come from Options.ClientInfoFunc.
+type ClientBinding struct {
+	// IPv4Prefix and IPv6Prefix are the bits of the IP address compared,
+	// for example 24 and 64 to allow for address changes within a network.
+	// Zero does not bind the addresses of that family.
+	IPv4Prefix int
+	IPv6Prefix int
+
+	// UserAgent binds the User-Agent.
+	UserAgent bool
+
+	// Action is taken on a mismatch, the default is RejectMismatch.
+	Action BindingAction
+
+	// Decide chooses the action for each mismatch instead of Action, for
+	// example to allow IP changes of mobile clients. The session is loaded
+	// from mongo.
+	Decide func(client ClientInfo, mismatch BindingMismatch, session *sessions.Session) BindingAction
+}
+
+// BindingMismatch tells which parts of the client binding did not match.
+type BindingMismatch struct {
+	IP        bool
+	UserAgent bool
+}
+
+// BindingHashes are the hashes of the client that created a session, stored
+// in the session document with Options.ClientBinding.
+type BindingHashes struct {
+	IP        string `bson:"ip,omitempty"`
+	UserAgent string `bson:"user_agent,omitempty"`
+}
+
+// ClientMismatch returns the mismatch of a session loaded with the
+// FlagMismatch action, ok is false if the client matched.
+func (s *Store) ClientMismatch(session *sessions.Session) (mismatch BindingMismatch, ok bool) {
+	mismatch, ok = session.Values[mismatchKey].(BindingMismatch)
+	return mismatch, ok
+}
+
+// bindClient records the client of the request in the session, New calls it
+// so the client is known when the session is read.
+func (s *Store) bindClient(r *http.Request, session *sessions.Session) {
+	if s.MongoStore.ClientBinding == nil {
+		return
+	}
+
+	session.Values[clientKey] = s.clientInfo(r)
+}
+
+// bindingHashes returns the hashes of the client stored with a new session,
+// or nil without Options.ClientBinding.
+func (s *Store) bindingHashes(r *http.Request) *BindingHashes {
+	binding := s.MongoStore.ClientBinding
+	if binding == nil {
+		return nil
+	}
+
+	hashes := binding.hashes(s.clientInfo(r))
+	return &hashes
+}
+
+// checkBinding compares the client of the request that loaded the session
+// with the stored hashes, it reports false if the session is rejected.
+func (s *Store) checkBinding(session *sessions.Session, stored *BindingHashes) bool {
+	binding := s.MongoStore.ClientBinding
+	client, ok := session.Values[clientKey].(ClientInfo)
+	if binding == nil || stored == nil || !ok {
+		return true
+	}
+
+	hashes := binding.hashes(client)
+	mismatch := BindingMismatch{
+		IP:        stored.IP != "" && hashes.IP != stored.IP,
+		UserAgent: stored.UserAgent != "" && hashes.UserAgent != stored.UserAgent,
+	}
+	if !mismatch.IP && !mismatch.UserAgent {
+		return true
+	}
+
+	action := binding.Action
+	if binding.Decide != nil {
+		action = binding.Decide(client, mismatch, session)
+	}
+
+	switch action {
+	case AllowMismatch:
+		return true
+	case FlagMismatch:
+		s.log(s.MongoStore.Context, slog.LevelWarn, "session loaded by another client", slog.String("name", session.Name()))
+		session.Values[mismatchKey] = mismatch
+		return true
+	default:
+		s.log(s.MongoStore.Context, slog.LevelWarn, "session rejected, loaded by another client", slog.String("name", session.Name()))
+		return false
+	}
+}
+
+// hashes returns the hashes of the bound parts of the client.
+func (b *ClientBinding) hashes(client ClientInfo) BindingHashes {
+	var hashes BindingHashes
+
+	if ip := net.ParseIP(client.IP); ip != nil {
+		var network *net.IPNet
+		if ip4 := ip.To4(); ip4 != nil && b.IPv4Prefix > 0 {
+			network = &net.IPNet{IP: ip4, Mask: net.CIDRMask(b.IPv4Prefix, 32)}
+		} else if ip4 == nil && b.IPv6Prefix > 0 {
+			network = &net.IPNet{IP: ip, Mask: net.CIDRMask(b.IPv6Prefix, 128)}
+		}
+		if network != nil {
+			hashes.IP = hashClient(network.IP.Mask(network.Mask).String())
+		}
+	}
+
+	if b.UserAgent {
+		hashes.UserAgent = hashClient(client.UserAgent)
+	}
+
+	return hashes
+}
+
+// hashClient returns the hex encoded sha256 of a part of the client.
+func hashClient(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:])
+}
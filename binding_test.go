@@ -0,0 +1,69 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestClientBinding(t *testing.T) {
+	store := newTestStore(t, "sessions_binding_test")
+	binding := &mongostore.ClientBinding{IPv4Prefix: 24, UserAgent: true}
+	store.MongoStore.ClientBinding = binding
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", "browser")
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	cookie := res.Header().Get("Set-Cookie")
+
+	load := func(remoteAddr string, userAgent string) *sessions.Session {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("User-Agent", userAgent)
+		req.Header.Set("Cookie", cookie)
+
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to get session: %v\n", err)
+		}
+		return session
+	}
+
+	// the same network and browser
+	session = load("10.0.0.2:4321", "browser")
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+
+	// another browser is rejected
+	session = load("10.0.0.1:1234", "another browser")
+	if !session.IsNew {
+		t.Fatal("expected the session of another client to be rejected")
+	}
+
+	// or flagged
+	binding.Action = mongostore.FlagMismatch
+	session = load("192.168.0.1:1234", "browser")
+	if session.IsNew {
+		t.Fatal("expected the flagged session to be loaded")
+	}
+	mismatch, ok := store.ClientMismatch(session)
+	if !ok || !mismatch.IP || mismatch.UserAgent {
+		t.Fatalf("expected an IP mismatch, got %+v", mismatch)
+	}
+}
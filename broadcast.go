@@ -0,0 +1,118 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeleteEvent is a session deleted by any instance of the store, as seen by
+// WatchDeletes.
+type DeleteEvent struct {
+	// DocumentID is the _id of the session document.
+	DocumentID interface{}
+
+	// SessionID is the session id as recorded in the audit log, the hash of
+	// the token with Options.OpaqueTokens. It is empty when a session with
+	// an opaque token is removed, the change stream only has its _id.
+	SessionID string
+
+	// Soft reports that the session was tombstoned with Options.SoftDelete.
+	Soft bool
+}
+
+// changeEvent is the part of a change stream event WatchDeletes reads.
+type changeEvent struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID interface{} `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument *struct {
+		TokenHash string `bson:"token_hash"`
+	} `bson:"fullDocument"`
+}
+
+// WatchDeletes follows the deletes of sessions by every instance with a
+// change stream on the session collection, so a logout on one instance
+// applies right away on the others. Each deleted session is evicted from
+// Options.Fallback, then passed to fn to evict it from the caches of the
+// application.
+//
+// It blocks until ctx is done, run it in a goroutine. Change streams need a
+// replica set or a sharded cluster, the error of the stream is returned and
+// the caller decides when to watch again. On backends without change
+// streams, and on DocumentDB until they are enabled, the error is
+// ErrUnsupported.
+func (s *Store) WatchDeletes(ctx context.Context, fn func(DeleteEvent)) error {
+	if !s.capabilities.ChangeStreams {
+		return fmt.Errorf("mongostore: watching deletes: %w", ErrUnsupported)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"operationType": "delete"},
+			bson.M{
+				"operationType": "update",
+				"updateDescription.updatedFields.deleted_at": bson.M{"$exists": true},
+			},
+		}}}},
+	}
+
+	stream, err := s.MongoStore.Collection.Watch(
+		ctx,
+		pipeline,
+		options.ChangeStream().SetFullDocument(options.UpdateLookup),
+	)
+	if err != nil {
+		// change streams are off by default on DocumentDB
+		if s.backend == BackendDocumentDB {
+			err = wrapError(ErrUnsupported, err)
+		}
+		return fmt.Errorf("mongostore: watching deletes: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change changeEvent
+		err := stream.Decode(&change)
+		if err != nil {
+			s.log(ctx, slog.LevelWarn, "decoding change event", errorAttr(err))
+			continue
+		}
+
+		event := DeleteEvent{
+			DocumentID: change.DocumentKey.ID,
+			Soft:       change.OperationType == "update",
+		}
+		switch {
+		case !s.MongoStore.OpaqueTokens:
+			event.SessionID = s.auditDocumentID(change.DocumentKey.ID, "")
+		case change.FullDocument != nil:
+			event.SessionID = change.FullDocument.TokenHash
+		}
+
+		// the fallback holds sessions by the id in the cookie
+		if s.MongoStore.Fallback != nil && !s.MongoStore.OpaqueTokens {
+			s.MongoStore.Fallback.Delete(event.SessionID)
+		}
+
+		if fn != nil {
+			fn(event)
+		}
+	}
+
+	err = stream.Err()
+	if err != nil && !errors.Is(err, context.Canceled) && ctx.Err() == nil {
+		if s.backend == BackendDocumentDB {
+			err = wrapError(ErrUnsupported, err)
+		}
+		return fmt.Errorf("mongostore: watching deletes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestWatchDeletes(t *testing.T) {
+	store := newTestStore(t, "sessions_broadcast_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan mongostore.DeleteEvent, 1)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- store.WatchDeletes(ctx, func(event mongostore.DeleteEvent) {
+			events <- event
+		})
+	}()
+
+	// change streams need a replica set
+	select {
+	case err := <-watchErr:
+		t.Skipf("change streams unavailable: %v", err)
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	// another instance logs the session out
+	session.Options.MaxAge = -1
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v\n", err)
+	}
+
+	select {
+	case event := <-events:
+		if event.SessionID != session.ID {
+			t.Fatalf("expected a delete event for %s, got %+v", session.ID, event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delete event")
+	}
+}
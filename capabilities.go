@@ -0,0 +1,91 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Capabilities are the features of the backend the store relies on. The
+// store probes them when it is created, and falls back to doing the work
+// itself when the backend lacks one:
+//
+//   - without TTLIndexes the time to live index is not created and the
+//     expired sessions are purged every Options.PurgeInterval, a minute by
+//     default
+//   - without Transactions the events of Options.OutboxCollection are
+//     written after the sessions instead of in the same transaction, an
+//     event can be lost if the process dies in between
+//   - without ChangeStreams WatchDeletes returns ErrUnsupported at once
+type Capabilities struct {
+	TTLIndexes    bool
+	Transactions  bool
+	ChangeStreams bool
+}
+
+// Error codes of the servers for the commands and options they do not
+// implement.
+const (
+	commandNotFoundCode     = 59
+	commandNotSupportedCode = 115
+	notImplementedCode      = 238
+)
+
+// Capabilities returns the features of the backend, as set with
+// Options.Capabilities or probed when the store was created.
+func (s *Store) Capabilities() Capabilities {
+	return s.capabilities
+}
+
+// probeCapabilities finds the features the backend supports. The time to
+// live index is probed when it is created, see insertTTL.
+func (s *Store) probeCapabilities(ctx context.Context) Capabilities {
+	caps := Capabilities{TTLIndexes: true}
+	col := s.MongoStore.Collection
+
+	// transactions need a replica set, or a backend emulating one
+	err := col.Database().Client().UseSession(ctx, func(sc mongo.SessionContext) error {
+		err := sc.StartTransaction()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = sc.AbortTransaction(context.Background()) }()
+
+		err = col.FindOne(sc, bson.M{"_id": nil}).Err()
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	})
+	caps.Transactions = err == nil
+	if err != nil {
+		s.log(ctx, slog.LevelInfo, "transactions not supported", slog.String("backend", s.backend.String()), errorAttr(err))
+	}
+
+	stream, err := col.Watch(ctx, mongo.Pipeline{})
+	caps.ChangeStreams = err == nil
+	if err != nil {
+		s.log(ctx, slog.LevelInfo, "change streams not supported", slog.String("backend", s.backend.String()), errorAttr(err))
+	} else {
+		_ = stream.Close(ctx)
+	}
+
+	return caps
+}
+
+// isUnsupported reports if the error is the server rejecting a command or
+// option it does not implement.
+func isUnsupported(err error) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	switch cmdErr.Code {
+	case commandNotFoundCode, commandNotSupportedCode, notImplementedCode, featureNotSupportedCode:
+		return true
+	}
+	return false
+}
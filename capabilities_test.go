@@ -0,0 +1,84 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestProbeCapabilities(t *testing.T) {
+	store := newTestStore(t, "sessions_capabilities_test")
+
+	if !store.Capabilities().TTLIndexes {
+		t.Fatal("expected time to live indexes to be supported")
+	}
+}
+
+func TestCapabilitiesFallbacks(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_fallbacks_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:    col,
+			Capabilities:  &mongostore.Capabilities{},
+			PurgeInterval: 50 * time.Millisecond,
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+	defer store.Close(context.TODO())
+
+	if indexNames(t, store)["ttl_1"] {
+		t.Fatal("expected no time to live index")
+	}
+	err = store.Ping(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to ping: %v\n", err)
+	}
+
+	err = store.WatchDeletes(context.TODO(), func(mongostore.DeleteEvent) {})
+	if !errors.Is(err, mongostore.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+
+	// the janitor purges the expired sessions instead of the index
+	_, err = col.InsertOne(context.TODO(), bson.M{
+		"_id":        primitive.NewObjectID(),
+		"expires_at": primitive.NewDateTimeFromTime(time.Now().Add(-time.Minute)),
+	})
+	if err != nil {
+		t.Fatalf("failed to insert expired session: %v\n", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		count, err := col.CountDocuments(context.TODO(), bson.M{})
+		if err != nil {
+			t.Fatalf("failed to count sessions: %v\n", err)
+		}
+		if count == 0 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("expected the expired session to be purged")
+}
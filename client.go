@@ -0,0 +1,46 @@
+package mongostore
+
+import (
+	"net"
+	"net/http"
+)
+
+// ClientInfo describes the client that created a session.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+
+	// RequestID is the id of the request, from Options.RequestIDFunc. It is
+	// filled in by the store, not by the ClientInfoFunc.
+	RequestID string
+}
+
+// DefaultClientInfo extracts the remote IP and User-Agent from the request.
+//
+// The IP is taken from r.RemoteAddr, applications running behind a proxy
+// should set Options.ClientInfoFunc to read X-Forwarded-For or similar.
+func DefaultClientInfo(r *http.Request) ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RemoteAddr has no port
+		ip = r.RemoteAddr
+	}
+
+	return ClientInfo{
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// clientInfo returns the client info for the request using the configured
+// extraction hook, or the default one.
+func (s *Store) clientInfo(r *http.Request) ClientInfo {
+	info := DefaultClientInfo
+	if s.MongoStore.ClientInfoFunc != nil {
+		info = s.MongoStore.ClientInfoFunc
+	}
+
+	client := info(r)
+	client.RequestID = s.requestID(r.Context())
+	return client
+}
@@ -0,0 +1,64 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestDefaultClientInfo(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", "test-agent")
+
+	info := mongostore.DefaultClientInfo(req)
+	if info.IP != "192.0.2.1" {
+		t.Fatalf("expected ip 192.0.2.1, got %q", info.IP)
+	}
+	if info.UserAgent != "test-agent" {
+		t.Fatalf("expected user agent test-agent, got %q", info.UserAgent)
+	}
+
+	// no port in the remote address
+	req.RemoteAddr = "192.0.2.1"
+	info = mongostore.DefaultClientInfo(req)
+	if info.IP != "192.0.2.1" {
+		t.Fatalf("expected ip 192.0.2.1, got %q", info.IP)
+	}
+}
+
+func TestRecordClientInfo(t *testing.T) {
+	store := newTestStore(t, "sessions_client_test")
+	store.MongoStore.RecordClientInfo = true
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("User-Agent", "test-agent")
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	mongoSession := &mongostore.MongoSession{}
+	err = store.MongoStore.Collection.FindOne(
+		context.TODO(),
+		bson.M{"ip": "192.0.2.1", "user_agent": "test-agent"},
+	).Decode(mongoSession)
+	if err != nil {
+		t.Fatalf("failed to find session with client info: %v\n", err)
+	}
+	if mongoSession.Created == 0 {
+		t.Fatal("expected created_at to be set")
+	}
+}
@@ -0,0 +1,26 @@
+package mongostore
+
+import (
+	"time"
+)
+
+// Clock tells the store the current time, for the modified, expiry and time
+// to live dates of sessions and for expiry checks.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock used when Options.Clock is nil.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// now returns the current time of the store clock.
+func (s *Store) now() time.Time {
+	if s.MongoStore.Clock == nil {
+		return systemClock{}.Now()
+	}
+	return s.MongoStore.Clock.Now()
+}
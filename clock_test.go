@@ -0,0 +1,57 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testClock is a clock the tests move forward.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func TestClock(t *testing.T) {
+	store := newTestStore(t, "sessions_clock_test")
+	clock := &testClock{now: time.Now()}
+	store.MongoStore.Clock = clock
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["foo"] = "bar"
+
+	res := httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	// still valid before its max age
+	clock.now = clock.now.Add(200 * time.Second)
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if session.IsNew {
+		t.Fatal("expected the session to be valid")
+	}
+
+	// expired once the clock passes its max age of 240 seconds
+	clock.now = clock.now.Add(time.Minute)
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatal("expected the session to have expired")
+	}
+}
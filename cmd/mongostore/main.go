@@ -0,0 +1,218 @@
+// Command mongostore inspects and revokes the sessions of a mongostore
+// collection.
+//
+// Usage:
+//
+//	mongostore [flags] list [-user id] [-limit n] [-after token] [-oldest]
+//	mongostore [flags] get <session id>
+//	mongostore [flags] delete <session id>
+//	mongostore [flags] purge
+//	mongostore [flags] count
+//	mongostore [flags] decode <cookie name> <cookie value>
+//
+// Sessions are printed as relaxed extended JSON, one per line, with the
+// values of the keys matching the -redact globs masked. Decoding a
+// cookie needs the keys of the application, -auth-key and -enc-key take the
+// same values as the key pairs passed to mongostore.NewStore.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/glezjose/mongostore"
+)
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mongostore: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	flags := flag.NewFlagSet("mongostore", flag.ExitOnError)
+	uri := flags.String("uri", envOr("MONGODB_URI", "mongodb://localhost:27017"), "mongo connection string")
+	database := flags.String("database", "", "database of the sessions")
+	collection := flags.String("collection", "sessions", "collection of the sessions")
+	authKey := flags.String("auth-key", os.Getenv("GORILLA_SESSION_AUTH_KEY"), "authentication key, to decode cookies")
+	encKey := flags.String("enc-key", os.Getenv("GORILLA_SESSION_ENC_KEY"), "encryption key, to decode cookies")
+	opaque := flags.Bool("opaque-tokens", false, "the store uses opaque tokens")
+	redact := flags.String("redact", os.Getenv("MONGOSTORE_REDACT"), "comma separated globs of the data keys to mask")
+	timeout := flags.Duration("timeout", 30*time.Second, "timeout of the command")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: mongostore [flags] list|get|delete|purge|count|decode [args]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 || *database == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(*uri))
+	if err != nil {
+		return err
+	}
+	defer client.Disconnect(context.Background())
+
+	// an empty encryption key disables encryption
+	keyPairs := [][]byte{[]byte(*authKey)}
+	if *encKey != "" {
+		keyPairs = append(keyPairs, []byte(*encKey))
+	}
+
+	// the cli never creates indexes
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Context:           ctx,
+			Collection:        client.Database(*database).Collection(*collection),
+			OpaqueTokens:      *opaque,
+			SkipIndexCreation: true,
+			Redact:            redactGlobs(*redact),
+		},
+		http.Cookie{},
+		keyPairs...,
+	)
+	if err != nil {
+		return err
+	}
+
+	cmd, cmdArgs := flags.Arg(0), flags.Args()[1:]
+	switch cmd {
+	case "list":
+		return list(ctx, store, cmdArgs)
+
+	case "get":
+		if len(cmdArgs) != 1 {
+			return fmt.Errorf("usage: get <session id>")
+		}
+		mongoSession, err := store.FindSession(ctx, cmdArgs[0])
+		if err != nil {
+			return err
+		}
+		return printSession(store, mongoSession)
+
+	case "delete":
+		if len(cmdArgs) != 1 {
+			return fmt.Errorf("usage: delete <session id>")
+		}
+		return store.DeleteSession(ctx, cmdArgs[0])
+
+	case "purge":
+		deleted, err := store.PurgeExpired(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d expired session(s) deleted\n", deleted)
+		return nil
+
+	case "count":
+		count, err := store.CountSessions(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Println(count)
+		return nil
+
+	case "decode":
+		if len(cmdArgs) != 2 {
+			return fmt.Errorf("usage: decode <cookie name> <cookie value>")
+		}
+		id, err := store.DecodeCookie(cmdArgs[0], cmdArgs[1])
+		if err != nil {
+			return err
+		}
+		mongoSession, err := store.FindSession(ctx, id)
+		if err != nil {
+			return fmt.Errorf("session %s: %w", id, err)
+		}
+		return printSession(store, mongoSession)
+
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+// list prints a page of sessions, newest first, and the token of the next
+// page on stderr.
+func list(ctx context.Context, store *mongostore.Store, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	user := flags.String("user", "", "only list the sessions of the user")
+	skip := flags.Int64("skip", 0, "number of sessions to skip")
+	limit := flags.Int64("limit", 100, "maximum number of sessions, 0 for all")
+	after := flags.String("after", "", "list the sessions after the page token")
+	oldest := flags.Bool("oldest", false, "list the oldest sessions first")
+	_ = flags.Parse(args)
+
+	opts := mongostore.ListOptions{
+		UserID: *user,
+		Skip:   *skip,
+		Limit:  *limit,
+		After:  *after,
+	}
+	if *oldest {
+		opts.Sort = mongostore.ListOldest
+	}
+
+	mongoSessions, err := store.ListSessions(ctx, opts)
+	if err != nil {
+		return err
+	}
+	if *limit > 0 && int64(len(mongoSessions)) == *limit {
+		defer fmt.Fprintln(os.Stderr, "next page: -after", mongostore.PageToken(mongoSessions[len(mongoSessions)-1]))
+	}
+
+	for _, mongoSession := range mongoSessions {
+		err = printSession(store, mongoSession)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printSession writes a session as relaxed extended JSON, redacted.
+func printSession(store *mongostore.Store, mongoSession *mongostore.MongoSession) error {
+	b, err := bson.MarshalExtJSON(store.Redact(mongoSession), false, false)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Println(string(b))
+	return err
+}
+
+// redactGlobs splits the globs of the -redact flag.
+func redactGlobs(value string) []string {
+	var globs []string
+	for _, glob := range strings.Split(value, ",") {
+		if glob = strings.TrimSpace(glob); glob != "" {
+			globs = append(globs, glob)
+		}
+	}
+	return globs
+}
+
+// envOr returns the environment variable, or a default value.
+func envOr(key string, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -0,0 +1,90 @@
+package mongostore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// coalesceSweep is the number of recorded writes over which the expired ones
+// are removed.
+const coalesceSweep = 1024
+
+// coalescer remembers the last write of each session with
+// Options.CoalesceWindow.
+type coalescer struct {
+	mu      sync.Mutex
+	written map[string]coalescedWrite // by session id
+}
+
+// coalescedWrite is the last write of a session.
+type coalescedWrite struct {
+	fingerprint string
+	at          time.Time
+}
+
+// coalesced reports if the session is the same as when it was last written,
+// less than Options.CoalesceWindow ago, so the update can be skipped.
+func (s *Store) coalesced(session *sessions.Session) bool {
+	window := s.MongoStore.CoalesceWindow
+	if window <= 0 || session.ID == "" {
+		return false
+	}
+
+	c := &s.coalescer
+	c.mu.Lock()
+	last, ok := c.written[session.ID]
+	c.mu.Unlock()
+	if !ok || s.now().Sub(last.at) >= window {
+		return false
+	}
+
+	fp, err := s.fingerprint(session)
+	return err == nil && fp == last.fingerprint
+}
+
+// recordWrite remembers the write of the session for coalesced, and for
+// Options.ReadYourWrites.
+func (s *Store) recordWrite(session *sessions.Session) {
+	s.markWritten(session.ID)
+
+	window := s.MongoStore.CoalesceWindow
+	if window <= 0 || session.ID == "" {
+		return
+	}
+
+	fp, err := s.fingerprint(session)
+	if err != nil {
+		return
+	}
+
+	now := s.now()
+	c := &s.coalescer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.written == nil {
+		c.written = make(map[string]coalescedWrite)
+	}
+	if len(c.written) >= coalesceSweep {
+		for id, w := range c.written {
+			if now.Sub(w.at) >= window {
+				delete(c.written, id)
+			}
+		}
+	}
+	c.written[session.ID] = coalescedWrite{fingerprint: fp, at: now}
+}
+
+// forgetWrite forgets the last write of a deleted session, the delete is
+// remembered for Options.ReadYourWrites.
+func (s *Store) forgetWrite(id string) {
+	s.markWritten(id)
+
+	c := &s.coalescer
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.written, id)
+}
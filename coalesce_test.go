@@ -0,0 +1,68 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestCoalesceWindow(t *testing.T) {
+	store := newTestStore(t, "sessions_coalesce_test")
+	clock := &testClock{now: time.Now()}
+	store.MongoStore.Clock = clock
+	store.MongoStore.CoalesceWindow = 5 * time.Second
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	modified := func() time.Time {
+		var doc mongostore.MongoSession
+		err := store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Decode(&doc)
+		if err != nil {
+			t.Fatalf("failed to find session: %v\n", err)
+		}
+		return doc.Modified.Time()
+	}
+	inserted := modified()
+
+	// saving the same session again within the window writes nothing
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	clock.now = clock.now.Add(time.Second)
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if !modified().Equal(inserted) {
+		t.Fatal("expected the identical write to be coalesced")
+	}
+
+	// a change is written
+	session.Values["test"] = "changed"
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if modified().Equal(inserted) {
+		t.Fatal("expected the changed session to be written")
+	}
+}
@@ -0,0 +1,71 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// namespaceExistsCode is the error code of createCollection when the
+// collection exists.
+const namespaceExistsCode = 48
+
+// EnsureCollections creates the collections of the store that do not exist
+// yet: the session collections with Options.CollectionOptions, and the
+// audit collection with Options.AuditCollectionOptions. Collections without
+// options are left for mongo to create on the first write, and existing
+// collections are not changed, apart from the validator of EnsureSchema
+// when Options.SchemaValidation is set.
+//
+// NewStore calls it.
+func (s *Store) EnsureCollections(ctx context.Context) error {
+	if s.MongoStore.SchemaValidation {
+		err := s.EnsureSchema(ctx)
+		if err != nil {
+			return err
+		}
+	} else if s.MongoStore.CollectionOptions != nil {
+		for _, col := range s.sessionCollections() {
+			_, err := createCollection(ctx, col, s.MongoStore.CollectionOptions)
+			if err != nil {
+				return fmt.Errorf("mongostore: creating %s: %w", col.Name(), err)
+			}
+		}
+	}
+
+	if s.MongoStore.AuditCollection != nil && s.MongoStore.AuditCollectionOptions != nil {
+		col := s.MongoStore.AuditCollection
+		_, err := createCollection(ctx, col, s.MongoStore.AuditCollectionOptions)
+		if err != nil {
+			return fmt.Errorf("mongostore: creating %s: %w", col.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// sessionCollections returns the collections holding the sessions.
+func (s *Store) sessionCollections() []*mongo.Collection {
+	cols := []*mongo.Collection{s.MongoStore.Collection}
+	if s.MongoStore.Secondary != nil {
+		cols = append(cols, s.MongoStore.Secondary)
+	}
+	return cols
+}
+
+// createCollection creates the collection with the options, it reports
+// false if the collection already exists.
+func createCollection(ctx context.Context, col *mongo.Collection, opts ...*options.CreateCollectionOptions) (bool, error) {
+	err := col.Database().CreateCollection(ctx, col.Name(), opts...)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == namespaceExistsCode {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
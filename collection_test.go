@@ -0,0 +1,70 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/glezjose/mongostore"
+)
+
+// collectionOptions returns the options of a collection.
+func collectionOptions(t *testing.T, name string) bson.Raw {
+	t.Helper()
+
+	specs, err := mongoclient.Database("test-database").ListCollectionSpecifications(context.TODO(), bson.M{"name": name})
+	if err != nil {
+		t.Fatalf("failed to list collections: %v\n", err)
+	}
+	if len(specs) != 1 {
+		t.Fatalf("expected collection %s, got %d", name, len(specs))
+	}
+	return specs[0].Options
+}
+
+func TestCollectionOptions(t *testing.T) {
+	// DocumentDB and Cosmos DB do not support collations
+	skipOnBackends(t, "documentdb", "cosmos")
+
+	db := mongoclient.Database("test-database")
+	col := db.Collection("sessions_collection_test")
+	audit := db.Collection("sessions_collection_audit_test")
+	for _, c := range []string{col.Name(), audit.Name()} {
+		err := db.Collection(c).Drop(context.TODO())
+		if err != nil {
+			t.Fatalf("failed to drop test collection: %v\n", err)
+		}
+	}
+
+	_, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection: col,
+			CollectionOptions: options.CreateCollection().
+				SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+			AuditCollection: audit,
+			AuditCollectionOptions: options.CreateCollection().
+				SetCapped(true).
+				SetSizeInBytes(1 << 20),
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	if locale, _ := collectionOptions(t, col.Name()).Lookup("collation", "locale").StringValueOK(); locale != "en" {
+		t.Fatalf("expected the en collation, got %q", locale)
+	}
+	if capped, _ := collectionOptions(t, audit.Name()).Lookup("capped").BooleanOK(); !capped {
+		t.Fatal("expected a capped audit collection")
+	}
+}
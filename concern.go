@@ -0,0 +1,62 @@
+package mongostore
+
+import (
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// readCollection returns the collection used to read sessions, with the
+// configured read preference and read concern.
+func (s *Store) readCollection() *mongo.Collection {
+	if s.MongoStore.ReadPreference == nil && s.MongoStore.ReadConcern == nil && s.MongoStore.Registry == nil {
+		return s.MongoStore.Collection
+	}
+
+	return s.cloneCollection(
+		options.Collection().
+			SetReadPreference(s.MongoStore.ReadPreference).
+			SetReadConcern(s.MongoStore.ReadConcern),
+	)
+}
+
+// writeCollection returns the collection used to insert and update
+// sessions, with the configured write concern.
+func (s *Store) writeCollection() *mongo.Collection {
+	if s.MongoStore.WriteConcern == nil && s.MongoStore.Registry == nil {
+		return s.MongoStore.Collection
+	}
+
+	return s.cloneCollection(
+		options.Collection().
+			SetWriteConcern(s.MongoStore.WriteConcern),
+	)
+}
+
+// deleteCollection returns the collection used to delete sessions, with the
+// configured delete write concern.
+func (s *Store) deleteCollection() *mongo.Collection {
+	if s.MongoStore.DeleteWriteConcern == nil {
+		return s.writeCollection()
+	}
+
+	return s.cloneCollection(
+		options.Collection().
+			SetWriteConcern(s.MongoStore.DeleteWriteConcern),
+	)
+}
+
+// cloneCollection returns a copy of the collection with the given options,
+// and the configured registry.
+func (s *Store) cloneCollection(opts *options.CollectionOptions) *mongo.Collection {
+	if s.MongoStore.Registry != nil {
+		opts.SetRegistry(s.MongoStore.Registry)
+	}
+
+	col, err := s.MongoStore.Collection.Clone(opts)
+	if err != nil {
+		// Clone does not fail in practice, keep the collection defaults
+		return s.MongoStore.Collection
+	}
+
+	return col
+}
@@ -0,0 +1,50 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+func TestConcerns(t *testing.T) {
+	store := newTestStore(t, "sessions_concern_test")
+	store.MongoStore.ReadPreference = readpref.PrimaryPreferred()
+	store.MongoStore.ReadConcern = readconcern.Local()
+	store.MongoStore.WriteConcern = writeconcern.W1()
+	store.MongoStore.DeleteWriteConcern = writeconcern.Majority()
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", res.Header()["Set-Cookie"][0])
+	res = httptest.NewRecorder()
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+
+	session.Options.MaxAge = -1
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v\n", err)
+	}
+}
@@ -0,0 +1,257 @@
+package mongostore
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxConflictRetries is the number of times an update is resolved and
+// written again when other requests keep saving the session meanwhile.
+const maxConflictRetries = 3
+
+// conflictTombstoneAge is how long the time of a deleted key is kept, so a
+// request loaded before the delete does not bring the key back.
+const conflictTombstoneAge = 10 * time.Minute
+
+// Conflict is a session saved by another request since it was loaded by the
+// request saving it, passed to Options.ConflictResolver.
+type Conflict struct {
+	// Session is the session being saved.
+	Session *sessions.Session
+
+	// Mine are the values being saved, Theirs the values saved by the
+	// other request. They leave out the metadata of the store.
+	Mine   map[interface{}]interface{}
+	Theirs map[interface{}]interface{}
+
+	// MineModified and TheirsModified are when each key was last set or
+	// deleted on each side, a deleted key has a time but no value. Keys
+	// saved before Options.ConflictResolver was set have no time.
+	MineModified   map[interface{}]time.Time
+	TheirsModified map[interface{}]time.Time
+}
+
+// ConflictResolver returns the values to save when the session was saved by
+// another request since it was loaded, or an error to fail the save.
+type ConflictResolver func(c *Conflict) (map[interface{}]interface{}, error)
+
+// LastWriteWins resolves a conflict by saving the values of the request
+// saving last, overwriting the other request.
+func LastWriteWins(c *Conflict) (map[interface{}]interface{}, error) {
+	return c.Mine, nil
+}
+
+// FailOnConflict resolves a conflict by failing the save with ErrConflict,
+// the request saving last loses its changes.
+func FailOnConflict(c *Conflict) (map[interface{}]interface{}, error) {
+	return nil, ErrConflict
+}
+
+// MergeKeys resolves a conflict by keeping each key as set or deleted last
+// by either request, so requests changing different keys both keep their
+// changes. Keys changed by both keep the change of the request saving last.
+func MergeKeys(c *Conflict) (map[interface{}]interface{}, error) {
+	merged := make(map[interface{}]interface{}, len(c.Mine))
+
+	keys := make(map[interface{}]bool)
+	for _, m := range []map[interface{}]interface{}{c.Mine, c.Theirs} {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+	for _, m := range []map[interface{}]time.Time{c.MineModified, c.TheirsModified} {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+
+	for k := range keys {
+		values := c.Mine
+		if c.TheirsModified[k].After(c.MineModified[k]) {
+			values = c.Theirs
+		}
+		if v, ok := values[k]; ok {
+			merged[k] = v
+		}
+	}
+
+	return merged, nil
+}
+
+// loadedVersion is the version of a session as loaded or last saved, kept
+// under versionKey with Options.ConflictResolver.
+type loadedVersion struct {
+	version  int64
+	data     primitive.M // the stored data, to find the keys changed since
+	modified map[string]primitive.DateTime
+
+	// next is the version being written by the update of the session
+	next *loadedVersion
+}
+
+// recordVersion remembers the version of the session loaded from mongo.
+func (s *Store) recordVersion(session *sessions.Session, mongoSession *MongoSession) {
+	if s.MongoStore.ConflictResolver == nil {
+		return
+	}
+
+	session.Values[versionKey] = &loadedVersion{
+		version:  mongoSession.Version,
+		data:     s.sessionData(session),
+		modified: mongoSession.KeyModified,
+	}
+}
+
+// sessionVersion returns the version of the session as loaded, zero for a
+// session inserted by this request.
+func sessionVersion(session *sessions.Session) *loadedVersion {
+	if v, ok := session.Values[versionKey].(*loadedVersion); ok {
+		return v
+	}
+	v := &loadedVersion{}
+	session.Values[versionKey] = v
+	return v
+}
+
+// nextVersion sets the version and the key times of the update of the
+// session, the next version once the update is written.
+func (s *Store) nextVersion(session *sessions.Session, mongoSession *MongoSession) {
+	loaded := sessionVersion(session)
+	data := s.sessionData(session)
+
+	loaded.next = &loadedVersion{
+		version:  loaded.version + 1,
+		data:     data,
+		modified: s.keyModified(session, data),
+	}
+	mongoSession.Version = loaded.next.version
+	mongoSession.KeyModified = loaded.next.modified
+}
+
+// keyModified returns when each key of the session was last set or deleted,
+// the keys changed since the session was loaded are set or deleted now.
+func (s *Store) keyModified(session *sessions.Session, data primitive.M) map[string]primitive.DateTime {
+	loaded := sessionVersion(session)
+	now := s.now()
+
+	modified := make(map[string]primitive.DateTime, len(data))
+	for k, at := range loaded.modified {
+		if _, ok := data[k]; !ok && now.Sub(at.Time()) >= conflictTombstoneAge {
+			continue
+		}
+		modified[k] = at
+	}
+	for k, v := range data {
+		if old, ok := loaded.data[k]; !ok || !reflect.DeepEqual(old, v) {
+			modified[k] = primitive.NewDateTimeFromTime(now)
+		}
+	}
+	for k := range loaded.data {
+		if _, ok := data[k]; !ok {
+			modified[k] = primitive.NewDateTimeFromTime(now)
+		}
+	}
+
+	return modified
+}
+
+// versionFilter adds the version the session was loaded at to the filter of
+// its update, so the update only matches if no other request saved it since.
+func versionFilter(session *sessions.Session, filter bson.M) bson.M {
+	versioned := make(bson.M, len(filter)+1)
+	for k, v := range filter {
+		versioned[k] = v
+	}
+
+	if version := sessionVersion(session).version; version > 0 {
+		versioned["version"] = version
+	} else {
+		versioned["version"] = bson.M{"$exists": false}
+	}
+	return versioned
+}
+
+// savedVersion remembers the version the session was just saved at, for the
+// next save of the same request.
+func savedVersion(session *sessions.Session) {
+	if next := sessionVersion(session).next; next != nil {
+		session.Values[versionKey] = next
+	}
+}
+
+// isConflict reports if the update of the session failed because another
+// request saved it since it was loaded: the update matched nothing while
+// the session is still stored, or its upsert collided with it.
+func isConflict(res *mongo.UpdateResult, err error) bool {
+	if err != nil {
+		return mongo.IsDuplicateKeyError(err)
+	}
+	return res.MatchedCount == 0 && res.UpsertedCount == 0
+}
+
+// resolveConflict loads the session as saved by the other request and sets
+// the values returned by Options.ConflictResolver on the session, to be
+// saved over the stored version. It returns ErrSessionNotFound when the
+// session was deleted instead.
+func (s *Store) resolveConflict(session *sessions.Session) error {
+	stored := s.storedSession(session)
+	err := s.findOneIn(stored, s.primaryCollection())
+	if err != nil {
+		return err
+	}
+	theirs := sessionVersion(stored)
+
+	mine := make(map[interface{}]interface{}, len(session.Values))
+	for k, v := range session.Values {
+		if _, ok := k.(metaKey); !ok {
+			mine[k] = v
+		}
+	}
+	mineModified := make(map[interface{}]time.Time)
+	for k, at := range s.keyModified(session, s.sessionData(session)) {
+		mineModified[s.decodeKey(k)] = at.Time()
+	}
+
+	c := &Conflict{
+		Session:        session,
+		Mine:           mine,
+		Theirs:         make(map[interface{}]interface{}, len(stored.Values)),
+		MineModified:   mineModified,
+		TheirsModified: make(map[interface{}]time.Time, len(theirs.modified)),
+	}
+	for k, v := range stored.Values {
+		if _, ok := k.(metaKey); !ok {
+			c.Theirs[k] = v
+		}
+	}
+	for k, at := range theirs.modified {
+		c.TheirsModified[s.decodeKey(k)] = at.Time()
+	}
+
+	resolved, err := s.MongoStore.ConflictResolver(c)
+	if err != nil {
+		return err
+	}
+	if resolved == nil {
+		return errors.New("mongostore: conflict resolver returned no values")
+	}
+
+	for k := range mine {
+		delete(session.Values, k)
+	}
+	for k, v := range resolved {
+		session.Values[k] = v
+	}
+
+	// the resolved values are saved over the stored version, the keys
+	// differing from it are changed now
+	session.Values[versionKey] = theirs
+	return nil
+}
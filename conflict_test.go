@@ -0,0 +1,89 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/glezjose/mongostore"
+)
+
+// concurrentSessions saves a session, then loads it twice as two concurrent
+// requests would.
+func concurrentSessions(t *testing.T, store *mongostore.Store) (*http.Request, *sessions.Session, *sessions.Session) {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["a"] = "a0"
+	session.Values["b"] = "b0"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	first, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	second, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	return req, first, second
+}
+
+func TestConflictResolver(t *testing.T) {
+	store := newTestStore(t, "sessions_conflict_test")
+
+	for _, test := range []struct {
+		name     string
+		resolver mongostore.ConflictResolver
+		err      error
+		a, b     interface{}
+	}{
+		{name: "merge", resolver: mongostore.MergeKeys, a: "a1", b: "b2"},
+		{name: "last write wins", resolver: mongostore.LastWriteWins, a: "a0", b: "b2"},
+		{name: "fail", resolver: mongostore.FailOnConflict, err: mongostore.ErrConflict, a: "a1", b: "b0"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			store.MongoStore.ConflictResolver = test.resolver
+			req, first, second := concurrentSessions(t, store)
+
+			first.Values["a"] = "a1"
+			err := store.Save(req, httptest.NewRecorder(), first)
+			if err != nil {
+				t.Fatalf("failed to save session: %v\n", err)
+			}
+
+			second.Values["b"] = "b2"
+			err = store.Save(req, httptest.NewRecorder(), second)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+
+			stored, err := store.New(req, "test-session")
+			if err != nil {
+				t.Fatalf("failed to get session: %v\n", err)
+			}
+			if stored.Values["a"] != test.a || stored.Values["b"] != test.b {
+				t.Fatalf("expected a=%v b=%v, got %v", test.a, test.b, stored.Values)
+			}
+
+			err = store.MongoStore.Collection.Drop(context.TODO())
+			if err != nil {
+				t.Fatalf("failed to drop test collection: %v\n", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+// Package chistore plugs a mongostore.Store into chi.
+//
+//	r := chi.NewRouter()
+//	r.Use(chistore.Middleware(store, "session"))
+//
+// The middleware loads the session before the handler and saves it when it
+// was modified, handlers get it with Session and never call Save.
+package chistore
+
+import (
+	"net/http"
+
+	gsessions "github.com/gorilla/sessions"
+
+	"github.com/glezjose/mongostore"
+)
+
+// Middleware returns a chi middleware loading the session of the given name
+// and saving it if modified, see mongostore.Store.Middleware.
+func Middleware(store *mongostore.Store, name string) func(http.Handler) http.Handler {
+	return store.Middleware(name)
+}
+
+// Session returns the session loaded by the innermost Middleware, or nil if
+// the request did not go through one.
+func Session(r *http.Request) *gsessions.Session {
+	return mongostore.FromContext(r.Context())
+}
+
+// NamedSession returns the session of the given name loaded by Middleware, or
+// nil if the request did not go through one for the name.
+func NamedSession(r *http.Request, name string) *gsessions.Session {
+	return mongostore.NamedFromContext(r.Context(), name)
+}
@@ -0,0 +1,33 @@
+package chistore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/glezjose/mongostore/contrib/chistore"
+	"github.com/glezjose/mongostore/contrib/internal/storetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := storetest.NewStore(t, "sessions_chi_test")
+
+	r := chi.NewRouter()
+	r.Use(chistore.Middleware(store, "test-session"))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		session := chistore.Session(r)
+		if session != chistore.NamedSession(r, "test-session") {
+			t.Fatal("expected the same session by name")
+		}
+		session.Values["foo"] = "bar"
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	if res.Header().Get("Set-Cookie") == "" {
+		t.Fatal("expected the session to be saved")
+	}
+}
@@ -0,0 +1,37 @@
+// Package echostore plugs a mongostore.Store into echo.
+//
+// A *mongostore.Store is a gorilla store, so it works with the session
+// middleware of echo-contrib as is:
+//
+//	e.Use(session.Middleware(store))
+//
+// Middleware instead loads the session before the handler and saves it when
+// it was modified, handlers get it with Get and never call Save:
+//
+//	e.Use(echostore.Middleware(store, "session"))
+package echostore
+
+import (
+	gsessions "github.com/gorilla/sessions"
+	"github.com/labstack/echo/v4"
+
+	"github.com/glezjose/mongostore"
+)
+
+// Middleware returns an echo middleware loading the session of the given
+// name and saving it if modified, see mongostore.Store.Middleware.
+func Middleware(store *mongostore.Store, name string) echo.MiddlewareFunc {
+	return echo.WrapMiddleware(store.Middleware(name))
+}
+
+// Get returns the session loaded by the innermost Middleware, or nil if the
+// request did not go through one.
+func Get(c echo.Context) *gsessions.Session {
+	return mongostore.FromContext(c.Request().Context())
+}
+
+// GetNamed returns the session of the given name loaded by Middleware, or
+// nil if the request did not go through one for the name.
+func GetNamed(c echo.Context, name string) *gsessions.Session {
+	return mongostore.NamedFromContext(c.Request().Context(), name)
+}
@@ -0,0 +1,59 @@
+package echostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	"github.com/glezjose/mongostore/contrib/echostore"
+	"github.com/glezjose/mongostore/contrib/internal/storetest"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := storetest.NewStore(t, "sessions_echo_test")
+
+	e := echo.New()
+	e.Use(echostore.Middleware(store, "test-session"))
+	e.GET("/", func(c echo.Context) error {
+		session := echostore.Get(c)
+		if session != echostore.GetNamed(c, "test-session") {
+			t.Fatal("expected the same session by name")
+		}
+		session.Values["foo"] = "bar"
+		return c.String(http.StatusOK, "ok")
+	})
+
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	if res.Header().Get("Set-Cookie") == "" {
+		t.Fatal("expected the session to be saved")
+	}
+}
+
+func TestEchoContrib(t *testing.T) {
+	store := storetest.NewStore(t, "sessions_echo_test")
+
+	e := echo.New()
+	e.Use(session.Middleware(store))
+	e.GET("/", func(c echo.Context) error {
+		sess, err := session.Get("test-session", c)
+		if err != nil {
+			return err
+		}
+		sess.Values["foo"] = "bar"
+		err = sess.Save(c.Request(), c.Response())
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+	if res.Code != http.StatusOK || res.Header().Get("Set-Cookie") == "" {
+		t.Fatalf("expected the session to be saved, got %d", res.Code)
+	}
+}
@@ -0,0 +1,79 @@
+// Package ginstore adapts a mongostore.Store to gin-contrib/sessions.
+//
+//	router.Use(sessions.Sessions("session", ginstore.NewStore(store)))
+//
+// Handlers use sessions.Default as with any other store, or Default for the
+// underlying gorilla session, to pass it to the methods of the store such as
+// SetOwner.
+package ginstore
+
+import (
+	"log/slog"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	gsessions "github.com/gorilla/sessions"
+
+	"github.com/glezjose/mongostore"
+)
+
+// Store is a mongostore.Store usable with gin-contrib/sessions.
+type Store struct {
+	*mongostore.Store
+}
+
+// NewStore returns store as a gin-contrib/sessions store.
+func NewStore(store *mongostore.Store) sessions.Store {
+	return &Store{Store: store}
+}
+
+// Options sets the default cookie options of the store. A different MaxAge
+// also updates the time to live index, like mongostore.Store.MaxAge.
+func (s *Store) Options(options sessions.Options) {
+	maxAge := s.Store.CookieStore.Options.MaxAge
+	s.Store.CookieStore.Options = options.ToGorillaOptions()
+	s.Store.CookieStore.Options.MaxAge = maxAge
+
+	if options.MaxAge != maxAge {
+		err := s.Store.MaxAge(options.MaxAge)
+		if err != nil {
+			s.logError("setting max age", err)
+		}
+	}
+}
+
+// logError logs an error of the adapter with the logger of the store,
+// Options.Logger or slog.Default(), unless Options.LogLevel is above errors.
+func (s *Store) logError(msg string, err error) {
+	level := s.Store.MongoStore.LogLevel
+	if level != nil && slog.LevelError < level.Level() {
+		return
+	}
+
+	logger := s.Store.MongoStore.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.LogAttrs(s.Store.MongoStore.Context, slog.LevelError, msg, slog.Any("error", err))
+}
+
+// Default returns the gorilla session behind sessions.Default(c).
+func Default(c *gin.Context) *gsessions.Session {
+	return underlying(sessions.Default(c))
+}
+
+// DefaultMany returns the gorilla session behind
+// sessions.DefaultMany(c, name).
+func DefaultMany(c *gin.Context, name string) *gsessions.Session {
+	return underlying(sessions.DefaultMany(c, name))
+}
+
+// underlying returns the gorilla session of a gin-contrib session, it is nil
+// if the session could not be loaded.
+func underlying(session sessions.Session) *gsessions.Session {
+	s, ok := session.(interface{ Session() *gsessions.Session })
+	if !ok {
+		return nil
+	}
+	return s.Session()
+}
@@ -0,0 +1,54 @@
+package ginstore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/tester"
+	"github.com/gin-gonic/gin"
+
+	"github.com/glezjose/mongostore/contrib/ginstore"
+	"github.com/glezjose/mongostore/contrib/internal/storetest"
+)
+
+func newStore(t *testing.T) sessions.Store {
+	return ginstore.NewStore(storetest.NewStore(t, "sessions_gin_test"))
+}
+
+func TestGinStore(t *testing.T) {
+	tester.GetSet(t, newStore)
+	tester.DeleteKey(t, newStore)
+	tester.Flashes(t, newStore)
+	tester.Clear(t, newStore)
+	tester.Many(t, newStore)
+}
+
+func TestDefault(t *testing.T) {
+	store := storetest.NewStore(t, "sessions_gin_test")
+
+	r := gin.New()
+	r.Use(sessions.Sessions("test-session", ginstore.NewStore(store)))
+	r.GET("/", func(c *gin.Context) {
+		session := ginstore.Default(c)
+		store.SetOwner(session, "gin-user")
+		err := session.Save(c.Request, c.Writer)
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+		c.String(http.StatusOK, session.ID)
+	})
+
+	res := httptest.NewRecorder()
+	r.ServeHTTP(res, httptest.NewRequest("GET", "/", nil))
+
+	mongoSession, err := store.FindSession(context.Background(), res.Body.String())
+	if err != nil {
+		t.Fatalf("failed to find session: %v\n", err)
+	}
+	if mongoSession.UserID != "gin-user" {
+		t.Fatalf("expected the session of gin-user, got %q", mongoSession.UserID)
+	}
+}
@@ -0,0 +1,55 @@
+// Package storetest creates the stores used by the tests of the adapters.
+package storetest
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/glezjose/mongostore"
+)
+
+// NewStore returns a store using random keys and its own collection, on the
+// mongo at MONGODB_URI or on localhost.
+func NewStore(t *testing.T, collection string) *mongostore.Store {
+	t.Helper()
+
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mongoclient, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to mongo: %v\n", err)
+	}
+	t.Cleanup(func() { _ = mongoclient.Disconnect(context.Background()) })
+
+	// start from an empty collection
+	col := mongoclient.Database("test-database").Collection(collection)
+	err = col.Drop(ctx)
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	store, err := mongostore.NewStore(
+		col,
+		http.Cookie{Path: "/", MaxAge: 240, HttpOnly: true, SameSite: http.SameSiteStrictMode},
+		securecookie.GenerateRandomKey(32),
+		securecookie.GenerateRandomKey(16),
+	)
+	if err != nil {
+		t.Fatalf("failed to create test store: %v\n", err)
+	}
+
+	return store
+}
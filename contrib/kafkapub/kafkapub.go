@@ -0,0 +1,62 @@
+// Package kafkapub publishes the session events of a mongostore.Store to
+// Kafka.
+//
+// Kafka clients have no common interface, wrap the producer of the client in
+// a ProducerFunc, for example with segmentio/kafka-go:
+//
+//	w := &kafka.Writer{Addr: kafka.TCP("localhost:9092"), Topic: "sessions"}
+//	opts.Publisher = kafkapub.New(kafkapub.ProducerFunc(
+//		func(ctx context.Context, key, value []byte) error {
+//			return w.WriteMessages(ctx, kafka.Message{Key: key, Value: value})
+//		},
+//	))
+//
+// Events are written as JSON, keyed by session id so the events of a
+// session stay in order on one partition.
+package kafkapub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/glezjose/mongostore"
+)
+
+// Producer writes a message to the topic of the events.
+type Producer interface {
+	Produce(ctx context.Context, key, value []byte) error
+}
+
+// ProducerFunc adapts a function to the Producer interface.
+type ProducerFunc func(ctx context.Context, key, value []byte) error
+
+// Produce calls f.
+func (f ProducerFunc) Produce(ctx context.Context, key, value []byte) error {
+	return f(ctx, key, value)
+}
+
+// Publisher is a mongostore.Publisher writing to Kafka.
+type Publisher struct {
+	producer Producer
+}
+
+// New returns a publisher writing the events with producer.
+func New(producer Producer) *Publisher {
+	return &Publisher{producer: producer}
+}
+
+// Publish writes the event as JSON.
+func (p *Publisher) Publish(ctx context.Context, event mongostore.SessionEvent) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("kafkapub: encoding event: %w", err)
+	}
+
+	err = p.producer.Produce(ctx, []byte(event.SessionID), value)
+	if err != nil {
+		return fmt.Errorf("kafkapub: publishing event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package kafkapub_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+	"github.com/glezjose/mongostore/contrib/kafkapub"
+)
+
+func TestPublish(t *testing.T) {
+	var key, value []byte
+	p := kafkapub.New(kafkapub.ProducerFunc(func(ctx context.Context, k, v []byte) error {
+		key, value = k, v
+		return nil
+	}))
+
+	err := p.Publish(context.Background(), mongostore.SessionEvent{Type: mongostore.SessionDestroyed, SessionID: "abc"})
+	if err != nil {
+		t.Fatalf("failed to publish event: %v\n", err)
+	}
+
+	var event mongostore.SessionEvent
+	err = json.Unmarshal(value, &event)
+	if err != nil {
+		t.Fatalf("failed to decode event: %v\n", err)
+	}
+	if string(key) != "abc" || event.Type != mongostore.SessionDestroyed {
+		t.Fatalf("unexpected message %s %s", key, value)
+	}
+}
@@ -0,0 +1,49 @@
+// Package natspub publishes the session events of a mongostore.Store to
+// NATS.
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	opts.Publisher = natspub.New(nc, "sessions")
+//
+// Events are published as JSON on the subject followed by the event type,
+// such as "sessions.session.created".
+package natspub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/glezjose/mongostore"
+)
+
+// Conn publishes messages, a *nats.Conn is one.
+type Conn interface {
+	Publish(subject string, data []byte) error
+}
+
+// Publisher is a mongostore.Publisher writing to NATS.
+type Publisher struct {
+	conn   Conn
+	prefix string
+}
+
+// New returns a publisher writing the events on subjects starting with
+// prefix.
+func New(conn Conn, prefix string) *Publisher {
+	return &Publisher{conn: conn, prefix: prefix}
+}
+
+// Publish writes the event as JSON.
+func (p *Publisher) Publish(ctx context.Context, event mongostore.SessionEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("natspub: encoding event: %w", err)
+	}
+
+	err = p.conn.Publish(p.prefix+"."+string(event.Type), data)
+	if err != nil {
+		return fmt.Errorf("natspub: publishing event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package natspub_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+	"github.com/glezjose/mongostore/contrib/natspub"
+)
+
+type conn struct {
+	subject string
+	data    []byte
+}
+
+func (c *conn) Publish(subject string, data []byte) error {
+	c.subject, c.data = subject, data
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	c := &conn{}
+	p := natspub.New(c, "sessions")
+
+	err := p.Publish(context.Background(), mongostore.SessionEvent{Type: mongostore.SessionCreated, SessionID: "abc"})
+	if err != nil {
+		t.Fatalf("failed to publish event: %v\n", err)
+	}
+
+	var event mongostore.SessionEvent
+	err = json.Unmarshal(c.data, &event)
+	if err != nil {
+		t.Fatalf("failed to decode event: %v\n", err)
+	}
+	if c.subject != "sessions.session.created" || event.SessionID != "abc" {
+		t.Fatalf("unexpected message %s %s", c.subject, c.data)
+	}
+}
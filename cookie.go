@@ -0,0 +1,57 @@
+package mongostore
+
+import (
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// CookieAttributes are the cookie attributes of a session that a handler
+// changed from the store defaults, for example SameSite=None for the session
+// of a cross-site OAuth callback. They are stored with the session so every
+// later Save sends the same cookie.
+type CookieAttributes struct {
+	Path     string        `bson:"path"`
+	Domain   string        `bson:"domain"`
+	Secure   bool          `bson:"secure"`
+	HttpOnly bool          `bson:"http_only"`
+	SameSite http.SameSite `bson:"same_site"`
+}
+
+// cookieAttributes returns the cookie attributes of the session, or nil if
+// they are the store defaults.
+func (s *Store) cookieAttributes(session *sessions.Session) *CookieAttributes {
+	defaults := s.sessionOptions()
+	applyCookiePrefix(session.Name(), defaults)
+
+	opts := session.Options
+	if opts.Path == defaults.Path &&
+		opts.Domain == defaults.Domain &&
+		opts.Secure == defaults.Secure &&
+		opts.HttpOnly == defaults.HttpOnly &&
+		opts.SameSite == defaults.SameSite {
+		return nil
+	}
+
+	return &CookieAttributes{
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HttpOnly,
+		SameSite: opts.SameSite,
+	}
+}
+
+// applyCookieAttributes restores the cookie attributes stored with the
+// session.
+func applyCookieAttributes(session *sessions.Session, attrs *CookieAttributes) {
+	if attrs == nil {
+		return
+	}
+
+	session.Options.Path = attrs.Path
+	session.Options.Domain = attrs.Domain
+	session.Options.Secure = attrs.Secure
+	session.Options.HttpOnly = attrs.HttpOnly
+	session.Options.SameSite = attrs.SameSite
+}
@@ -0,0 +1,46 @@
+package mongostore
+
+import (
+	"context"
+	"log/slog"
+)
+
+// requestIDContextKey is the context key of the id set with WithRequestID.
+type requestIDContextKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the id of the request, read
+// by DefaultRequestID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, id)
+}
+
+// DefaultRequestID returns the id set on the context with WithRequestID.
+//
+// Applications using a tracing library or a request id middleware should set
+// Options.RequestIDFunc to read the id from there instead.
+func DefaultRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// requestID returns the id of the request of ctx using the configured
+// extractor, or the default one.
+func (s *Store) requestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if s.MongoStore.RequestIDFunc != nil {
+		return s.MongoStore.RequestIDFunc(ctx)
+	}
+	return DefaultRequestID(ctx)
+}
+
+// requestIDAttrs appends the request id of ctx to the attributes of a log
+// record, if there is one.
+func (s *Store) requestIDAttrs(ctx context.Context, attrs []slog.Attr) []slog.Attr {
+	id := s.requestID(ctx)
+	if id == "" {
+		return attrs
+	}
+	return append(attrs, slog.String("request_id", id))
+}
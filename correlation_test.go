@@ -0,0 +1,73 @@
+package mongostore_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+type traceKey struct{}
+
+func TestRequestID(t *testing.T) {
+	store := newTestStore(t, "sessions_correlation_test")
+
+	audit := mongoclient.Database("test-database").Collection("sessions_correlation_audit_test")
+	err := audit.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop audit collection: %v\n", err)
+	}
+	store.MongoStore.AuditCollection = audit
+
+	var buf bytes.Buffer
+	store.MongoStore.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	store.MongoStore.RequestIDFunc = func(ctx context.Context) string {
+		id, _ := ctx.Value(traceKey{}).(string)
+		return id
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), traceKey{}, "trace-1"))
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	if !strings.Contains(buf.String(), `"request_id":"trace-1"`) {
+		t.Fatalf("expected the request id in the logs, got %s", buf.String())
+	}
+
+	records, err := store.AuditLog(context.TODO(), mongostore.AuditQuery{RequestID: "trace-1"})
+	if err != nil {
+		t.Fatalf("failed to query audit log: %v\n", err)
+	}
+	if len(records) != 1 || records[0].Op != mongostore.AuditCreate {
+		t.Fatalf("expected the create record of the request, got %+v", records)
+	}
+
+	err = store.VerifyAuditLog(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to verify audit log: %v\n", err)
+	}
+}
+
+func TestDefaultRequestID(t *testing.T) {
+	ctx := mongostore.WithRequestID(context.Background(), "abc")
+	if id := mongostore.DefaultRequestID(ctx); id != "abc" {
+		t.Fatalf("expected abc, got %q", id)
+	}
+	if id := mongostore.DefaultRequestID(context.Background()); id != "" {
+		t.Fatalf("expected no request id, got %q", id)
+	}
+}
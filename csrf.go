@@ -0,0 +1,113 @@
+package mongostore
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+const (
+	// CSRFHeader is the request header CSRF reads the token from.
+	CSRFHeader = "X-CSRF-Token"
+
+	// CSRFField is the form field CSRF reads the token from when the header
+	// is not set.
+	CSRFField = "csrf_token"
+)
+
+// CSRFToken returns the CSRF token of the session, generating one if it has
+// none. The token is stored in the session document on the next Save, and
+// replaced by Elevate on login.
+func (s *Store) CSRFToken(session *sessions.Session) (string, error) {
+	err := s.Load(session)
+	if err != nil {
+		return "", err
+	}
+
+	if token := csrfToken(session); token != "" {
+		return token, nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return "", fmt.Errorf("mongostore: generating csrf token: %w", err)
+	}
+	session.Values[csrfKey] = token
+
+	return token, nil
+}
+
+// VerifyCSRF reports if token is the CSRF token of the session.
+func (s *Store) VerifyCSRF(session *sessions.Session, token string) bool {
+	err := s.Load(session)
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelWarn, "loading session to verify csrf token", errorAttr(err))
+		return false
+	}
+
+	expected := csrfToken(session)
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
+
+// CSRF returns a middleware rejecting with 403 Forbidden the unsafe requests
+// (not GET, HEAD, OPTIONS or TRACE) whose CSRFHeader, or CSRFField form
+// field, is not the CSRF token of the session with the given name. It uses
+// the session loaded by Middleware when there is one.
+func (s *Store) CSRF(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			session := NamedFromContext(r.Context(), name)
+			if session == nil {
+				var err error
+				session, err = s.Get(r, name)
+				if err != nil {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+			}
+
+			token := r.Header.Get(CSRFHeader)
+			if token == "" {
+				token = r.PostFormValue(CSRFField)
+			}
+
+			if !s.VerifyCSRF(session, token) {
+				s.log(r.Context(), slog.LevelWarn, "csrf token mismatch", slog.String("method", r.Method), slog.String("path", r.URL.Path))
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// csrfToken returns the CSRF token of the session, or an empty string.
+func csrfToken(session *sessions.Session) string {
+	token, _ := session.Values[csrfKey].(string)
+	return token
+}
+
+// rotateCSRF replaces the CSRF token of the session, if it has one.
+func rotateCSRF(session *sessions.Session) error {
+	if csrfToken(session) == "" {
+		return nil
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return fmt.Errorf("mongostore: generating csrf token: %w", err)
+	}
+	session.Values[csrfKey] = token
+
+	return nil
+}
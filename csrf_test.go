@@ -0,0 +1,73 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestCSRF(t *testing.T) {
+	store := newTestStore(t, "sessions_csrf_test")
+
+	// a form is rendered with the token
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	token, err := store.CSRFToken(session)
+	if err != nil {
+		t.Fatalf("failed to get csrf token: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	cookie := res.Header().Get("Set-Cookie")
+
+	handler := store.CSRF("test-session")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	post := func(token string) int {
+		req, _ := http.NewRequest("POST", "http://localhost:8080/", nil)
+		req.Header.Set("Cookie", cookie)
+		if token != "" {
+			req.Header.Set(mongostore.CSRFHeader, token)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	if code := post(""); code != http.StatusForbidden {
+		t.Fatalf("expected 403 without a token, got %d", code)
+	}
+	if code := post("forged"); code != http.StatusForbidden {
+		t.Fatalf("expected 403 with a forged token, got %d", code)
+	}
+	if code := post(token); code != http.StatusNoContent {
+		t.Fatalf("expected 204 with the token, got %d", code)
+	}
+
+	// the token changes on login
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", cookie)
+	res = httptest.NewRecorder()
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	err = store.Elevate(req, res, session, "user-1")
+	if err != nil {
+		t.Fatalf("failed to elevate session: %v\n", err)
+	}
+	if store.VerifyCSRF(session, token) {
+		t.Fatal("expected the csrf token to be rotated")
+	}
+}
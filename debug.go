@@ -0,0 +1,85 @@
+package mongostore
+
+import (
+	"expvar"
+	"sync/atomic"
+)
+
+// DebugStats are the counters of the operations of a store since it was
+// created, cheap enough to collect on every request.
+type DebugStats struct {
+	// Loads counts the sessions read from mongo, LoadMisses the cookies
+	// whose session was not found or expired
+	Loads      int64 `json:"loads"`
+	LoadMisses int64 `json:"load_misses"`
+
+	// FallbackLoads counts the sessions served by Options.Fallback
+	FallbackLoads int64 `json:"fallback_loads"`
+
+	// the writes of Save, by SaveOp
+	Inserts    int64 `json:"inserts"`
+	Updates    int64 `json:"updates"`
+	Deletes    int64 `json:"deletes"`
+	Queued     int64 `json:"queued"`
+	ClientSide int64 `json:"client_side"`
+	Skipped    int64 `json:"skipped"`
+
+	// DecodeFailures counts the cookies that could not be decoded,
+	// ReadFailures the sessions that could not be read from mongo
+	DecodeFailures int64 `json:"decode_failures"`
+	ReadFailures   int64 `json:"read_failures"`
+
+	// Retries counts the retried mongo operations, see Options.MaxRetries
+	Retries int64 `json:"retries"`
+}
+
+// DebugStats returns the counters of the store.
+func (s *Store) DebugStats() DebugStats {
+	c := &s.counters
+	return DebugStats{
+		Loads:          atomic.LoadInt64(&c.Loads),
+		LoadMisses:     atomic.LoadInt64(&c.LoadMisses),
+		FallbackLoads:  atomic.LoadInt64(&c.FallbackLoads),
+		Inserts:        atomic.LoadInt64(&c.Inserts),
+		Updates:        atomic.LoadInt64(&c.Updates),
+		Deletes:        atomic.LoadInt64(&c.Deletes),
+		Queued:         atomic.LoadInt64(&c.Queued),
+		ClientSide:     atomic.LoadInt64(&c.ClientSide),
+		Skipped:        atomic.LoadInt64(&c.Skipped),
+		DecodeFailures: atomic.LoadInt64(&c.DecodeFailures),
+		ReadFailures:   atomic.LoadInt64(&c.ReadFailures),
+		Retries:        atomic.LoadInt64(&c.Retries),
+	}
+}
+
+// PublishExpvar publishes DebugStats under the given name, so they show on
+// /debug/vars. Like expvar.Publish it panics if the name is already used.
+func (s *Store) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return s.DebugStats()
+	}))
+}
+
+// count increments a counter of the store.
+func (s *Store) count(counter *int64) {
+	atomic.AddInt64(counter, 1)
+}
+
+// countSave counts a write of Save.
+func (s *Store) countSave(op SaveOp) {
+	c := &s.counters
+	switch op {
+	case SaveInsert:
+		s.count(&c.Inserts)
+	case SaveUpdate:
+		s.count(&c.Updates)
+	case SaveDelete:
+		s.count(&c.Deletes)
+	case SaveQueued:
+		s.count(&c.Queued)
+	case SaveClientSide:
+		s.count(&c.ClientSide)
+	case SaveSkipped:
+		s.count(&c.Skipped)
+	}
+}
@@ -0,0 +1,47 @@
+package mongostore_test
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDebugStats(t *testing.T) {
+	store := newTestStore(t, "sessions_debug_test")
+	store.PublishExpvar("mongostore_debug_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	_, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: "tampered"})
+	_, _ = store.New(req, "test-session")
+
+	stats := store.DebugStats()
+	if stats.Inserts != 1 || stats.Loads != 1 || stats.DecodeFailures != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	published := expvar.Get("mongostore_debug_test").String()
+	if !strings.Contains(published, `"inserts":1`) {
+		t.Fatalf("expected the stats on expvar, got %s", published)
+	}
+}
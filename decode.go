@@ -0,0 +1,78 @@
+package mongostore
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// DecodePolicy is what New does with a cookie that can not be decoded.
+type DecodePolicy int
+
+const (
+	// DecodeLenient logs the cookie and returns a new session without an
+	// error. Saving the session replaces the cookie, or expires it if the
+	// session was left empty, as Middleware does for untouched sessions.
+	DecodeLenient DecodePolicy = iota
+
+	// DecodeReport returns a new session along with ErrCookieDecode, like
+	// CookieStore, so callers can carry on.
+	DecodeReport
+
+	// DecodeStrict returns a nil session with ErrCookieDecode, Middleware
+	// responds 400 Bad Request.
+	DecodeStrict
+)
+
+// decodeFailed returns the result of New for a session whose cookie could
+// not be decoded, according to Options.DecodePolicy.
+func (s *Store) decodeFailed(r *http.Request, session *sessions.Session, err error) (*sessions.Session, error) {
+	err = wrapError(ErrCookieDecode, err)
+	s.count(&s.counters.DecodeFailures)
+
+	switch s.MongoStore.DecodePolicy {
+	case DecodeStrict:
+		return nil, err
+	case DecodeReport:
+		return s.freshSession(r, session), err
+	}
+
+	s.log(r.Context(), slog.LevelWarn, "replacing session", slog.String("name", session.Name()), errorAttr(err))
+	fresh := s.freshSession(r, session)
+	fresh.Values[badCookieKey] = true
+
+	return fresh, nil
+}
+
+// freshSession returns a new session replacing one that could not be
+// decoded, sent back on the same transport.
+func (s *Store) freshSession(r *http.Request, session *sessions.Session) *sessions.Session {
+	fresh := s.newSession(r, session.Name())
+	if i, ok := session.Values[transportKey]; ok {
+		fresh.Values[transportKey] = i
+	}
+
+	return fresh
+}
+
+// expireBadCookie expires the cookie of a session that replaced one that
+// could not be decoded, when the session holds no values to write instead.
+// It reports if the cookie was expired.
+func (s *Store) expireBadCookie(w http.ResponseWriter, session *sessions.Session) bool {
+	if _, ok := session.Values[badCookieKey]; !ok {
+		return false
+	}
+	for k := range session.Values {
+		if _, ok := k.(metaKey); !ok {
+			return false
+		}
+	}
+	delete(session.Values, badCookieKey)
+
+	options := *session.Options
+	options.MaxAge = -1
+	s.writeTransport(w, session, "", &options)
+
+	return true
+}
@@ -0,0 +1,38 @@
+package mongostore
+
+import (
+	"net/http"
+)
+
+// Destroy logs the session of the given name out: it deletes the session
+// from mongo and from Options.Fallback, and expires the cookie. The values
+// of the session are cleared for the rest of the request.
+//
+// A cookie that can not be decoded is expired too, there is no stored
+// session to delete.
+func (s *Store) Destroy(r *http.Request, w http.ResponseWriter, name string) error {
+	s = s.route(name)
+
+	session, err := s.Get(r, name)
+	if err != nil || session == nil {
+		session = s.newSession(r, name)
+	}
+
+	session.Options.MaxAge = -1
+	err = s.Save(r, w, session)
+	if err != nil {
+		return err
+	}
+
+	if s.MongoStore.Fallback != nil && session.ID != "" {
+		s.MongoStore.Fallback.Delete(session.ID)
+	}
+
+	for k := range session.Values {
+		if _, ok := k.(metaKey); !ok {
+			delete(session.Values, k)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestDestroy(t *testing.T) {
+	store := newTestStore(t, "sessions_destroy_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["user"] = "gopher"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	res = httptest.NewRecorder()
+
+	err = store.Destroy(req, res, "test-session")
+	if err != nil {
+		t.Fatalf("failed to destroy session: %v\n", err)
+	}
+
+	if !strings.Contains(res.Header().Get("Set-Cookie"), "Max-Age=0") {
+		t.Fatalf("expected the cookie to be expired, got %s", res.Header().Get("Set-Cookie"))
+	}
+	n, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected the session to be deleted, got %d", n)
+	}
+
+	// the request sees the session as logged out
+	session, err = store.Get(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if _, ok := session.Values["user"]; ok {
+		t.Fatal("expected the values to be cleared")
+	}
+}
@@ -0,0 +1,80 @@
+package mongostore
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// Elevate logs a user in: it gives the session a new id and CSRF token,
+// makes userID its owner, resets its lifetime to its tier and saves it, then
+// deletes the session stored under the previous id. The values of the
+// session are kept.
+//
+// Changing the id on login prevents session fixation, an id planted in the
+// browser before the login is worthless after it.
+func (s *Store) Elevate(r *http.Request, w http.ResponseWriter, session *sessions.Session, userID string) error {
+	s = s.route(session.Name())
+
+	// carry the stored values over to the new id
+	if isLazy(session) {
+		err := s.Load(session)
+		if err != nil {
+			return err
+		}
+	}
+
+	previous := session.ID
+	var stored *sessions.Session
+	if previous != "" && !session.IsNew {
+		stored = s.storedSession(session)
+	}
+
+	// a token seen before the login is worthless after it too
+	err := rotateCSRF(session)
+	if err != nil {
+		return err
+	}
+
+	session.ID = ""
+	session.IsNew = true
+	delete(session.Values, fingerprintKey)
+	s.SetOwner(session, userID)
+	session.Options.MaxAge = s.tierMaxAge(session)
+
+	err = s.Save(r, w, session)
+	if err != nil {
+		return err
+	}
+
+	if stored != nil {
+		s.dropQueued(previous)
+		res, err := s.deleteOne(s.MongoStore.Context, stored)
+		if err != nil {
+			return fmt.Errorf("mongostore: deleting session: %w", err)
+		}
+		s.log(r.Context(), slog.LevelInfo, "session deleted", slog.String("op", "elevate"), slog.String("name", session.Name()), s.sessionIDAttr(previous), slog.Int64("count", res.DeletedCount))
+		s.audit(r, stored, AuditDelete)
+	}
+
+	return nil
+}
+
+// storedSession returns a session with only the id of the given session, and
+// the metadata its mongo filter depends on.
+func (s *Store) storedSession(session *sessions.Session) *sessions.Session {
+	stored := sessions.NewSession(s, session.Name())
+	stored.Options = session.Options
+	stored.ID = session.ID
+
+	// the filter of the session depends on its tenant and shard key
+	for _, key := range []metaKey{tenantKey, shardKeyKey} {
+		if v, ok := session.Values[key]; ok {
+			stored.Values[key] = v
+		}
+	}
+
+	return stored
+}
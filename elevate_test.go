@@ -0,0 +1,69 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestElevate(t *testing.T) {
+	store := newTestStore(t, "sessions_elevate_test")
+
+	// an anonymous session, such as a shopping cart
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["cart"] = "book"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	anonymous := res.Header().Get("Set-Cookie")
+
+	// logs in
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", anonymous)
+	res = httptest.NewRecorder()
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	previous := session.ID
+
+	err = store.Elevate(req, res, session, "user-1")
+	if err != nil {
+		t.Fatalf("failed to elevate session: %v\n", err)
+	}
+	if session.ID == previous {
+		t.Fatal("expected a new session id")
+	}
+
+	// the session keeps its values under the new id
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["cart"] != "book" || store.Owner(session) != "user-1" {
+		t.Fatalf("expected the elevated session, got %v", session.Values)
+	}
+
+	// and the anonymous cookie no longer works
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", anonymous)
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatal("expected the previous session to be deleted")
+	}
+}
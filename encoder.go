@@ -0,0 +1,12 @@
+package mongostore
+
+// Encoder encodes the session id in the cookie, replacing securecookie.
+// Decode returns an error, reported as ErrCookieDecode, when the value was
+// tampered with or can not be decoded.
+//
+// Set Options.Encoder to standardize on another token format, such as the
+// PASETO tokens of PASETOEncoder. Options.JWTKey takes precedence over it.
+type Encoder interface {
+	Encode(name string, id string) (string, error)
+	Decode(name string, value string) (string, error)
+}
@@ -0,0 +1,111 @@
+package mongostore
+
+import (
+	"errors"
+)
+
+var (
+	// ErrSessionNotFound means the session is not in mongo, it was deleted or
+	// removed by the time to live index.
+	ErrSessionNotFound = errors.New("mongostore: session not found")
+
+	// ErrSessionExpired means the session is past its expiry but was not
+	// removed by the time to live index yet, or its token expired.
+	ErrSessionExpired = errors.New("mongostore: session expired")
+
+	// ErrCookieDecode means the cookie could not be decoded, it was tampered
+	// with or encoded with keys that are no longer used.
+	ErrCookieDecode = errors.New("mongostore: cookie could not be decoded")
+
+	// ErrStoreUnavailable means mongo could not be reached, or kept failing
+	// after all retries, or the circuit breaker is open.
+	ErrStoreUnavailable = errors.New("mongostore: store unavailable")
+
+	// ErrSessionLimit is returned by Save when a user already has the maximum
+	// number of sessions and the RejectNew policy is used.
+	ErrSessionLimit = errors.New("mongostore: session limit reached")
+
+	// ErrInvalidJWT is returned when a JWT is malformed or has a bad
+	// signature.
+	ErrInvalidJWT = errors.New("mongostore: invalid jwt")
+
+	// ErrValueNotFound is returned by Get when the session has no value for
+	// the key.
+	ErrValueNotFound = errors.New("mongostore: value not found")
+
+	// ErrValueType is returned by Get when the value can not be converted to
+	// the requested type.
+	ErrValueType = errors.New("mongostore: wrong value type")
+
+	// ErrSessionTooLarge is returned by Save when the session data is over
+	// Options.MaxDataSize.
+	ErrSessionTooLarge = errors.New("mongostore: session too large")
+
+	// ErrCookieTooLong is returned by Save when the encoded cookie is over
+	// the length set with MaxLength.
+	ErrCookieTooLong = errors.New("mongostore: cookie too long")
+
+	// ErrCookiePrefix is returned by Save when the options of a session
+	// named with a __Host- or __Secure- prefix lack the attributes the prefix
+	// requires.
+	ErrCookiePrefix = errors.New("mongostore: cookie attributes do not match the name prefix")
+
+	// ErrAuditTampered is returned by VerifyAuditLog when audit records were
+	// changed or removed.
+	ErrAuditTampered = errors.New("mongostore: audit log tampered")
+
+	// ErrWeakKey is returned by NewStore and Rotate when no keys are given,
+	// or a key is too short or has an invalid length.
+	ErrWeakKey = errors.New("mongostore: weak key")
+
+	// ErrClientMismatch means the session was created by another client,
+	// and Options.ClientBinding rejected it.
+	ErrClientMismatch = errors.New("mongostore: session bound to another client")
+
+	// ErrWebhookSignature is returned by VerifyWebhook when a webhook
+	// request is not signed with the secret, or its signature is too old.
+	ErrWebhookSignature = errors.New("mongostore: invalid webhook signature")
+
+	// ErrUnsupported is returned when the backend does not support a
+	// feature, such as the change streams of WatchDeletes.
+	ErrUnsupported = errors.New("mongostore: not supported by the backend")
+
+	// ErrLockLost is returned by Unlock when the lease of the session
+	// expired and it may have been locked by another holder since.
+	ErrLockLost = errors.New("mongostore: session lock lost")
+
+	// ErrConflict is returned by Save when the session was saved by another
+	// request since it was loaded, and Options.ConflictResolver failed the
+	// save or the session kept changing.
+	ErrConflict = errors.New("mongostore: session modified concurrently")
+
+	// ErrInvalidPageToken is returned by ListSessions when
+	// ListOptions.After is not a page token returned by PageToken.
+	ErrInvalidPageToken = errors.New("mongostore: invalid page token")
+)
+
+// storeError classifies the error that caused a failure with one of the
+// exported errors, so errors.Is matches both.
+type storeError struct {
+	kind error
+	err  error
+}
+
+// wrapError returns err classified as kind.
+func wrapError(kind error, err error) error {
+	return &storeError{kind: kind, err: err}
+}
+
+func (e *storeError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+// Is reports if the error is classified as target.
+func (e *storeError) Is(target error) bool {
+	return target == e.kind
+}
+
+// Unwrap returns the error that caused the failure.
+func (e *storeError) Unwrap() error {
+	return e.err
+}
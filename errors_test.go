@@ -0,0 +1,129 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestErrCookieDecode(t *testing.T) {
+	store := newTestStore(t, "sessions_errors_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: "tampered"})
+
+	store.MongoStore.DecodePolicy = mongostore.DecodeReport
+	_, err := store.New(req, "test-session")
+	if !errors.Is(err, mongostore.ErrCookieDecode) {
+		t.Fatalf("expected ErrCookieDecode, got %v", err)
+	}
+}
+
+func TestExpiredSession(t *testing.T) {
+	store := newTestStore(t, "sessions_errors_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// expire the session before the TTL monitor removes it
+	_, err = store.MongoStore.Collection.UpdateMany(
+		context.TODO(),
+		bson.M{},
+		bson.M{"$set": bson.M{"expires_at": primitive.NewDateTimeFromTime(time.Now().Add(-time.Minute))}},
+	)
+	if err != nil {
+		t.Fatalf("failed to expire session: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", res.Header()["Set-Cookie"][0])
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatal("expected an expired session to be new")
+	}
+}
+
+func TestDecodePolicy(t *testing.T) {
+	store := newTestStore(t, "sessions_errors_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: "tampered"})
+
+	// a new session comes back without an error
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if session == nil || !session.IsNew {
+		t.Fatalf("expected a new session, got %v", session)
+	}
+
+	// saving the empty session expires the cookie
+	res := httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if !strings.Contains(res.Header().Get("Set-Cookie"), "Max-Age=0") {
+		t.Fatalf("expected the cookie to be expired, got %s", res.Header().Get("Set-Cookie"))
+	}
+
+	// the middleware expires the cookie of an untouched session
+	res = httptest.NewRecorder()
+	store.Middleware("test-session")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})).ServeHTTP(res, req)
+	if !strings.Contains(res.Header().Get("Set-Cookie"), "Max-Age=0") {
+		t.Fatalf("expected the cookie to be expired, got %s", res.Header().Get("Set-Cookie"))
+	}
+
+	// or with the error
+	store.MongoStore.DecodePolicy = mongostore.DecodeReport
+	session, err = store.New(req, "test-session")
+	if !errors.Is(err, mongostore.ErrCookieDecode) {
+		t.Fatalf("expected ErrCookieDecode, got %v", err)
+	}
+	if session == nil || !session.IsNew {
+		t.Fatalf("expected a new session, got %v", session)
+	}
+
+	store.MongoStore.DecodePolicy = mongostore.DecodeStrict
+	session, err = store.New(req, "test-session")
+	if !errors.Is(err, mongostore.ErrCookieDecode) {
+		t.Fatalf("expected ErrCookieDecode, got %v", err)
+	}
+	if session != nil {
+		t.Fatalf("expected no session in strict mode, got %v", session)
+	}
+
+	// the middleware does not replace the session in strict mode
+	res = httptest.NewRecorder()
+	store.Middleware("test-session")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("expected the request to be rejected")
+	})).ServeHTTP(res, req)
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", res.Code)
+	}
+}
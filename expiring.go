@@ -0,0 +1,103 @@
+package mongostore
+
+import (
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetExpiring stores value under key in the session until ttl has passed,
+// for short-lived values such as OTP codes or OAuth state. Expired values
+// are removed when the session is loaded or saved, whatever the lifetime of
+// the session.
+//
+// The expiry is dropped when the key is deleted, setting the key again with
+// session.Values keeps it.
+func (s *Store) SetExpiring(session *sessions.Session, key interface{}, value interface{}, ttl time.Duration) {
+	expiring, _ := session.Values[expiringKey].(map[interface{}]time.Time)
+	if expiring == nil {
+		expiring = make(map[interface{}]time.Time)
+		session.Values[expiringKey] = expiring
+	}
+
+	session.Values[key] = value
+	expiring[key] = s.now().Add(ttl)
+}
+
+// KeyExpires returns when the value under key expires, it reports false if
+// the value was not set with SetExpiring.
+func (s *Store) KeyExpires(session *sessions.Session, key interface{}) (time.Time, bool) {
+	expiring, _ := session.Values[expiringKey].(map[interface{}]time.Time)
+	if _, ok := session.Values[key]; !ok {
+		return time.Time{}, false
+	}
+
+	expires, ok := expiring[key]
+	return expires, ok
+}
+
+// pruneExpired removes the expired values of the session, and the expiry of
+// deleted values.
+func (s *Store) pruneExpired(session *sessions.Session) {
+	expiring, _ := session.Values[expiringKey].(map[interface{}]time.Time)
+	if expiring == nil {
+		return
+	}
+
+	now := s.now()
+	for key, expires := range expiring {
+		if _, ok := session.Values[key]; !ok {
+			delete(expiring, key)
+			continue
+		}
+		if !now.Before(expires) {
+			delete(session.Values, key)
+			delete(expiring, key)
+		}
+	}
+
+	if len(expiring) == 0 {
+		delete(session.Values, expiringKey)
+	}
+}
+
+// keyExpiries returns the expiries of the session by Data key, as stored in
+// mongo.
+func (s *Store) keyExpiries(session *sessions.Session) map[string]primitive.DateTime {
+	expiring, _ := session.Values[expiringKey].(map[interface{}]time.Time)
+
+	var expiries map[string]primitive.DateTime
+	for key, expires := range expiring {
+		if _, ok := session.Values[key]; !ok {
+			continue
+		}
+		k, ok := s.encodeKey(key)
+		if !ok {
+			continue
+		}
+		if expiries == nil {
+			expiries = make(map[string]primitive.DateTime, len(expiring))
+		}
+		expiries[k] = primitive.NewDateTimeFromTime(expires)
+	}
+
+	return expiries
+}
+
+// restoreKeyExpiries sets the expiries read from mongo on the session and
+// removes the expired values.
+func (s *Store) restoreKeyExpiries(session *sessions.Session, expiries map[string]primitive.DateTime) {
+	if len(expiries) == 0 {
+		return
+	}
+
+	expiring := make(map[interface{}]time.Time, len(expiries))
+	for k, expires := range expiries {
+		expiring[s.decodeKey(k)] = expires.Time()
+	}
+	session.Values[expiringKey] = expiring
+
+	s.pruneExpired(session)
+}
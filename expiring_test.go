@@ -0,0 +1,53 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSetExpiring(t *testing.T) {
+	store := newTestStore(t, "sessions_expiring_test")
+	clock := &testClock{now: time.Now()}
+	store.MongoStore.Clock = clock
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["user"] = "gopher"
+	store.SetExpiring(session, "otp", "123456", time.Minute)
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.Values["otp"] != "123456" {
+		t.Fatalf("expected the otp before it expires, got %v", session.Values)
+	}
+	if _, ok := store.KeyExpires(session, "otp"); !ok {
+		t.Fatal("expected the otp to expire")
+	}
+
+	// the expired value is gone, the rest of the session stays
+	clock.now = clock.now.Add(2 * time.Minute)
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if _, ok := session.Values["otp"]; ok {
+		t.Fatal("expected the otp to have expired")
+	}
+	if session.Values["user"] != "gopher" {
+		t.Fatalf("expected the session to be kept, got %v", session.Values)
+	}
+}
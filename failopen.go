@@ -0,0 +1,48 @@
+package mongostore
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// ReadPolicy is what New does when reading a session from mongo fails, for
+// another reason than the session not being found, and Options.Fallback
+// does not serve it.
+type ReadPolicy int
+
+const (
+	// ReadFailClosed returns a nil session with the error, the default.
+	// Middleware fails the request with a 500, or a 503 while mongo is
+	// unavailable.
+	ReadFailClosed ReadPolicy = iota
+
+	// ReadFailOpen returns an empty session without an error, so the
+	// request is served as anonymous. Degraded reports the session, Save
+	// does not write it so the stored session is kept for later requests.
+	ReadFailOpen
+)
+
+// Degraded reports if the session is an empty stand-in for a session that
+// could not be read from mongo, with ReadFailOpen.
+func Degraded(session *sessions.Session) bool {
+	_, ok := session.Values[degradedKey]
+	return ok
+}
+
+// readFailed returns the result of New for a session that could not be read
+// from mongo, according to Options.ReadPolicy.
+func (s *Store) readFailed(r *http.Request, session *sessions.Session, err error) (*sessions.Session, error) {
+	s.count(&s.counters.ReadFailures)
+	if s.MongoStore.ReadPolicy != ReadFailOpen {
+		return nil, err
+	}
+
+	s.log(r.Context(), slog.LevelWarn, "serving session empty", slog.String("name", session.Name()), errorAttr(err))
+	degraded := s.freshSession(r, session)
+	degraded.ID = session.ID
+	degraded.Values[degradedKey] = true
+
+	return degraded, nil
+}
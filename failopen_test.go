@@ -0,0 +1,64 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestReadFailOpen(t *testing.T) {
+	store := newTestStore(t, "sessions_failopen_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	available := store.MongoStore.Collection
+	store.MongoStore.Collection = unavailableCollection(t)
+
+	// fails closed by default
+	_, err = store.New(req, "test-session")
+	if err == nil {
+		t.Fatal("expected the read to fail")
+	}
+
+	// an empty session stands in for the stored one
+	store.MongoStore.ReadPolicy = mongostore.ReadFailOpen
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if !mongostore.Degraded(session) || session.Values["test"] != nil {
+		t.Fatalf("expected a degraded session, got %v", session.Values)
+	}
+	res = httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if res.Header().Get("Set-Cookie") != "" {
+		t.Fatal("expected the degraded session not to be saved")
+	}
+
+	// the stored session is intact once mongo is back
+	store.MongoStore.Collection = available
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.Values["test"] != "testdata" {
+		t.Fatalf("expected the stored session, got %v", session.Values)
+	}
+}
@@ -0,0 +1,184 @@
+package mongostore
+
+import (
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+)
+
+// FallbackPolicy decides how the store behaves while mongo is unavailable.
+type FallbackPolicy int
+
+const (
+	// FailClosed returns the mongo errors, the default.
+	FailClosed FallbackPolicy = iota
+
+	// FailOpenReadOnly serves sessions from the fallback backend and drops
+	// writes, the cookie is left untouched.
+	FailOpenReadOnly
+
+	// QueueWrites serves and saves sessions with the fallback backend, the
+	// sessions are written to mongo once it is available again.
+	QueueWrites
+)
+
+// FallbackBackend keeps sessions while mongo is unavailable.
+type FallbackBackend interface {
+	// Load returns the values of the session with the given id.
+	Load(id string) (values map[interface{}]interface{}, ok bool)
+
+	// Save stores the values of the session with the given id.
+	Save(id string, values map[interface{}]interface{})
+
+	// Delete removes the session with the given id.
+	Delete(id string)
+}
+
+// MemoryFallback is an in-memory FallbackBackend.
+type MemoryFallback struct {
+	mu       sync.Mutex
+	sessions map[string]map[interface{}]interface{}
+}
+
+// NewMemoryFallback returns an empty in-memory FallbackBackend.
+func NewMemoryFallback() *MemoryFallback {
+	return &MemoryFallback{
+		sessions: make(map[string]map[interface{}]interface{}),
+	}
+}
+
+// Load returns a copy of the values of the session.
+func (m *MemoryFallback) Load(id string) (map[interface{}]interface{}, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values, ok := m.sessions[id]
+	if !ok {
+		return nil, false
+	}
+
+	return copyValues(values), true
+}
+
+// Save stores a copy of the values of the session.
+func (m *MemoryFallback) Save(id string, values map[interface{}]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[id] = copyValues(values)
+}
+
+// Delete removes the session.
+func (m *MemoryFallback) Delete(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, id)
+}
+
+// copyValues returns a shallow copy of session values.
+func copyValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	c := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
+
+// isUnavailable reports if err means mongo can not be reached, as opposed to
+// a failed operation.
+func isUnavailable(err error) bool {
+	return errors.Is(err, ErrStoreUnavailable) ||
+		mongo.IsNetworkError(err) ||
+		mongo.IsTimeout(err) ||
+		errors.As(err, &topology.ServerSelectionError{})
+}
+
+// loadFallback fills the session from the fallback backend.
+func (s *Store) loadFallback(session *sessions.Session) *sessions.Session {
+	values, ok := s.MongoStore.Fallback.Load(session.ID)
+	if ok {
+		for k, v := range values {
+			session.Values[k] = v
+		}
+		session.IsNew = false
+		session.Options.MaxAge = s.tierMaxAge(session)
+	}
+
+	return session
+}
+
+// saveFallback writes the session to the fallback backend and queues it to be
+// written to mongo.
+func (s *Store) saveFallback(session *sessions.Session) error {
+	// a new session needs an id to be found again
+	if session.ID == "" {
+		id, err := s.idGenerator().NewID()
+		if err != nil {
+			return err
+		}
+		if s.MongoStore.OpaqueTokens {
+			id, err = newToken()
+			if err != nil {
+				return err
+			}
+		}
+		session.ID = id
+	}
+
+	if session.Options.MaxAge == -1 {
+		s.MongoStore.Fallback.Delete(session.ID)
+	} else {
+		s.MongoStore.Fallback.Save(session.ID, session.Values)
+	}
+
+	s.fallbackMu.Lock()
+	defer s.fallbackMu.Unlock()
+
+	if s.fallbackPending == nil {
+		s.fallbackPending = make(map[string]bool)
+	}
+	s.fallbackPending[session.ID] = true
+
+	return nil
+}
+
+// flushFallback writes the sessions queued while mongo was unavailable, a
+// session missing from the fallback backend was deleted.
+func (s *Store) flushFallback() {
+	if s.MongoStore.Fallback == nil {
+		return
+	}
+
+	s.fallbackMu.Lock()
+	defer s.fallbackMu.Unlock()
+
+	for id := range s.fallbackPending {
+		session := sessions.NewSession(s, "")
+		session.Options = s.sessionOptions()
+		session.ID = id
+
+		var err error
+		values, ok := s.MongoStore.Fallback.Load(id)
+		if ok {
+			session.Values = values
+			session.Options.MaxAge = s.tierMaxAge(session)
+			_, err = s.updateOne(session, options.Update().SetUpsert(true))
+		} else {
+			_, err = s.deleteOne(s.MongoStore.Context, session)
+		}
+		if err != nil {
+			s.log(s.MongoStore.Context, slog.LevelError, "replaying queued session", s.sessionIDAttr(id), errorAttr(err))
+			return
+		}
+
+		s.MongoStore.Fallback.Delete(id)
+		delete(s.fallbackPending, id)
+	}
+}
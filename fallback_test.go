@@ -0,0 +1,82 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/glezjose/mongostore"
+)
+
+// unavailableCollection returns a collection on a server that does not exist.
+func unavailableCollection(t *testing.T) *mongo.Collection {
+	t.Helper()
+
+	client, err := mongo.Connect(
+		context.TODO(),
+		options.Client().ApplyURI("mongodb://127.0.0.1:1/?serverSelectionTimeoutMS=100"),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v\n", err)
+	}
+
+	return client.Database("test-database").Collection("sessions_unavailable")
+}
+
+func TestFallbackQueueWrites(t *testing.T) {
+	store := newTestStore(t, "sessions_fallback_test")
+	store.MongoStore.Fallback = mongostore.NewMemoryFallback()
+	store.MongoStore.FallbackPolicy = mongostore.QueueWrites
+
+	available := store.MongoStore.Collection
+	store.MongoStore.Collection = unavailableCollection(t)
+
+	// save while mongo is down
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to queue session: %v\n", err)
+	}
+
+	// read while mongo is down
+	cookie := res.Header()["Set-Cookie"][0]
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", cookie)
+	res = httptest.NewRecorder()
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the queued session, got %v", session.Values)
+	}
+
+	// mongo is back, the queued session is written on the next save
+	store.MongoStore.Collection = available
+
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	count, err := available.CountDocuments(context.TODO(), bson.M{"data.test": "testdata"})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected the queued session in mongo, got %d sessions", count)
+	}
+}
@@ -0,0 +1,148 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErasurePolicy decides how EraseUser erases the sessions of a user.
+type ErasurePolicy int
+
+const (
+	// EraseDelete deletes the sessions, the default.
+	EraseDelete ErasurePolicy = iota
+
+	// EraseAnonymize removes the data, owner and client info of the
+	// sessions, keeping their timestamps.
+	EraseAnonymize
+)
+
+// ErasureResult counts the sessions erased by EraseUser, as evidence of the
+// erasure.
+type ErasureResult struct {
+	Deleted    int64
+	Anonymized int64
+}
+
+// ExportUser writes every session of a user to w as newline delimited JSON,
+// oldest first, to answer data subject access requests. Each line is a
+// MongoSession in relaxed extended JSON, with Data loaded from the overflow
+// collection when needed and the values matching Options.Redact masked.
+func (s *Store) ExportUser(ctx context.Context, userID string, w io.Writer) error {
+	cursor, err := s.readCollection().Find(
+		ctx,
+		bson.M{
+			"user_id": userID,
+		},
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return fmt.Errorf("mongostore: finding sessions of %s: %w", userID, err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		mongoSession := &MongoSession{}
+		err = cursor.Decode(mongoSession)
+		if err != nil {
+			return fmt.Errorf("mongostore: decoding session of %s: %w", userID, err)
+		}
+
+		if !mongoSession.Overflow.IsZero() {
+			// the chunks of an expired session can be gone already
+			mongoSession.Data, err = s.readOverflow(mongoSession.Overflow)
+			if err != nil && !errors.Is(err, ErrSessionExpired) {
+				return err
+			}
+		}
+
+		line, err := bson.MarshalExtJSON(s.Redact(mongoSession), false, false)
+		if err != nil {
+			return fmt.Errorf("mongostore: encoding session of %s: %w", userID, err)
+		}
+
+		_, err = w.Write(append(line, '\n'))
+		if err != nil {
+			return fmt.Errorf("mongostore: writing session of %s: %w", userID, err)
+		}
+	}
+
+	return cursor.Err()
+}
+
+// EraseUser erases every session of a user according to
+// Options.ErasurePolicy, including the overflow chunks of their data.
+func (s *Store) EraseUser(ctx context.Context, userID string) (ErasureResult, error) {
+	return s.eraseUser(ctx, userID, s.MongoStore.ErasurePolicy)
+}
+
+// eraseUser erases every session of a user with the given policy.
+func (s *Store) eraseUser(ctx context.Context, userID string, policy ErasurePolicy) (ErasureResult, error) {
+	var result ErasureResult
+	filter := bson.M{
+		"user_id": userID,
+	}
+
+	// the overflow chunks are not linked to the user, find them first
+	overflows, err := s.MongoStore.Collection.Distinct(ctx, "overflow", filter)
+	if err != nil {
+		return result, fmt.Errorf("mongostore: finding overflow of %s: %w", userID, err)
+	}
+
+	switch policy {
+	case EraseAnonymize:
+		update := bson.M{
+			"$unset": bson.M{
+				"data":       "",
+				"overflow":   "",
+				"user_id":    "",
+				"ip":         "",
+				"user_agent": "",
+			},
+		}
+
+		res, err := s.writeCollection().UpdateMany(ctx, filter, update)
+		if err != nil {
+			return result, fmt.Errorf("mongostore: anonymizing sessions of %s: %w", userID, err)
+		}
+		result.Anonymized = res.ModifiedCount
+
+		s.replicate(func(col *mongo.Collection) error {
+			_, err := col.UpdateMany(ctx, filter, update)
+			return err
+		})
+
+	default:
+		res, err := s.deleteCollection().DeleteMany(ctx, filter)
+		if err != nil {
+			return result, fmt.Errorf("mongostore: deleting sessions of %s: %w", userID, err)
+		}
+		result.Deleted = res.DeletedCount
+
+		s.replicate(func(col *mongo.Collection) error {
+			_, err := col.DeleteMany(ctx, filter)
+			return err
+		})
+	}
+
+	if len(overflows) > 0 {
+		_, err = s.overflowCollection().DeleteMany(ctx, bson.M{"overflow": bson.M{"$in": overflows}})
+		if err != nil {
+			return result, fmt.Errorf("mongostore: deleting overflow of %s: %w", userID, err)
+		}
+	}
+
+	err = s.eraseArchived(ctx, userID)
+	if err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
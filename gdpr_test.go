@@ -0,0 +1,103 @@
+package mongostore_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestExportUser(t *testing.T) {
+	store := newTestStore(t, "sessions_gdpr_test")
+
+	for _, userID := range []string{"export-user", "export-user", "other-user"} {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		res := httptest.NewRecorder()
+
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to create new session: %v\n", err)
+		}
+		store.SetOwner(session, userID)
+		session.Values["email"] = userID + "@example.com"
+		err = store.Save(req, res, session)
+		if err != nil {
+			t.Fatalf("failed to insert session: %v\n", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := store.ExportUser(context.TODO(), "export-user", &buf)
+	if err != nil {
+		t.Fatalf("failed to export user: %v\n", err)
+	}
+
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var doc struct {
+			UserID string                 `json:"user_id"`
+			Data   map[string]interface{} `json:"data"`
+		}
+		err = json.Unmarshal(scanner.Bytes(), &doc)
+		if err != nil {
+			t.Fatalf("failed to decode line: %v\n", err)
+		}
+		if doc.UserID != "export-user" || doc.Data["email"] != "export-user@example.com" {
+			t.Fatalf("unexpected session %+v", doc)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Fatalf("expected 2 sessions, got %d", lines)
+	}
+}
+
+func TestEraseUser(t *testing.T) {
+	store := newTestStore(t, "sessions_gdpr_test")
+
+	for i := 0; i < 2; i++ {
+		err := login(t, store, "erase-user")
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	result, err := store.EraseUser(context.TODO(), "erase-user")
+	if err != nil {
+		t.Fatalf("failed to erase user: %v\n", err)
+	}
+	if result.Deleted != 2 {
+		t.Fatalf("expected 2 deleted sessions, got %+v", result)
+	}
+
+	// anonymizing keeps the sessions without the owner
+	store.MongoStore.ErasurePolicy = mongostore.EraseAnonymize
+	err = login(t, store, "anonymize-user")
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	result, err = store.EraseUser(context.TODO(), "anonymize-user")
+	if err != nil {
+		t.Fatalf("failed to erase user: %v\n", err)
+	}
+	if result.Anonymized != 1 {
+		t.Fatalf("expected 1 anonymized session, got %+v", result)
+	}
+
+	count, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{"user_id": bson.M{"$exists": false}})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 anonymous session, got %d", count)
+	}
+}
@@ -0,0 +1,188 @@
+// Package grpcadmin serves the SessionAdmin gRPC service, to manage the
+// sessions of a mongostore.Store from admin planes that do not speak HTTP.
+//
+// It is a separate module so the store does not depend on gRPC.
+package grpcadmin
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative adminpb/admin.proto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/glezjose/mongostore"
+	"github.com/glezjose/mongostore/grpcadmin/adminpb"
+)
+
+// defaultLimit is the page size of ListSessions when the request has none.
+const defaultLimit = 100
+
+// Server implements adminpb.SessionAdminServer with a store.
+//
+// Like Store.AdminHandler the service has no access control, register it on
+// a server with the authentication of the internal tools. The values
+// matching Options.Redact of the store are masked.
+type Server struct {
+	adminpb.UnimplementedSessionAdminServer
+
+	store *mongostore.Store
+}
+
+// NewServer returns a server managing the sessions of store.
+func NewServer(store *mongostore.Store) *Server {
+	return &Server{store: store}
+}
+
+// Register registers the service of a server managing the sessions of store
+// on s.
+func Register(s grpc.ServiceRegistrar, store *mongostore.Store) {
+	adminpb.RegisterSessionAdminServer(s, NewServer(store))
+}
+
+// ListSessions returns a page of sessions, newest first.
+func (s *Server) ListSessions(ctx context.Context, req *adminpb.ListSessionsRequest) (*adminpb.ListSessionsResponse, error) {
+	if req.GetSkip() < 0 || req.GetLimit() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "skip and limit must not be negative")
+	}
+
+	opts := mongostore.ListOptions{
+		UserID: req.GetUserId(),
+		Skip:   req.GetSkip(),
+		Limit:  req.GetLimit(),
+	}
+	if opts.Limit == 0 {
+		opts.Limit = defaultLimit
+	}
+
+	mongoSessions, err := s.store.ListSessions(ctx, opts)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	res := &adminpb.ListSessionsResponse{}
+	for _, mongoSession := range mongoSessions {
+		session, err := toSession(s.store.Redact(mongoSession))
+		if err != nil {
+			return nil, statusError(err)
+		}
+		res.Sessions = append(res.Sessions, session)
+	}
+
+	return res, nil
+}
+
+// GetSession returns a session by the id stored in its cookie.
+func (s *Server) GetSession(ctx context.Context, req *adminpb.GetSessionRequest) (*adminpb.Session, error) {
+	mongoSession, err := s.store.FindSession(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	session, err := toSession(s.store.Redact(mongoSession))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return session, nil
+}
+
+// RevokeSession deletes a session by the id stored in its cookie.
+func (s *Server) RevokeSession(ctx context.Context, req *adminpb.RevokeSessionRequest) (*adminpb.RevokeResponse, error) {
+	err := s.store.DeleteSession(ctx, req.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &adminpb.RevokeResponse{Deleted: 1}, nil
+}
+
+// RevokeUser deletes every session of a user.
+func (s *Server) RevokeUser(ctx context.Context, req *adminpb.RevokeUserRequest) (*adminpb.RevokeResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	deleted, err := s.store.DeleteUserSessions(ctx, req.GetUserId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &adminpb.RevokeResponse{Deleted: deleted}, nil
+}
+
+// Stats returns the counts of the store.
+func (s *Server) Stats(ctx context.Context, req *adminpb.StatsRequest) (*adminpb.StatsResponse, error) {
+	stats, err := s.store.Stats(ctx)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &adminpb.StatsResponse{
+		Sessions:    stats.Sessions,
+		Owned:       stats.Owned,
+		Persistent:  stats.Persistent,
+		Expired:     stats.Expired,
+		Queued:      int64(stats.Queued),
+		BreakerOpen: stats.BreakerOpen,
+	}, nil
+}
+
+// toSession converts a session document, the values are written as relaxed
+// extended JSON like the cli.
+func toSession(mongoSession *mongostore.MongoSession) (*adminpb.Session, error) {
+	data, err := bson.MarshalExtJSON(mongoSession.Data, false, false)
+	if err != nil {
+		return nil, fmt.Errorf("grpcadmin: encoding session data: %w", err)
+	}
+
+	id := fmt.Sprint(mongoSession.ID)
+	if objectID, ok := mongoSession.ID.(primitive.ObjectID); ok {
+		id = objectID.Hex()
+	}
+
+	return &adminpb.Session{
+		Id:         id,
+		UserId:     mongoSession.UserID,
+		TenantId:   mongoSession.TenantID,
+		Persistent: mongoSession.Persistent,
+		CreatedAt:  timestamp(mongoSession.Created),
+		ModifiedAt: timestamp(mongoSession.Modified),
+		ExpiresAt:  timestamp(mongoSession.Expires),
+		Data:       string(data),
+		Ip:         mongoSession.IP,
+		UserAgent:  mongoSession.UserAgent,
+	}, nil
+}
+
+// timestamp converts a date, leaving unset dates nil.
+func timestamp(d primitive.DateTime) *timestamppb.Timestamp {
+	if d == 0 {
+		return nil
+	}
+	return timestamppb.New(d.Time())
+}
+
+// statusError returns err with the status code matching it.
+func statusError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, mongostore.ErrSessionNotFound):
+		code = codes.NotFound
+	case errors.Is(err, mongostore.ErrStoreUnavailable):
+		code = codes.Unavailable
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	}
+
+	return status.Error(code, err.Error())
+}
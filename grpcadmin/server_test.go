@@ -0,0 +1,141 @@
+package grpcadmin_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/glezjose/mongostore"
+	"github.com/glezjose/mongostore/grpcadmin"
+	"github.com/glezjose/mongostore/grpcadmin/adminpb"
+)
+
+// newTestClient returns a client of a server managing a store on its own
+// collection.
+func newTestClient(t *testing.T) (adminpb.SessionAdminClient, *mongostore.Store) {
+	t.Helper()
+
+	uri := os.Getenv("MONGODB_URI")
+	if uri == "" {
+		uri = "mongodb://localhost:27017"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	mongoclient, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to mongo: %v\n", err)
+	}
+	t.Cleanup(func() { _ = mongoclient.Disconnect(context.Background()) })
+
+	col := mongoclient.Database("test-database").Collection("sessions_grpc_test")
+	err = col.Drop(ctx)
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	store, err := mongostore.NewStore(
+		col,
+		http.Cookie{Path: "/", MaxAge: 240, HttpOnly: true, SameSite: http.SameSiteStrictMode},
+		securecookie.GenerateRandomKey(32),
+		securecookie.GenerateRandomKey(16),
+	)
+	if err != nil {
+		t.Fatalf("failed to create test store: %v\n", err)
+	}
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	grpcadmin.Register(server, store)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial test server: %v\n", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return adminpb.NewSessionAdminClient(conn), store
+}
+
+func TestServer(t *testing.T) {
+	client, store := newTestClient(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to create new session: %v\n", err)
+		}
+		store.SetOwner(session, "grpc-user")
+		session.Values["foo"] = "bar"
+
+		err = store.Save(req, httptest.NewRecorder(), session)
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	list, err := client.ListSessions(ctx, &adminpb.ListSessionsRequest{UserId: "grpc-user"})
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v\n", err)
+	}
+	if len(list.Sessions) != 2 || list.Sessions[0].Data != `{"foo":"bar"}` {
+		t.Fatalf("expected 2 sessions, got %+v", list.Sessions)
+	}
+
+	session, err := client.GetSession(ctx, &adminpb.GetSessionRequest{Id: list.Sessions[0].Id})
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.UserId != "grpc-user" {
+		t.Fatalf("expected the session of grpc-user, got %+v", session)
+	}
+
+	stats, err := client.Stats(ctx, &adminpb.StatsRequest{})
+	if err != nil {
+		t.Fatalf("failed to get stats: %v\n", err)
+	}
+	if stats.Sessions != 2 || stats.Owned != 2 {
+		t.Fatalf("expected stats of 2 sessions, got %+v", stats)
+	}
+
+	_, err = client.RevokeSession(ctx, &adminpb.RevokeSessionRequest{Id: session.Id})
+	if err != nil {
+		t.Fatalf("failed to revoke session: %v\n", err)
+	}
+
+	revoked, err := client.RevokeUser(ctx, &adminpb.RevokeUserRequest{UserId: "grpc-user"})
+	if err != nil {
+		t.Fatalf("failed to revoke user: %v\n", err)
+	}
+	if revoked.Deleted != 1 {
+		t.Fatalf("expected 1 session to be revoked, got %d", revoked.Deleted)
+	}
+
+	_, err = client.GetSession(ctx, &adminpb.GetSessionRequest{Id: session.Id})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package mongostore
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AdminHandler returns a handler serving JSON endpoints to manage the
+// sessions, for support tooling:
+//
+//	GET    /sessions?user=&limit=&after=&sort=  list the sessions, newest first
+//	GET    /sessions/{id}                        get a session
+//	DELETE /sessions/{id}                        delete a session
+//	DELETE /users/{id}/sessions                  delete the sessions of a user
+//	GET    /stats                                get the Stats of the store
+//
+// A full page of sessions has a next token, passed as after to get the next
+// page. sort=oldest lists the oldest sessions first, skip= is still
+// accepted.
+//
+// The handler has no access control, mount it behind the authentication of
+// the internal tools, with http.StripPrefix when it is not mounted at the
+// root. The values matching Options.Redact are masked in the sessions.
+func (s *Store) AdminHandler() http.Handler {
+	return http.HandlerFunc(s.serveAdmin)
+}
+
+func (s *Store) serveAdmin(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "sessions" && r.Method == http.MethodGet:
+		s.adminList(w, r)
+
+	case len(parts) == 2 && parts[0] == "sessions" && r.Method == http.MethodGet:
+		mongoSession, err := s.FindSession(r.Context(), parts[1])
+		if err != nil {
+			adminError(w, err)
+			return
+		}
+		doc, err := bson.MarshalExtJSON(s.Redact(mongoSession), false, false)
+		if err != nil {
+			adminError(w, err)
+			return
+		}
+		adminJSON(w, http.StatusOK, map[string]json.RawMessage{"session": doc})
+
+	case len(parts) == 2 && parts[0] == "sessions" && r.Method == http.MethodDelete:
+		err := s.DeleteSession(r.Context(), parts[1])
+		if err != nil {
+			adminError(w, err)
+			return
+		}
+		adminJSON(w, http.StatusOK, map[string]int64{"deleted": 1})
+
+	case len(parts) == 3 && parts[0] == "users" && parts[2] == "sessions" && r.Method == http.MethodDelete:
+		deleted, err := s.DeleteUserSessions(r.Context(), parts[1])
+		if err != nil {
+			adminError(w, err)
+			return
+		}
+		adminJSON(w, http.StatusOK, map[string]int64{"deleted": deleted})
+
+	case path == "stats" && r.Method == http.MethodGet:
+		stats, err := s.Stats(r.Context())
+		if err != nil {
+			adminError(w, err)
+			return
+		}
+		adminJSON(w, http.StatusOK, stats)
+
+	default:
+		adminJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+}
+
+// adminList serves a page of sessions.
+func (s *Store) adminList(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	opts := ListOptions{
+		UserID: query.Get("user"),
+		Limit:  100,
+		After:  query.Get("after"),
+	}
+	switch query.Get("sort") {
+	case "", "newest":
+	case "oldest":
+		opts.Sort = ListOldest
+	default:
+		adminJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid sort"})
+		return
+	}
+
+	for name, value := range map[string]*int64{"skip": &opts.Skip, "limit": &opts.Limit} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				adminJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid " + name})
+				return
+			}
+			*value = n
+		}
+	}
+
+	mongoSessions, err := s.ListSessions(r.Context(), opts)
+	if err != nil {
+		adminError(w, err)
+		return
+	}
+
+	// sessions are written as relaxed extended JSON, like the cli
+	docs := make([]json.RawMessage, 0, len(mongoSessions))
+	for _, mongoSession := range mongoSessions {
+		doc, err := bson.MarshalExtJSON(s.Redact(mongoSession), false, false)
+		if err != nil {
+			adminError(w, err)
+			return
+		}
+		docs = append(docs, doc)
+	}
+
+	// a full page has a token for the next one
+	page := map[string]interface{}{"sessions": docs}
+	if opts.Limit > 0 && int64(len(mongoSessions)) == opts.Limit {
+		page["next"] = PageToken(mongoSessions[len(mongoSessions)-1])
+	}
+
+	adminJSON(w, http.StatusOK, page)
+}
+
+// adminError writes an error, with the status matching the error.
+func adminError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrInvalidPageToken):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrStoreUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+
+	adminJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// adminJSON writes v as JSON with the given status.
+func adminJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,62 @@
+package mongostore_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestAdminHandler(t *testing.T) {
+	store := newTestStore(t, "sessions_handler_test")
+
+	for i := 0; i < 2; i++ {
+		err := login(t, store, "handler-user")
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	handler := http.StripPrefix("/admin", store.AdminHandler())
+	serve := func(method string, target string, v interface{}) int {
+		req := httptest.NewRequest(method, target, nil)
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+
+		if v != nil {
+			err := json.Unmarshal(res.Body.Bytes(), v)
+			if err != nil {
+				t.Fatalf("failed to decode response: %v\n", err)
+			}
+		}
+		return res.Code
+	}
+
+	var list struct {
+		Sessions []struct {
+			ID json.RawMessage `json:"_id"`
+		} `json:"sessions"`
+	}
+	code := serve("GET", "/admin/sessions?user=handler-user&limit=10", &list)
+	if code != http.StatusOK || len(list.Sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d %+v", code, list)
+	}
+
+	var stats mongostore.Stats
+	code = serve("GET", "/admin/stats", &stats)
+	if code != http.StatusOK || stats.Sessions != 2 || stats.Owned != 2 {
+		t.Fatalf("expected stats of 2 sessions, got %d %+v", code, stats)
+	}
+
+	code = serve("DELETE", "/admin/users/handler-user/sessions", nil)
+	if code != http.StatusOK {
+		t.Fatalf("expected the sessions to be deleted, got %d", code)
+	}
+
+	code = serve("GET", "/admin/sessions/000000000000000000000000", nil)
+	if code != http.StatusNotFound {
+		t.Fatalf("expected not found, got %d", code)
+	}
+}
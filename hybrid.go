@@ -0,0 +1,112 @@
+package mongostore
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// clientSidePrefix marks a cookie holding the whole session instead of the
+// session id.
+const clientSidePrefix = "c."
+
+// clientSession is what a client side session encodes in the cookie.
+type clientSession struct {
+	Values     map[string]interface{}
+	Persistent bool
+	Cookie     *CookieAttributes
+	Tenant     string
+	CSRFToken  string
+	KeyExpires map[string]primitive.DateTime
+}
+
+// encodeClientSide encodes the whole session for the cookie, it reports false
+// if the session must be stored in mongo: hybrid storage is disabled, the
+// store binds sessions to their client with Options.ClientBinding, the
+// session is being deleted, it has an owner, namespaces or values marked
+// with MarkSensitive, its values can not be encoded or the encoded session is
+// over Options.HybridThreshold.
+func (s *Store) encodeClientSide(session *sessions.Session) (string, bool) {
+	threshold := s.MongoStore.HybridThreshold
+	if threshold <= 0 || s.MongoStore.ClientBinding != nil || session.Options.MaxAge < 0 || s.Owner(session) != "" || namespaces(session) != nil {
+		return "", false
+	}
+
+	values := make(map[string]interface{}, len(session.Values))
+	for k, v := range session.Values {
+		if _, ok := k.(metaKey); ok {
+			continue
+		}
+		key, ok := s.encodeKey(k)
+		if !ok || s.sensitive[key] {
+			return "", false
+		}
+		values[key] = v
+	}
+
+	encoded, err := securecookie.EncodeMulti(
+		session.Name(),
+		&clientSession{
+			Values:     values,
+			Persistent: s.IsPersistent(session),
+			Cookie:     s.cookieAttributes(session),
+			Tenant:     tenant(session),
+			CSRFToken:  csrfToken(session),
+			KeyExpires: s.keyExpiries(session),
+		},
+		s.codecs()...,
+	)
+	encoded = clientSidePrefix + encoded
+	if err != nil || len(encoded) > threshold || s.checkLength(session.Name(), encoded) != nil {
+		return "", false
+	}
+
+	return encoded, true
+}
+
+// isClientSide reports if the value sent by the client holds a client side
+// session.
+func isClientSide(value string) bool {
+	return strings.HasPrefix(value, clientSidePrefix)
+}
+
+// decodeClientSide fills the session from a client side session cookie.
+func (s *Store) decodeClientSide(session *sessions.Session, value string) error {
+	var cs clientSession
+	stale, err := s.decodeMulti(session.Name(), strings.TrimPrefix(value, clientSidePrefix), &cs)
+	if err != nil {
+		return err
+	}
+
+	// bound sessions are only checked in mongo, a client side cookie would
+	// be valid from any client
+	if s.MongoStore.ClientBinding != nil {
+		return errors.New("client side session with client binding")
+	}
+
+	// the cookie of another tenant
+	if cs.Tenant != tenant(session) {
+		return errors.New("session of another tenant")
+	}
+
+	for k, v := range cs.Values {
+		session.Values[s.decodeKey(k)] = v
+	}
+	if cs.Persistent {
+		session.Values[persistentKey] = true
+	}
+	if cs.CSRFToken != "" {
+		session.Values[csrfKey] = cs.CSRFToken
+	}
+	if stale {
+		session.Values[reissueKey] = true
+	}
+	s.restoreKeyExpiries(session, cs.KeyExpires)
+	applyCookieAttributes(session, cs.Cookie)
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestHybridStorage(t *testing.T) {
+	store := newTestStore(t, "sessions_hybrid_test")
+	store.MongoStore.HybridThreshold = 1024
+
+	count := func() int64 {
+		n, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{})
+		if err != nil {
+			t.Fatalf("failed to count sessions: %v\n", err)
+		}
+		return n
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	// a small session stays in the cookie
+	session.Values["flash"] = "saved"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if count() != 0 {
+		t.Fatal("expected no session in mongo")
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["flash"] != "saved" {
+		t.Fatalf("expected client side session, got %v", session.Values)
+	}
+
+	// growing over the threshold moves it to mongo
+	session.Values["big"] = strings.Repeat("x", 2048)
+	res = httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if count() != 1 {
+		t.Fatal("expected the session in mongo")
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["flash"] != "saved" {
+		t.Fatalf("expected session from mongo, got %v", session.Values)
+	}
+
+	// shrinking moves it back to the cookie
+	delete(session.Values, "big")
+	res = httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if count() != 0 {
+		t.Fatal("expected the session to be removed from mongo")
+	}
+}
+
+func TestHybridStorageClientBinding(t *testing.T) {
+	store := newTestStore(t, "sessions_hybrid_binding_test")
+	store.MongoStore.HybridThreshold = 1024
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["flash"] = "saved"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	cookie := res.Header().Get("Set-Cookie")
+
+	// a bound store keeps small sessions in mongo
+	store.MongoStore.ClientBinding = &mongostore.ClientBinding{IPv4Prefix: 24}
+	res = httptest.NewRecorder()
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["flash"] = "saved"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	n, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected the bound session in mongo, got %d", n)
+	}
+
+	// and does not trust client side cookies, valid from any client
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Add("Cookie", cookie)
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatalf("expected a new session, got %v", session.Values)
+	}
+}
@@ -0,0 +1,198 @@
+package mongostore
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IDGenerator creates session ids and maps them to the mongo _id field.
+type IDGenerator interface {
+	// NewID returns a new session id, as stored in session.ID and the cookie.
+	NewID() (string, error)
+
+	// DocumentID converts a session id to the value stored in _id, it returns
+	// an error if the id was not created by this generator.
+	DocumentID(id string) (interface{}, error)
+}
+
+// ObjectIDGenerator creates mongo ObjectIDs, the default.
+//
+// ObjectIDs embed a timestamp and a counter, so they are sortable but
+// somewhat guessable.
+type ObjectIDGenerator struct{}
+
+// NewID returns a new ObjectID in hex.
+func (ObjectIDGenerator) NewID() (string, error) {
+	return primitive.NewObjectID().Hex(), nil
+}
+
+// DocumentID parses a hex ObjectID.
+func (ObjectIDGenerator) DocumentID(id string) (interface{}, error) {
+	return primitive.ObjectIDFromHex(id)
+}
+
+// UUIDv4Generator creates random UUIDs (version 4), stored as strings.
+type UUIDv4Generator struct{}
+
+// NewID returns a new random UUID.
+func (UUIDv4Generator) NewID() (string, error) {
+	var u [16]byte
+	_, err := rand.Read(u[:])
+	if err != nil {
+		return "", err
+	}
+
+	return formatUUID(u, 4), nil
+}
+
+// DocumentID validates a UUID.
+func (UUIDv4Generator) DocumentID(id string) (interface{}, error) {
+	return parseUUID(id)
+}
+
+// UUIDv7Generator creates time ordered UUIDs (version 7), stored as strings.
+type UUIDv7Generator struct{}
+
+// NewID returns a new time ordered UUID.
+func (UUIDv7Generator) NewID() (string, error) {
+	var u [16]byte
+	_, err := rand.Read(u[6:])
+	if err != nil {
+		return "", err
+	}
+
+	// the first 48 bits are the unix time in milliseconds
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	copy(u[:6], ms[2:])
+
+	return formatUUID(u, 7), nil
+}
+
+// DocumentID validates a UUID.
+func (UUIDv7Generator) DocumentID(id string) (interface{}, error) {
+	return parseUUID(id)
+}
+
+// formatUUID sets the version and variant bits and formats the UUID.
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = (u[6] & 0x0f) | version<<4
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+// parseUUID validates the canonical form of a UUID.
+func parseUUID(id string) (string, error) {
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+		return "", fmt.Errorf("invalid uuid: %q", id)
+	}
+
+	_, err := hex.DecodeString(strings.Replace(id, "-", "", -1))
+	if err != nil {
+		return "", fmt.Errorf("invalid uuid: %q", id)
+	}
+
+	return id, nil
+}
+
+// ULIDGenerator creates ULIDs, lexicographically sortable ids stored as
+// strings.
+type ULIDGenerator struct{}
+
+// crockford is the base32 alphabet used by ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewID returns a new ULID.
+func (ULIDGenerator) NewID() (string, error) {
+	var u [16]byte
+	_, err := rand.Read(u[6:])
+	if err != nil {
+		return "", err
+	}
+
+	// the first 48 bits are the unix time in milliseconds
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(time.Now().UnixNano()/int64(time.Millisecond)))
+	copy(u[:6], ms[2:])
+
+	// encode the 128 bits as 26 characters of 5 bits, the first character
+	// only holds the 3 most significant bits
+	hi := binary.BigEndian.Uint64(u[:8])
+	lo := binary.BigEndian.Uint64(u[8:])
+
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+
+	return string(out[:]), nil
+}
+
+// DocumentID validates a ULID.
+func (ULIDGenerator) DocumentID(id string) (interface{}, error) {
+	if len(id) != 26 || strings.IndexByte("01234567", id[0]) == -1 {
+		return nil, fmt.Errorf("invalid ulid: %q", id)
+	}
+
+	for i := 1; i < len(id); i++ {
+		if strings.IndexByte(crockford, id[i]) == -1 {
+			return nil, fmt.Errorf("invalid ulid: %q", id)
+		}
+	}
+
+	return id, nil
+}
+
+// idGenerator returns the configured id generator, or the default one.
+func (s *Store) idGenerator() IDGenerator {
+	if s.MongoStore.IDGenerator != nil {
+		return s.MongoStore.IDGenerator
+	}
+	return ObjectIDGenerator{}
+}
+
+// documentID returns the mongo _id of the session. An id the generator can
+// not parse, such as one from a previous Options.IDGenerator, returns
+// ErrSessionNotFound: no stored session has it.
+func (s *Store) documentID(id string) (interface{}, error) {
+	documentID, err := s.idGenerator().DocumentID(id)
+	if err != nil {
+		return nil, wrapError(ErrSessionNotFound, err)
+	}
+	return documentID, nil
+}
+
+// Base32IDGenerator creates ids like gorilla redistore and the gorilla
+// filesystem store: 256 random bits in unpadded base32, stored as strings.
+type Base32IDGenerator struct{}
+
+// NewID returns a new base32 id.
+func (Base32IDGenerator) NewID() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(b), "="), nil
+}
+
+// DocumentID validates a base32 id.
+func (Base32IDGenerator) DocumentID(id string) (interface{}, error) {
+	_, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(id)
+	if err != nil || id == "" {
+		return nil, fmt.Errorf("invalid base32 id: %q", id)
+	}
+
+	return id, nil
+}
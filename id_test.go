@@ -0,0 +1,105 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestIDGenerators(t *testing.T) {
+	generators := map[string]mongostore.IDGenerator{
+		"objectid": mongostore.ObjectIDGenerator{},
+		"uuidv4":   mongostore.UUIDv4Generator{},
+		"uuidv7":   mongostore.UUIDv7Generator{},
+		"ulid":     mongostore.ULIDGenerator{},
+		"base32":   mongostore.Base32IDGenerator{},
+	}
+
+	for name, generator := range generators {
+		t.Run(name, func(t *testing.T) {
+			id, err := generator.NewID()
+			if err != nil {
+				t.Fatalf("failed to generate id: %v\n", err)
+			}
+
+			other, err := generator.NewID()
+			if err != nil {
+				t.Fatalf("failed to generate id: %v\n", err)
+			}
+			if id == other {
+				t.Fatalf("expected unique ids, got %s twice", id)
+			}
+
+			_, err = generator.DocumentID(id)
+			if err != nil {
+				t.Fatalf("failed to parse generated id %s: %v\n", id, err)
+			}
+
+			_, err = generator.DocumentID("not-an-id")
+			if err == nil {
+				t.Fatal("expected an invalid id to fail")
+			}
+
+			// save and reload a session using the generator
+			store := newTestStore(t, "sessions_id_test")
+			store.MongoStore.IDGenerator = generator
+
+			req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+			res := httptest.NewRecorder()
+
+			session, err := store.New(req, "test-session")
+			if err != nil {
+				t.Fatalf("failed to create new session: %v\n", err)
+			}
+			session.Values["test"] = "testdata"
+			err = store.Save(req, res, session)
+			if err != nil {
+				t.Fatalf("failed to insert session: %v\n", err)
+			}
+
+			req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+			req.Header.Add("Cookie", res.Header()["Set-Cookie"][0])
+
+			session, err = store.New(req, "test-session")
+			if err != nil {
+				t.Fatalf("failed to get session: %v\n", err)
+			}
+			if session.IsNew || session.Values["test"] != "testdata" {
+				t.Fatalf("expected the saved session, got %v", session.Values)
+			}
+		})
+	}
+}
+
+func TestIDGeneratorSwitch(t *testing.T) {
+	store := newTestStore(t, "sessions_id_switch_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the object id of the cookie is not a ulid, the user gets a new session
+	store.MongoStore.IDGenerator = mongostore.ULIDGenerator{}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", res.Header()["Set-Cookie"][0])
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatalf("expected a new session, got %v", session.Values)
+	}
+}
@@ -0,0 +1,157 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Index is a secondary index on a field of the mongo sessions.
+type Index struct {
+	// Field is the indexed field, for example "user_id".
+	Field string
+
+	// Name names the index, mongo picks the name when it is empty.
+	Name string
+}
+
+var (
+	// UserIDIndex speeds up finding the sessions of a user.
+	UserIDIndex = Index{Field: "user_id"}
+
+	// ExpiresAtIndex speeds up finding sessions by expiry.
+	ExpiresAtIndex = Index{Field: "expires_at"}
+
+	// TenantIndex speeds up finding the sessions of a tenant.
+	TenantIndex = Index{Field: "tenant_id"}
+)
+
+// defaultTTLField is the field of the time to live index when
+// TTLIndex.Field is empty.
+const defaultTTLField = "ttl"
+
+// TTLIndex defines the time to live index removing the expired sessions.
+type TTLIndex struct {
+	// Field is the indexed date field, "ttl" by default. Documents are
+	// removed MaxAge seconds after it, for example indexing "last_seen" with
+	// Options.LastSeenInterval removes idle sessions. The lifetimes of
+	// sessions with their own MaxAge and of Options.SoftDelete tombstones
+	// are written to the ttl field, they do not apply to other fields.
+	Field string
+
+	// PartialFilter only expires the documents matching it, for example
+	// bson.M{"persistent": false} keeps "remember me" sessions until they
+	// are deleted. The index is sparse without it.
+	PartialFilter interface{}
+}
+
+// ttlField returns the field of the time to live index.
+func (s *Store) ttlField() string {
+	if s.backend == BackendCosmos {
+		return cosmosTTLField
+	}
+	if s.MongoStore.ExpireAt {
+		return "expires_at"
+	}
+	if s.MongoStore.TTLIndex.Field != "" {
+		return s.MongoStore.TTLIndex.Field
+	}
+	return defaultTTLField
+}
+
+// EnsureIndexes creates the indexes of the store if they do not exist: the
+// time to live index, the shard key index when Options.ShardKey is set, the
+// token index when Options.OpaqueTokens is set, the
+// user_id index, Options.Indexes, the indexes of the overflow collection
+// when Options.OverflowThreshold is set, and the time to live index of
+// Options.ArchiveCollection. The time to live index is updated when its
+// expireAfterSeconds does not match the MaxAge.
+//
+// NewStore calls it, unless Options.SkipIndexCreation is set.
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	cols := []*mongo.Collection{s.MongoStore.Collection}
+	if s.MongoStore.Secondary != nil {
+		cols = append(cols, s.MongoStore.Secondary)
+	}
+
+	for _, col := range cols {
+		err := s.insertTTL(ctx, col)
+		if err != nil {
+			return fmt.Errorf("mongostore: adding time to live index to %s: %w", col.Name(), err)
+		}
+
+		if s.MongoStore.ShardKey.Field != "" {
+			err = insertShardKeyIndex(ctx, col, s.MongoStore.ShardKey)
+			if err != nil {
+				return fmt.Errorf("mongostore: adding shard key index to %s: %w", col.Name(), err)
+			}
+		}
+
+		if s.MongoStore.OpaqueTokens {
+			err = s.insertTokenIndex(ctx, col)
+			if err != nil {
+				return fmt.Errorf("mongostore: adding token index to %s: %w", col.Name(), err)
+			}
+		}
+
+		models := make([]mongo.IndexModel, 0, len(s.MongoStore.Indexes)+1)
+		for _, index := range s.indexes() {
+			indexOptions := options.Index()
+			if index.Name != "" {
+				indexOptions.SetName(index.Name)
+			}
+			// DocumentDB does not support collations
+			if s.MongoStore.IndexCollation != nil && s.backend != BackendDocumentDB {
+				indexOptions.SetCollation(s.MongoStore.IndexCollation)
+			}
+
+			models = append(models, mongo.IndexModel{
+				Keys:    bson.D{{Key: index.Field, Value: 1}},
+				Options: indexOptions,
+			})
+		}
+
+		// creating an index that already exists is a no-op
+		_, err = col.Indexes().CreateMany(ctx, models)
+		if err != nil {
+			return fmt.Errorf("mongostore: adding indexes to %s: %w", col.Name(), err)
+		}
+	}
+
+	if s.MongoStore.OverflowThreshold > 0 {
+		err := s.insertOverflowIndexes(ctx)
+		if err != nil {
+			return fmt.Errorf("mongostore: adding overflow indexes: %w", err)
+		}
+	}
+
+	if s.MongoStore.ArchiveCollection != nil && s.capabilities.TTLIndexes {
+		err := s.insertArchiveIndex(ctx)
+		if err != nil {
+			return fmt.Errorf("mongostore: adding archive index: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// indexes returns the secondary indexes to create, the user_id index is always
+// created unless Options.Indexes configures it.
+func (s *Store) indexes() []Index {
+	indexes := []Index{UserIDIndex}
+	for _, index := range s.MongoStore.Indexes {
+		switch index.Field {
+		case UserIDIndex.Field:
+			indexes[0] = index
+		case s.ttlField():
+			// the time to live index serves it
+		default:
+			indexes = append(indexes, index)
+		}
+	}
+
+	return indexes
+}
@@ -0,0 +1,179 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestEnsureIndexes(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_indexes_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:        col,
+			SkipIndexCreation: true,
+			TTLIndexName:      "sessions_ttl",
+			Indexes: []mongostore.Index{
+				{Field: "user_id", Name: "sessions_user_id"},
+				mongostore.ExpiresAtIndex,
+			},
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	// no indexes were created
+	if names := indexNames(t, store); len(names) != 0 {
+		t.Fatalf("expected no indexes, got %v", names)
+	}
+
+	err = store.EnsureIndexes(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to create indexes: %v\n", err)
+	}
+
+	names := indexNames(t, store)
+	if !names["sessions_ttl"] || !names["sessions_user_id"] || !names["expires_at_1"] {
+		t.Fatalf("expected the configured indexes, got %v", names)
+	}
+}
+
+// indexNames returns the names of the indexes of the store collection, except
+// the _id index.
+func indexNames(t *testing.T, store *mongostore.Store) map[string]bool {
+	t.Helper()
+
+	cursor, err := store.MongoStore.Collection.Indexes().List(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to list indexes: %v\n", err)
+	}
+
+	var indexes []struct {
+		Name string `bson:"name"`
+	}
+	err = cursor.All(context.TODO(), &indexes)
+	if err != nil {
+		t.Fatalf("failed to decode indexes: %v\n", err)
+	}
+
+	names := make(map[string]bool)
+	for _, index := range indexes {
+		if index.Name != "_id_" {
+			names[index.Name] = true
+		}
+	}
+
+	return names
+}
+
+func TestTTLIndexPartialFilter(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_ttl_index_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	_, err = mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:   col,
+			TTLIndexName: "sessions_ttl",
+			TTLIndex: mongostore.TTLIndex{
+				Field:         "modified_at",
+				PartialFilter: bson.M{"persistent": false},
+			},
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	cursor, err := col.Indexes().List(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to list indexes: %v\n", err)
+	}
+	var indexes []struct {
+		Name          string `bson:"name"`
+		Key           bson.M `bson:"key"`
+		Sparse        bool   `bson:"sparse"`
+		PartialFilter bson.M `bson:"partialFilterExpression"`
+	}
+	err = cursor.All(context.TODO(), &indexes)
+	if err != nil {
+		t.Fatalf("failed to decode indexes: %v\n", err)
+	}
+
+	for _, index := range indexes {
+		if index.Name != "sessions_ttl" {
+			continue
+		}
+		if index.Key["modified_at"] == nil || index.Sparse || index.PartialFilter["persistent"] != false {
+			t.Fatalf("unexpected time to live index %+v", index)
+		}
+		return
+	}
+	t.Fatal("no time to live index")
+}
+
+func TestExpireAt(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_expire_at_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection: col,
+			ExpireAt:   true,
+			Indexes:    []mongostore.Index{mongostore.ExpiresAtIndex},
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	// the time to live index serves ExpiresAtIndex
+	names := indexNames(t, store)
+	if len(names) != 2 || !names["expires_at_1"] {
+		t.Fatalf("expected the user_id and expires_at indexes, got %v", names)
+	}
+	if got := ttlExpireAfterSeconds(t, col); got != 0 {
+		t.Fatalf("expected expireAfterSeconds 0, got %d", got)
+	}
+
+	// the index does not follow the MaxAge
+	err = store.MaxAge(600)
+	if err != nil {
+		t.Fatalf("failed to set max age: %v\n", err)
+	}
+	if got := ttlExpireAfterSeconds(t, col); got != 0 {
+		t.Fatalf("expected expireAfterSeconds 0, got %d", got)
+	}
+}
@@ -0,0 +1,93 @@
+package mongostore
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// defaultPurgeInterval is how often expired sessions are purged on backends
+// that need it when Options.PurgeInterval is zero.
+const defaultPurgeInterval = time.Minute
+
+// janitor purges the expired sessions every Options.PurgeInterval.
+type janitor struct {
+	mu      sync.Mutex
+	started bool
+	closed  bool
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// purgeInterval returns how often the janitor purges the expired sessions,
+// zero when the time to live index removes them on time and they are not
+// archived first.
+func (s *Store) purgeInterval() time.Duration {
+	if s.MongoStore.PurgeInterval > 0 {
+		return s.MongoStore.PurgeInterval
+	}
+	if s.backend == BackendCosmos || !s.capabilities.TTLIndexes || s.MongoStore.ArchiveCollection != nil {
+		return defaultPurgeInterval
+	}
+	return 0
+}
+
+// startJanitor purges the expired sessions every interval until Close.
+func (s *Store) startJanitor() {
+	interval := s.purgeInterval()
+	if interval <= 0 {
+		return
+	}
+
+	j := &s.janitor
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.started || j.closed {
+		return
+	}
+	j.started = true
+	j.stop = make(chan struct{})
+	j.stopped = make(chan struct{})
+
+	go func() {
+		defer close(j.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-j.stop:
+				return
+			}
+
+			s.archiveExpiring(s.MongoStore.Context, interval)
+
+			purged, err := s.PurgeExpired(s.MongoStore.Context)
+			if err != nil {
+				s.log(s.MongoStore.Context, slog.LevelWarn, "purging expired sessions", errorAttr(err))
+				continue
+			}
+			if purged > 0 {
+				s.log(s.MongoStore.Context, slog.LevelInfo, "expired sessions purged", slog.String("op", "purge"), slog.Int64("count", purged))
+			}
+		}
+	}()
+}
+
+// stopJanitor stops purging the expired sessions.
+func (s *Store) stopJanitor() {
+	j := &s.janitor
+	j.mu.Lock()
+	started := j.started && !j.closed
+	j.closed = true
+	j.mu.Unlock()
+
+	if started {
+		close(j.stop)
+		<-j.stopped
+	}
+}
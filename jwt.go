@@ -0,0 +1,113 @@
+package mongostore
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+)
+
+// jwtHeader is the encoded header of every token, only HS256 is supported.
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// jwtClaims are the claims of a reference token, it only references the
+// session, the data stays in mongo.
+type jwtClaims struct {
+	SessionID string `json:"sid"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	Expires   int64  `json:"exp"`
+}
+
+// encodeJWT returns a signed HS256 JWT for the session id, valid for maxAge
+// seconds from now.
+func encodeJWT(key []byte, name string, id string, maxAge int, now time.Time) (string, error) {
+	claims, err := json.Marshal(jwtClaims{
+		SessionID: id,
+		Audience:  name,
+		IssuedAt:  now.Unix(),
+		Expires:   now.Add(time.Duration(maxAge) * time.Second).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	return unsigned + "." + signJWT(key, unsigned), nil
+}
+
+// decodeJWT validates the token at the time now and returns the session id
+// it references.
+func decodeJWT(key []byte, name string, token string, now time.Time) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return "", ErrInvalidJWT
+	}
+
+	// check the signature before looking at the claims
+	if !hmac.Equal([]byte(parts[2]), []byte(signJWT(key, parts[0]+"."+parts[1]))) {
+		return "", ErrInvalidJWT
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrInvalidJWT
+	}
+
+	var claims jwtClaims
+	err = json.Unmarshal(b, &claims)
+	if err != nil {
+		return "", ErrInvalidJWT
+	}
+
+	if claims.Audience != name || claims.SessionID == "" {
+		return "", ErrInvalidJWT
+	}
+
+	if claims.Expires < now.Unix() {
+		return "", wrapError(ErrSessionExpired, ErrInvalidJWT)
+	}
+
+	return claims.SessionID, nil
+}
+
+// signJWT returns the encoded HS256 signature of the unsigned token.
+func signJWT(key []byte, unsigned string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// encodeID encodes the session id for the client, as a JWT when
+// Options.JWTKey is set, with Options.Encoder when it is set, otherwise with
+// the securecookie codecs.
+func (s *Store) encodeID(name string, id string, maxAge int) (string, error) {
+	if len(s.MongoStore.JWTKey) > 0 {
+		return encodeJWT(s.MongoStore.JWTKey, name, id, s.serverMaxAge(maxAge), s.now())
+	}
+	if s.MongoStore.Encoder != nil {
+		return s.MongoStore.Encoder.Encode(name, id)
+	}
+	return securecookie.EncodeMulti(name, id, s.codecs()...)
+}
+
+// decodeID decodes the session id sent by the client, stale reports that it
+// was encoded with an older key pair.
+func (s *Store) decodeID(name string, value string) (id string, stale bool, err error) {
+	if len(s.MongoStore.JWTKey) > 0 {
+		id, err = decodeJWT(s.MongoStore.JWTKey, name, value, s.now())
+		return id, false, err
+	}
+	if s.MongoStore.Encoder != nil {
+		id, err = s.MongoStore.Encoder.Decode(name, value)
+		return id, false, err
+	}
+
+	stale, err = s.decodeMulti(name, value, &id)
+	return id, stale, err
+}
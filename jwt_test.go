@@ -0,0 +1,56 @@
+package mongostore_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestJWT(t *testing.T) {
+	store := newTestStore(t, "sessions_jwt_test")
+	store.MongoStore.JWTKey = []byte("test-jwt-key")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the cookie is a JWT
+	token := res.Result().Cookies()[0].Value
+	if strings.Count(token, ".") != 2 {
+		t.Fatalf("expected a jwt, got %s", token)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: token})
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+
+	// a tampered JWT is rejected before mongo is queried
+	store.MongoStore.DecodePolicy = mongostore.DecodeReport
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: token + "x"})
+
+	_, err = store.New(req, "test-session")
+	if !errors.Is(err, mongostore.ErrInvalidJWT) {
+		t.Fatalf("expected ErrInvalidJWT, got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package mongostore
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+// keyPrefix starts the Data keys of session.Values keys that are not
+// strings, followed by the name of the key type, a colon and the JSON of the
+// key, such as "~int:42".
+const keyPrefix = "~"
+
+// escapedPrefix starts the Data keys escaped because mongo rejects or
+// misreads them: keys with a dot, a NUL byte, or starting with a dollar sign.
+const escapedPrefix = keyPrefix + "%"
+
+var (
+	keyEscaper   = strings.NewReplacer("%", "%25", ".", "%2E", "$", "%24", "\x00", "%00")
+	keyUnescaper = strings.NewReplacer("%25", "%", "%2E", ".", "%24", "$", "%00", "\x00")
+)
+
+// keyTypes are the types of keys encoded without being registered.
+var keyTypes = map[string]reflect.Type{
+	"string":  reflect.TypeOf(""),
+	"bool":    reflect.TypeOf(false),
+	"int":     reflect.TypeOf(int(0)),
+	"int8":    reflect.TypeOf(int8(0)),
+	"int16":   reflect.TypeOf(int16(0)),
+	"int32":   reflect.TypeOf(int32(0)),
+	"int64":   reflect.TypeOf(int64(0)),
+	"uint":    reflect.TypeOf(uint(0)),
+	"uint8":   reflect.TypeOf(uint8(0)),
+	"uint16":  reflect.TypeOf(uint16(0)),
+	"uint32":  reflect.TypeOf(uint32(0)),
+	"uint64":  reflect.TypeOf(uint64(0)),
+	"float32": reflect.TypeOf(float32(0)),
+	"float64": reflect.TypeOf(float64(0)),
+}
+
+// encodeKey returns the Data key of a session.Values key. Strings are stored
+// as they are, keys of the basic types and of types registered with
+// RegisterType are stored with their type name. It reports false for keys
+// of other types, which can not be restored on load.
+func (s *Store) encodeKey(key interface{}) (string, bool) {
+	k, ok := s.typedKey(key)
+	if !ok {
+		return "", false
+	}
+
+	if strings.HasPrefix(k, "$") || strings.ContainsAny(k, ".\x00") {
+		return escapedPrefix + keyEscaper.Replace(k), true
+	}
+
+	return k, true
+}
+
+// typedKey returns the key as a string, with its type name unless it is a
+// string.
+func (s *Store) typedKey(key interface{}) (string, bool) {
+	// strings that look like an encoded key are encoded too
+	if k, ok := key.(string); ok && !strings.HasPrefix(k, keyPrefix) {
+		return k, true
+	}
+
+	t := reflect.TypeOf(key)
+	name, ok := s.typeNames[t]
+	if !ok {
+		if t == nil || keyTypes[t.Name()] != t {
+			return "", false
+		}
+		name = t.Name()
+	}
+
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", false
+	}
+
+	return keyPrefix + name + ":" + string(b), true
+}
+
+// decodeKey returns the session.Values key of a Data key. Keys that are not
+// encoded, or whose type is unknown, are returned as strings.
+func (s *Store) decodeKey(key string) interface{} {
+	if strings.HasPrefix(key, escapedPrefix) {
+		key = keyUnescaper.Replace(strings.TrimPrefix(key, escapedPrefix))
+	}
+	if !strings.HasPrefix(key, keyPrefix) {
+		return key
+	}
+
+	name, encoded, ok := strings.Cut(strings.TrimPrefix(key, keyPrefix), ":")
+	if !ok {
+		return key
+	}
+	t, ok := s.types[name]
+	if !ok {
+		t, ok = keyTypes[name]
+	}
+	if !ok {
+		return key
+	}
+
+	typed := reflect.New(t)
+	err := json.Unmarshal([]byte(encoded), typed.Interface())
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelWarn, "decoding session key", slog.String("key", key), errorAttr(err))
+		return key
+	}
+
+	return typed.Elem().Interface()
+}
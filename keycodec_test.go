@@ -0,0 +1,82 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type contextKey int
+
+func TestNonStringKeys(t *testing.T) {
+	store := newTestStore(t, "sessions_keycodec_test")
+	store.RegisterType("contextKey", contextKey(0))
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values[42] = "int"
+	session.Values[contextKey(1)] = "registered"
+	session.Values["~int:7"] = "string"
+	session.Values[struct{}{}] = "skipped"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	if session.Values[42] != "int" {
+		t.Fatalf("expected the int key to be restored, got %v", session.Values)
+	}
+	if session.Values[contextKey(1)] != "registered" {
+		t.Fatalf("expected the registered key to be restored, got %v", session.Values)
+	}
+	if session.Values["~int:7"] != "string" {
+		t.Fatalf("expected the string key to be restored, got %v", session.Values)
+	}
+	if _, ok := session.Values[struct{}{}]; ok {
+		t.Fatal("expected the unregistered key to be skipped")
+	}
+}
+
+func TestEscapedKeys(t *testing.T) {
+	store := newTestStore(t, "sessions_keycodec_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	keys := []interface{}{"user.name", "$where", "100%", "a.$b%2E", 1.5}
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	for i, k := range keys {
+		session.Values[k] = i
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	for _, k := range keys {
+		if _, ok := session.Values[k]; !ok {
+			t.Fatalf("expected key %v to be restored, got %v", k, session.Values)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package mongostore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultKeyRefreshInterval is how often the keys are fetched from
+// Options.KeyProvider when Options.KeyRefreshInterval is zero.
+const defaultKeyRefreshInterval = 5 * time.Minute
+
+// KeyProvider fetches the key pairs of the store from a key management
+// service, such as AWS KMS, GCP KMS or Vault, instead of passing them to
+// NewStore.
+type KeyProvider interface {
+	// Keys returns the key pairs like the keyPairs of NewStore, the pair
+	// encoding the cookies first.
+	Keys(ctx context.Context) ([][]byte, error)
+}
+
+// KeyProviderFunc adapts a function to a KeyProvider.
+type KeyProviderFunc func(ctx context.Context) ([][]byte, error)
+
+// Keys calls f(ctx).
+func (f KeyProviderFunc) Keys(ctx context.Context) ([][]byte, error) {
+	return f(ctx)
+}
+
+// generatedKeys is the key provider of Options.GenerateKeys, it stores a
+// generated key pair in a collection so every instance uses the same keys.
+type generatedKeys struct {
+	col *mongo.Collection
+	now func() time.Time
+}
+
+// generatedKeysID is the _id of the document holding the keys.
+const generatedKeysID = "keys"
+
+// Keys returns the stored key pairs, generating them on first use.
+func (g generatedKeys) Keys(ctx context.Context) ([][]byte, error) {
+	var doc struct {
+		Keys [][]byte `bson:"keys"`
+	}
+
+	find := func() error {
+		return g.col.FindOneAndUpdate(
+			ctx,
+			bson.M{"_id": generatedKeysID},
+			bson.M{"$setOnInsert": bson.M{
+				"keys": [][]byte{
+					securecookie.GenerateRandomKey(64),
+					securecookie.GenerateRandomKey(32),
+				},
+				"created_at": primitive.NewDateTimeFromTime(g.now()),
+			}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&doc)
+	}
+
+	err := find()
+	// another instance generated the keys first
+	if mongo.IsDuplicateKeyError(err) {
+		err = find()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return doc.Keys, nil
+}
+
+// keyProvider returns Options.KeyProvider, or the provider of
+// Options.GenerateKeys.
+func (s *Store) keyProvider() KeyProvider {
+	if s.MongoStore.KeyProvider != nil {
+		return s.MongoStore.KeyProvider
+	}
+	if !s.MongoStore.GenerateKeys {
+		return nil
+	}
+
+	col := s.MongoStore.KeysCollection
+	if col == nil {
+		col = s.MongoStore.Collection.Database().Collection(s.MongoStore.Collection.Name() + ".keys")
+	}
+	return generatedKeys{col: col, now: s.now}
+}
+
+// keyRefresh refreshes the keys from Options.KeyProvider.
+type keyRefresh struct {
+	mu      sync.Mutex
+	current [][]byte // the keys last returned by the provider
+	started bool
+	closed  bool
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// RefreshKeys fetches the keys from Options.KeyProvider, or the collection
+// of Options.GenerateKeys, and rotates to them
+// if they changed. The keys they replace keep decoding cookies until the
+// next change, and those cookies are re-issued with the new keys on Save.
+// It is called every Options.KeyRefreshInterval.
+func (s *Store) RefreshKeys(ctx context.Context) error {
+	provider := s.keyProvider()
+	if provider == nil {
+		return errors.New("mongostore: no key provider")
+	}
+
+	keys, err := provider.Keys(ctx)
+	if err != nil {
+		return fmt.Errorf("mongostore: fetching keys: %w", err)
+	}
+
+	kr := &s.keyRefresh
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if equalKeys(keys, kr.current) {
+		return nil
+	}
+
+	// keep decoding the cookies of the replaced keys
+	err = s.Rotate(append(pairs(keys), pairs(kr.current)...)...)
+	if err != nil {
+		return fmt.Errorf("mongostore: fetching keys: %w", err)
+	}
+	kr.current = keys
+	s.log(ctx, slog.LevelInfo, "session keys refreshed")
+
+	return nil
+}
+
+// startKeyRefresh refreshes the keys every interval until Close.
+func (s *Store) startKeyRefresh() {
+	kr := &s.keyRefresh
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if kr.started || kr.closed {
+		return
+	}
+	kr.started = true
+	kr.stop = make(chan struct{})
+	kr.stopped = make(chan struct{})
+
+	interval := s.MongoStore.KeyRefreshInterval
+	if interval <= 0 {
+		interval = defaultKeyRefreshInterval
+	}
+
+	go func() {
+		defer close(kr.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-kr.stop:
+				return
+			}
+
+			err := s.RefreshKeys(s.MongoStore.Context)
+			if err != nil {
+				// keep the current keys until the provider is back
+				s.log(s.MongoStore.Context, slog.LevelWarn, "refreshing session keys", errorAttr(err))
+			}
+		}
+	}()
+}
+
+// stopKeyRefresh stops refreshing the keys.
+func (s *Store) stopKeyRefresh() {
+	kr := &s.keyRefresh
+	kr.mu.Lock()
+	started := kr.started && !kr.closed
+	kr.closed = true
+	kr.mu.Unlock()
+
+	if started {
+		close(kr.stop)
+		<-kr.stopped
+	}
+}
+
+// pairs completes the last pair of keys, so more pairs can follow.
+func pairs(keys [][]byte) [][]byte {
+	p := append([][]byte{}, keys...)
+	if len(p)%2 != 0 {
+		p = append(p, nil)
+	}
+	return p
+}
+
+// equalKeys reports if a and b hold the same keys.
+func equalKeys(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
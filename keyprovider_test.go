@@ -0,0 +1,79 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestKeyProvider(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_keyprovider_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	var mu sync.Mutex
+	keys := [][]byte{securecookie.GenerateRandomKey(32)}
+	provider := mongostore.KeyProviderFunc(func(ctx context.Context) ([][]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return keys, nil
+	})
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:  col,
+			KeyProvider: provider,
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+	defer store.Close(context.TODO())
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	cookie := res.Header().Get("Set-Cookie")
+
+	// the provider reports new keys, the cookie of the old ones still works
+	mu.Lock()
+	keys = [][]byte{securecookie.GenerateRandomKey(32)}
+	mu.Unlock()
+
+	err = store.RefreshKeys(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to refresh keys: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", cookie)
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+}
@@ -0,0 +1,150 @@
+package mongostore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// minAuthKeyLength is the minimum length of an authentication key.
+const minAuthKeyLength = 32
+
+// Rotate replaces the key pairs of the store at runtime. Like in NewStore
+// the first pair encodes the cookies and the other pairs only decode them,
+// so pass the new pair first followed by the pairs being retired. It
+// returns ErrWeakKey and keeps the current keys if a key is too short.
+//
+// Cookies decoded with an older pair are re-issued with the first pair on
+// the next Save, even if the session did not change.
+func (s *Store) Rotate(keyPairs ...[]byte) error {
+	codecs, err := codecsFromPairs(keyPairs)
+	if err != nil {
+		return err
+	}
+	s.configureCodecs(codecs)
+
+	s.keysMu.Lock()
+	s.CookieStore.Codecs = codecs
+	s.keysMu.Unlock()
+
+	for _, route := range s.routes {
+		err = route.Rotate(keyPairs...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// codecsFromPairs checks the key pairs and returns their codecs. An empty
+// encryption key means no encryption, like a nil one.
+func codecsFromPairs(keyPairs [][]byte) ([]securecookie.Codec, error) {
+	err := checkKeys(keyPairs)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([][]byte, len(keyPairs))
+	for i, key := range keyPairs {
+		if len(key) > 0 {
+			pairs[i] = key
+		}
+	}
+
+	return securecookie.CodecsFromPairs(pairs...), nil
+}
+
+// checkKeys returns ErrWeakKey if there are no key pairs, an authentication
+// key is shorter than 32 bytes, or an encryption key is not an AES key.
+func checkKeys(keyPairs [][]byte) error {
+	if len(keyPairs) == 0 {
+		return wrapError(ErrWeakKey, errors.New("no keys"))
+	}
+
+	for i := 0; i < len(keyPairs); i += 2 {
+		pair := i/2 + 1
+
+		if n := len(keyPairs[i]); n < minAuthKeyLength {
+			return wrapError(ErrWeakKey, fmt.Errorf(
+				"authentication key of pair %d is %d bytes, at least %d are required", pair, n, minAuthKeyLength,
+			))
+		}
+
+		if i+1 < len(keyPairs) {
+			switch n := len(keyPairs[i+1]); n {
+			case 0, 16, 24, 32:
+			default:
+				return wrapError(ErrWeakKey, fmt.Errorf(
+					"encryption key of pair %d is %d bytes, AES requires 16, 24 or 32", pair, n,
+				))
+			}
+		}
+	}
+
+	return nil
+}
+
+// codecs returns the codecs of the current key pairs.
+func (s *Store) codecs() []securecookie.Codec {
+	s.keysMu.RLock()
+	defer s.keysMu.RUnlock()
+
+	return s.CookieStore.Codecs
+}
+
+// codecMaxAge returns the max age of the codecs, they must accept the
+// cookies of the longest session tier.
+func (s *Store) codecMaxAge() int {
+	age := s.defaultCookie.MaxAge
+	if s.MongoStore.PersistentMaxAge > age {
+		age = s.MongoStore.PersistentMaxAge
+	}
+	return age
+}
+
+// configureCodecs applies the settings of MaxAge and MaxLength to codecs.
+func (s *Store) configureCodecs(codecs []securecookie.Codec) {
+	for _, codec := range codecs {
+		sc, ok := codec.(*securecookie.SecureCookie)
+		if !ok {
+			continue
+		}
+		if age := s.codecMaxAge(); age > 0 {
+			sc.MaxAge(age)
+		}
+
+		// the store checks the length, so the error tells a cookie that is
+		// too long apart from other encoding errors
+		sc.MaxLength(0)
+	}
+}
+
+// decodeMulti is securecookie.DecodeMulti, it also reports if the value was
+// decoded by an older key pair than the first one.
+func (s *Store) decodeMulti(name string, value string, dst interface{}) (stale bool, err error) {
+	codecs := s.codecs()
+	if len(codecs) == 0 {
+		return false, errors.New("securecookie: no codecs provided")
+	}
+
+	var errs securecookie.MultiError
+	for i, codec := range codecs {
+		err := codec.Decode(name, value, dst)
+		if err == nil {
+			return i > 0, nil
+		}
+		errs = append(errs, err)
+	}
+
+	return false, errs
+}
+
+// mustReissue reports if the cookie of the session was decoded by an older
+// key pair and has to be re-issued.
+func mustReissue(session *sessions.Session) bool {
+	_, ok := session.Values[reissueKey]
+	return ok
+}
@@ -0,0 +1,146 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestRotate(t *testing.T) {
+	store := newTestStore(t, "sessions_keys_test")
+	store.MongoStore.SkipUnchanged = true
+
+	oldKeys := [][]byte{securecookie.GenerateRandomKey(32), securecookie.GenerateRandomKey(16)}
+	newKeys := [][]byte{securecookie.GenerateRandomKey(32), securecookie.GenerateRandomKey(16)}
+	err := store.Rotate(oldKeys...)
+	if err != nil {
+		t.Fatalf("failed to rotate keys: %v\n", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the old keys still decode the cookie
+	err = store.Rotate(append(newKeys, oldKeys...)...)
+	if err != nil {
+		t.Fatalf("failed to rotate keys: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	res = httptest.NewRecorder()
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+
+	// and the unchanged session is re-issued with the new keys
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if len(res.Header()["Set-Cookie"]) != 1 {
+		t.Fatal("expected the cookie to be re-issued. header:", res.Header())
+	}
+
+	err = store.Rotate(newKeys...)
+	if err != nil {
+		t.Fatalf("failed to rotate keys: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+}
+
+func TestWeakKeys(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_keys_test")
+
+	for _, keyPairs := range [][][]byte{
+		nil,
+		{[]byte("")},
+		{[]byte("short-authentication-key")},
+		{securecookie.GenerateRandomKey(32), []byte("not-an-aes-key")},
+	} {
+		_, err := mongostore.NewStore(col, http.Cookie{Path: "/", MaxAge: 240}, keyPairs...)
+		if !errors.Is(err, mongostore.ErrWeakKey) {
+			t.Fatalf("expected ErrWeakKey for %q, got %v", keyPairs, err)
+		}
+	}
+}
+
+func TestGenerateKeys(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_keys_test")
+	err := col.Database().Collection(col.Name() + ".keys").Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop keys collection: %v\n", err)
+	}
+
+	// two instances share the generated keys
+	newStore := func() *mongostore.Store {
+		store, err := mongostore.NewStoreWithOptions(
+			&mongostore.Options{
+				Collection:   col,
+				GenerateKeys: true,
+			},
+			http.Cookie{Path: "/", MaxAge: 240},
+		)
+		if err != nil {
+			t.Fatalf("failed to create store: %v\n", err)
+		}
+		t.Cleanup(func() { _ = store.Close(context.TODO()) })
+		return store
+	}
+	first, second := newStore(), newStore()
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := first.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = first.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = second.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+}
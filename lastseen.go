@@ -0,0 +1,69 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LastSeenIndex speeds up finding the sessions active recently, add it to
+// Options.Indexes with Options.LastSeenInterval.
+var LastSeenIndex = Index{Field: "last_seen"}
+
+// lastSeen returns the last_seen value of a session written now, or zero
+// without Options.LastSeenInterval.
+func (s *Store) lastSeen() primitive.DateTime {
+	if s.MongoStore.LastSeenInterval <= 0 {
+		return 0
+	}
+	return primitive.NewDateTimeFromTime(s.now())
+}
+
+// touch records the read of a session in last_seen, at most once every
+// Options.LastSeenInterval. A failed write is logged and does not fail the
+// read.
+func (s *Store) touch(filter bson.M, seen primitive.DateTime) {
+	interval := s.MongoStore.LastSeenInterval
+	if interval <= 0 {
+		return
+	}
+
+	now := s.now()
+	threshold := now.Add(-interval)
+	if seen != 0 && seen.Time().After(threshold) {
+		return
+	}
+
+	// concurrent reads agree on a single write
+	touchFilter := bson.M{"last_seen": bson.M{"$not": bson.M{"$gt": primitive.NewDateTimeFromTime(threshold)}}}
+	for k, v := range filter {
+		touchFilter[k] = v
+	}
+
+	_, err := s.writeCollection().UpdateOne(
+		s.MongoStore.Context,
+		touchFilter,
+		bson.M{"$set": bson.M{"last_seen": primitive.NewDateTimeFromTime(now)}},
+	)
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelWarn, "updating last seen", errorAttr(err))
+	}
+}
+
+// ActiveSessions returns the number of sessions read or saved within the
+// given duration, with Options.LastSeenInterval. The count is as precise as
+// the interval.
+func (s *Store) ActiveSessions(ctx context.Context, within time.Duration) (int64, error) {
+	since := primitive.NewDateTimeFromTime(s.now().Add(-within))
+
+	count, err := s.readCollection().CountDocuments(ctx, bson.M{"last_seen": bson.M{"$gte": since}})
+	if err != nil {
+		return 0, fmt.Errorf("mongostore: counting active sessions: %w", err)
+	}
+
+	return count, nil
+}
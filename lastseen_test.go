@@ -0,0 +1,61 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLastSeen(t *testing.T) {
+	store := newTestStore(t, "sessions_lastseen_test")
+	clock := &testClock{now: time.Now()}
+	store.MongoStore.Clock = clock
+	store.MongoStore.LastSeenInterval = time.Minute
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	// active in the last minute
+	active := func(within time.Duration) int64 {
+		count, err := store.ActiveSessions(context.TODO(), within)
+		if err != nil {
+			t.Fatalf("failed to count active sessions: %v\n", err)
+		}
+		return count
+	}
+	if n := active(time.Minute); n != 1 {
+		t.Fatalf("expected 1 active session, got %d", n)
+	}
+
+	// a read within the interval is not recorded
+	clock.now = clock.now.Add(30 * time.Second)
+	_, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if n := active(20 * time.Second); n != 0 {
+		t.Fatalf("expected 0 sessions active in the last 20s, got %d", n)
+	}
+
+	// a read after the interval is
+	clock.now = clock.now.Add(60 * time.Second)
+	_, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if n := active(time.Second); n != 1 {
+		t.Fatalf("expected 1 session active in the last second, got %d", n)
+	}
+}
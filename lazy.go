@@ -0,0 +1,64 @@
+package mongostore
+
+import (
+	"github.com/gorilla/sessions"
+)
+
+// Load reads a session returned by New with Options.LazyLoad from mongo,
+// and the data of a session moved to overflow chunks with
+// Options.OverflowThreshold. It does nothing if the session was already
+// read. Values set before Load, and the owner or tier, win over the stored
+// ones, call Load before deleting values.
+//
+// A session that is not in mongo, or expired, stays empty and becomes new.
+func (s *Store) Load(session *sessions.Session) error {
+	if !isLazy(session) {
+		return nil
+	}
+	if route := s.route(session.Name()); route != s {
+		return route.Load(session)
+	}
+
+	if _, ok := session.Values[lazyKey]; ok {
+		delete(session.Values, lazyKey)
+
+		// keep what the handler set before the read
+		set := make(map[interface{}]interface{}, len(session.Values))
+		for k, v := range session.Values {
+			set[k] = v
+		}
+
+		session.IsNew = true
+		err := s.load(nil, session)
+		if err == nil {
+			err = s.loadOverflow(session)
+		}
+
+		for k, v := range set {
+			session.Values[k] = v
+		}
+
+		return err
+	}
+
+	return s.loadOverflow(session)
+}
+
+// Values returns the values of the session, reading it from mongo first with
+// Options.LazyLoad.
+func (s *Store) Values(session *sessions.Session) (map[interface{}]interface{}, error) {
+	err := s.Load(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return session.Values, nil
+}
+
+// isLazy reports if the session, or its overflow chunks, were not read from
+// mongo yet.
+func isLazy(session *sessions.Session) bool {
+	_, lazy := session.Values[lazyKey]
+	_, pending := session.Values[overflowPendingKey]
+	return lazy || pending
+}
@@ -0,0 +1,67 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLazyLoad(t *testing.T) {
+	store := newTestStore(t, "sessions_lazy_test")
+	store.MongoStore.LazyLoad = true
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["foo"] = "bar"
+	session.Values["baz"] = "qux"
+
+	res := httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	// nothing is read until the values are used
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if session.Values["foo"] != nil {
+		t.Fatalf("expected the session not to be read, got %+v", session.Values)
+	}
+
+	values, err := store.Values(session)
+	if err != nil {
+		t.Fatalf("failed to read session: %v\n", err)
+	}
+	if values["foo"] != "bar" {
+		t.Fatalf("expected the stored value, got %+v", values)
+	}
+
+	// values set before the read win over the stored ones
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	session.Values["foo"] = "changed"
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	err = store.Load(session)
+	if err != nil {
+		t.Fatalf("failed to read session: %v\n", err)
+	}
+	if session.Values["foo"] != "changed" || session.Values["baz"] != "qux" {
+		t.Fatalf("expected the merged values, got %+v", session.Values)
+	}
+}
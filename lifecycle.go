@@ -0,0 +1,80 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Ping checks that mongo is reachable and the time to live index exists, for
+// use in readiness probes. The index is not checked on backends without
+// time to live indexes.
+func (s *Store) Ping(ctx context.Context) error {
+	err := s.MongoStore.Collection.Database().Client().Ping(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("mongostore: pinging mongo: %w", err)
+	}
+	if !s.capabilities.TTLIndexes {
+		return nil
+	}
+
+	found, _, err := findTTLIndex(ctx, s.MongoStore.Collection, s.ttlField())
+	if err != nil {
+		return fmt.Errorf("mongostore: listing indexes: %w", err)
+	}
+	if !found {
+		return errors.New("mongostore: time to live index missing")
+	}
+
+	return nil
+}
+
+// Close writes the updates queued with Options.WriteBehind and flushes the
+// sessions queued while mongo was unavailable, it returns an error if some of
+// them could not be written before ctx is done. It also stops refreshing the
+// keys of Options.KeyProvider, and purging the expired sessions.
+//
+// The mongo client is owned by the caller and is not disconnected, unless
+// the store was created with NewStoreFromURI.
+func (s *Store) Close(ctx context.Context) error {
+	s.stopKeyRefresh()
+	s.stopJanitor()
+
+	err := s.closeRoutes(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.closeWriteBehind()
+	if err != nil {
+		return err
+	}
+
+	for {
+		s.flushFallback()
+
+		s.fallbackMu.Lock()
+		pending := len(s.fallbackPending)
+		s.fallbackMu.Unlock()
+
+		if pending == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("mongostore: %d queued session(s) not written: %w", pending, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	if s.client != nil {
+		err = s.client.Disconnect(ctx)
+		if err != nil {
+			return fmt.Errorf("mongostore: disconnecting from mongo: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestPingAndClose(t *testing.T) {
+	store := newTestStore(t, "sessions_lifecycle_test")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := store.Ping(ctx)
+	if err != nil {
+		t.Fatalf("failed to ping: %v\n", err)
+	}
+
+	// without the TTL index the store is not ready
+	_, err = store.MongoStore.Collection.Indexes().DropAll(ctx)
+	if err != nil {
+		t.Fatalf("failed to drop mongo indexes: %v\n", err)
+	}
+	err = store.Ping(ctx)
+	if err == nil {
+		t.Fatal("expected ping to fail without the TTL index")
+	}
+
+	err = store.Close(ctx)
+	if err != nil {
+		t.Fatalf("failed to close: %v\n", err)
+	}
+}
+
+func TestNewStoreFromURI(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := mongostore.NewStoreFromURI(
+		ctx,
+		os.Getenv("MONGODB_URI"),
+		"test-database",
+		"sessions_uri_test",
+		http.Cookie{Path: "/", MaxAge: 240},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	err = store.Ping(ctx)
+	if err != nil {
+		t.Fatalf("failed to ping: %v\n", err)
+	}
+
+	// the store owns the client
+	err = store.Close(ctx)
+	if err != nil {
+		t.Fatalf("failed to close: %v\n", err)
+	}
+	err = store.Ping(ctx)
+	if err == nil {
+		t.Fatal("expected ping to fail once disconnected")
+	}
+}
+
+func TestNewStoreFromURIServerAPI(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store, err := mongostore.NewStoreFromURIWithOptions(
+		ctx,
+		os.Getenv("MONGODB_URI"),
+		"test-database",
+		"sessions_server_api_test",
+		&mongostore.Options{
+			ServerAPI: options.ServerAPI(options.ServerAPIVersion1).SetStrict(true),
+		},
+		http.Cookie{Path: "/", MaxAge: 240},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+	defer store.Close(ctx)
+
+	// the session commands are part of the stable API
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	rsp := httptest.NewRecorder()
+	session, err := store.Get(req, "hello")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	session.Values["a"] = "b"
+	err = session.Save(req, rsp)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	err = store.Ping(ctx)
+	if err != nil {
+		t.Fatalf("failed to ping: %v\n", err)
+	}
+}
@@ -0,0 +1,136 @@
+package mongostore
+
+import (
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionLimitPolicy decides what happens when a user goes over
+// Options.MaxSessionsPerUser.
+type SessionLimitPolicy int
+
+const (
+	// EvictOldest deletes the oldest sessions of the user.
+	EvictOldest SessionLimitPolicy = iota
+
+	// RejectNew deletes the session being saved and returns ErrSessionLimit.
+	RejectNew
+)
+
+// enforceSessionLimit applies the session limit for the owner of session.
+//
+// It runs after the session was written, so two concurrent logins can never
+// both slip under the limit: each one sees the other and, ordering the
+// sessions of the user oldest first by created_at then _id, they agree on
+// which sessions are kept. A login only evicts sessions older than its own,
+// so the newest sessions always stay.
+func (s *Store) enforceSessionLimit(session *sessions.Session) error {
+	max := s.MongoStore.MaxSessionsPerUser
+	owner := s.Owner(session)
+	if max <= 0 || owner == "" {
+		return nil
+	}
+
+	filter, err := s.sessionFilter(session)
+	if err != nil {
+		return err
+	}
+
+	// get the ids of all sessions of the user, oldest first
+	cursor, err := s.MongoStore.Collection.Find(
+		s.MongoStore.Context,
+		s.scope(session, bson.M{
+			"user_id":    owner,
+			"deleted_at": bson.M{"$exists": false},
+		}),
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+			SetProjection(bson.M{"_id": 1, "token_hash": 1}),
+	)
+	if err != nil {
+		return err
+	}
+
+	var ids []struct {
+		ID        interface{} `bson:"_id"`
+		TokenHash string      `bson:"token_hash"`
+	}
+	err = cursor.All(s.MongoStore.Context, &ids)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) <= max {
+		return nil
+	}
+
+	// find the position of the session being saved
+	current := -1
+	for i, id := range ids {
+		if id.ID == filter["_id"] || (id.TokenHash != "" && id.TokenHash == filter["token_hash"]) {
+			current = i
+		}
+	}
+
+	switch s.MongoStore.SessionLimitPolicy {
+	case RejectNew:
+		// the session is rejected if it is not one of the oldest sessions
+		if current != -1 && current < max {
+			return nil
+		}
+
+		_, err = s.deleteOne(s.MongoStore.Context, session)
+		if err != nil {
+			return err
+		}
+
+		return ErrSessionLimit
+
+	default:
+		// evict the oldest sessions over the limit, never the one being
+		// saved nor the newer ones, which their own login keeps
+		oldest := ids[:len(ids)-max]
+		if current != -1 && current < len(oldest) {
+			oldest = oldest[:current]
+		}
+		if len(oldest) == 0 {
+			return nil
+		}
+
+		evict := make([]interface{}, 0, len(oldest))
+		evicted := make([]string, 0, len(oldest))
+		for _, id := range oldest {
+			evict = append(evict, id.ID)
+			evicted = append(evicted, s.auditDocumentID(id.ID, id.TokenHash))
+		}
+
+		evictFilter := bson.M{
+			"_id": bson.M{"$in": evict},
+		}
+
+		s.archive(s.MongoStore.Context, evictFilter)
+		_, err = s.deleteCollection().DeleteMany(s.MongoStore.Context, evictFilter)
+		if err != nil {
+			return err
+		}
+
+		s.replicate(func(col *mongo.Collection) error {
+			_, err := col.DeleteMany(s.MongoStore.Context, evictFilter)
+			return err
+		})
+
+		for _, id := range evicted {
+			s.auditRecord(nil, &AuditRecord{
+				Op:        AuditEvict,
+				SessionID: id,
+				UserID:    owner,
+				TenantID:  tenant(session),
+			})
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+// login saves a new session owned by userID.
+func login(t *testing.T, store *mongostore.Store, userID string) error {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(session, userID)
+
+	return store.Save(req, res, session)
+}
+
+func TestSessionLimitEvictOldest(t *testing.T) {
+	store := newTestStore(t, "sessions_limit_test")
+	store.MongoStore.MaxSessionsPerUser = 2
+
+	for i := 0; i < 3; i++ {
+		err := login(t, store, "evict-user")
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	count, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{"user_id": "evict-user"})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 sessions, got %d", count)
+	}
+}
+
+func TestSessionLimitRejectNew(t *testing.T) {
+	store := newTestStore(t, "sessions_limit_test")
+	store.MongoStore.MaxSessionsPerUser = 1
+	store.MongoStore.SessionLimitPolicy = mongostore.RejectNew
+
+	err := login(t, store, "reject-user")
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	err = login(t, store, "reject-user")
+	if !errors.Is(err, mongostore.ErrSessionLimit) {
+		t.Fatalf("expected ErrSessionLimit, got %v", err)
+	}
+
+	count, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{"user_id": "reject-user"})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 session, got %d", count)
+	}
+}
+
+func TestSessionLimitConcurrentLogins(t *testing.T) {
+	store := newTestStore(t, "sessions_limit_concurrent_test")
+	store.MongoStore.MaxSessionsPerUser = 1
+
+	// concurrent logins never evict each other down to no session
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+			session, err := store.New(req, "test-session")
+			if err != nil {
+				errs <- err
+				return
+			}
+			store.SetOwner(session, "concurrent-user")
+			errs <- store.Save(req, httptest.NewRecorder(), session)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	count, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{"user_id": "concurrent-user"})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count == 0 {
+		t.Fatal("expected the newest session kept")
+	}
+}
@@ -0,0 +1,135 @@
+package mongostore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// lockPollInterval is how often Lock tries again while the session is
+// locked by another holder.
+const lockPollInterval = 25 * time.Millisecond
+
+// SessionLock is the lease on a session taken with Lock, released with
+// Unlock.
+type SessionLock struct {
+	session *sessions.Session
+	token   string
+
+	// Expires is when the lease ends if it is not released.
+	Expires time.Time
+}
+
+// Lock takes a lease on the stored session for ttl, for handlers that read,
+// change and save the session in a critical section, such as a checkout, and
+// must not overwrite each other across instances. It blocks until the lease
+// is taken or ctx is done, and returns ErrSessionNotFound if the session is
+// not in mongo.
+//
+// Once taken, the values of the session are reloaded from the primary, as
+// saved by the previous holder. The lease is advisory, saves without Lock are
+// not blocked, and it ends after ttl so a crashed holder does not lock the
+// session forever: keep ttl longer than the critical section.
+func (s *Store) Lock(ctx context.Context, session *sessions.Session, ttl time.Duration) (*SessionLock, error) {
+	if session.ID == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	filter, err := s.sessionFilter(session)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, 16)
+	_, err = rand.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: generating lock token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	for {
+		now := s.now()
+		expires := now.Add(ttl)
+
+		// the session is free, or the lease of the previous holder ended
+		free := bson.M{"$and": bson.A{filter, bson.M{"$or": bson.A{
+			bson.M{"lock": bson.M{"$exists": false}},
+			bson.M{"lock.until": bson.M{"$lte": primitive.NewDateTimeFromTime(now)}},
+		}}}}
+		err := s.MongoStore.Collection.FindOneAndUpdate(
+			ctx,
+			free,
+			bson.M{"$set": bson.M{"lock": bson.M{
+				"token": token,
+				"until": primitive.NewDateTimeFromTime(expires),
+			}}},
+			options.FindOneAndUpdate().SetProjection(bson.M{"_id": 1}),
+		).Err()
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("mongostore: locking session: %w", err)
+		}
+
+		if err == nil {
+			lock := &SessionLock{session: session, token: token, Expires: expires}
+
+			stored := s.storedSession(session)
+			err = s.findOneIn(stored, s.primaryCollection())
+			if err != nil {
+				_ = s.Unlock(context.Background(), lock)
+				return nil, err
+			}
+			session.Values = stored.Values
+
+			return lock, nil
+		}
+
+		// locked by another holder, unless the session is gone
+		err = s.primaryCollection().FindOne(ctx, filter).Err()
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, wrapError(ErrSessionNotFound, err)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("mongostore: locking session: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("mongostore: locking session: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+// Unlock releases the lease taken with Lock. It returns ErrLockLost if the
+// lease ended before, the changes saved in the critical section may have
+// been overwritten by the next holder.
+func (s *Store) Unlock(ctx context.Context, lock *SessionLock) error {
+	filter, err := s.sessionFilter(lock.session)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.MongoStore.Collection.UpdateOne(
+		ctx,
+		bson.M{"$and": bson.A{filter, bson.M{"lock.token": lock.token}}},
+		bson.M{"$unset": bson.M{"lock": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("mongostore: unlocking session: %w", err)
+	}
+	if res.MatchedCount == 0 || s.now().After(lock.Expires) {
+		return ErrLockLost
+	}
+
+	return nil
+}
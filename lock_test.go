@@ -0,0 +1,86 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestLock(t *testing.T) {
+	store := newTestStore(t, "sessions_lock_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["cart"] = "empty"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	first, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	second, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	lock, err := store.Lock(context.TODO(), first, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to lock session: %v\n", err)
+	}
+
+	// the second holder waits for the lease
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	_, err = store.Lock(ctx, second, time.Minute)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the session to stay locked, got %v", err)
+	}
+
+	first.Values["cart"] = "full"
+	err = store.Save(req, httptest.NewRecorder(), first)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	err = store.Unlock(context.TODO(), lock)
+	if err != nil {
+		t.Fatalf("failed to unlock session: %v\n", err)
+	}
+
+	// the next holder reads the values saved in the critical section
+	lock, err = store.Lock(context.TODO(), second, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("failed to lock session: %v\n", err)
+	}
+	if second.Values["cart"] != "full" {
+		t.Fatalf("expected the session to be reloaded, got %v", second.Values["cart"])
+	}
+
+	// an expired lease is taken over
+	time.Sleep(20 * time.Millisecond)
+	next, err := store.Lock(context.TODO(), first, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to lock session: %v\n", err)
+	}
+	err = store.Unlock(context.TODO(), lock)
+	if !errors.Is(err, mongostore.ErrLockLost) {
+		t.Fatalf("expected ErrLockLost, got %v", err)
+	}
+	err = store.Unlock(context.TODO(), next)
+	if err != nil {
+		t.Fatalf("failed to unlock session: %v\n", err)
+	}
+}
@@ -0,0 +1,68 @@
+package mongostore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+)
+
+// loggerContextKey is the context key of the logger set with WithLogger.
+type loggerContextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger, the store logs the
+// operations of requests with this context with it instead of
+// Options.Logger, for request-scoped attributes.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// logger returns the logger of the operations run with ctx.
+func (s *Store) logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	if s.MongoStore.Logger != nil {
+		return s.MongoStore.Logger
+	}
+	return slog.Default()
+}
+
+// log writes a record, unless its level is below Options.LogLevel.
+func (s *Store) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if s.MongoStore.LogLevel != nil && level < s.MongoStore.LogLevel.Level() {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	attrs = s.requestIDAttrs(ctx, attrs)
+
+	s.logger(ctx).LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logContext returns the context of the logs of a request, r may be nil.
+func (s *Store) logContext(r *http.Request) context.Context {
+	if r != nil {
+		return r.Context()
+	}
+	return s.MongoStore.Context
+}
+
+// sessionIDAttr returns the session id for a log record. Session ids are
+// bearer credentials, only a short hash is logged unless
+// Options.LogSessionIDs is set.
+func (s *Store) sessionIDAttr(id string) slog.Attr {
+	if s.MongoStore.LogSessionIDs || id == "" {
+		return slog.String("session_id", id)
+	}
+
+	sum := sha256.Sum256([]byte(id))
+	return slog.String("session_id", hex.EncodeToString(sum[:6]))
+}
+
+// errorAttr returns the error of a log record.
+func errorAttr(err error) slog.Attr {
+	return slog.Any("error", err)
+}
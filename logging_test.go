@@ -0,0 +1,93 @@
+package mongostore_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+// logRecords decodes the records written by a slog.JSONHandler.
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			t.Fatalf("failed to decode log record: %v\n", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogger(t *testing.T) {
+	store := newTestStore(t, "sessions_logging_test")
+
+	var buf bytes.Buffer
+	store.MongoStore.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req = req.WithContext(mongostore.WithLogger(req.Context(), store.MongoStore.Logger.With("request_id", "abc")))
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	var inserted map[string]interface{}
+	for _, record := range logRecords(t, &buf) {
+		if record["msg"] == "session inserted" {
+			inserted = record
+		}
+	}
+	if inserted == nil {
+		t.Fatalf("expected an insert record, got %s", buf.String())
+	}
+	if inserted["request_id"] != "abc" || inserted["op"] != "insert" || inserted["name"] != "test-session" {
+		t.Fatalf("unexpected insert record %v", inserted)
+	}
+	if id, _ := inserted["session_id"].(string); id == "" || id == session.ID || strings.Contains(buf.String(), session.ID) {
+		t.Fatalf("expected a hashed session id, got %v", inserted["session_id"])
+	}
+
+	// records below the level are dropped
+	buf.Reset()
+	store.MongoStore.LogLevel = slog.LevelWarn
+	session.Values["test"] = "updated"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no records, got %s", buf.String())
+	}
+
+	// ids are logged as they are on request
+	store.MongoStore.LogLevel = nil
+	store.MongoStore.LogSessionIDs = true
+	session.Values["test"] = "again"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if !strings.Contains(buf.String(), session.ID) {
+		t.Fatalf("expected the session id in %s", buf.String())
+	}
+}
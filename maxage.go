@@ -0,0 +1,30 @@
+package mongostore
+
+// MaxAge sets the default lifetime of sessions in seconds, like the MaxAge of
+// other gorilla stores.
+//
+// It updates the default cookie options, the max age of the securecookie
+// codecs, and the expireAfterSeconds of the time to live index, unless
+// Options.ExpireAt is set. Call it before serving requests. An age of 0 makes
+// browser session cookies, kept in mongo for Options.BrowserSessionRetention.
+func (s *Store) MaxAge(age int) error {
+	s.defaultCookie.MaxAge = age
+	s.CookieStore.Options.MaxAge = age
+
+	s.configureCodecs(s.codecs())
+
+	// reconcile the time to live indexes
+	err := s.insertTTL(s.MongoStore.Context, s.MongoStore.Collection)
+	if err != nil {
+		return err
+	}
+
+	if s.MongoStore.Secondary != nil {
+		err = s.insertTTL(s.MongoStore.Context, s.MongoStore.Secondary)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package mongostore
+
+import (
+	"fmt"
+)
+
+// defaultMaxLength is the securecookie default, browsers drop cookies over
+// 4096 bytes.
+const defaultMaxLength = 4096
+
+// MaxLength restricts the length of the cookies, like the MaxLength of other
+// gorilla stores. Save returns ErrCookieTooLong instead of sending a cookie
+// over the limit, and New rejects longer cookies with ErrCookieDecode. Zero
+// disables the check, the default is 4096.
+func (s *Store) MaxLength(l int) {
+	s.maxLength = l
+	s.configureCodecs(s.codecs())
+
+	for _, route := range s.routes {
+		route.MaxLength(l)
+	}
+}
+
+// checkLength returns ErrCookieTooLong if the cookie with the encoded value
+// is over the maximum length.
+func (s *Store) checkLength(name string, encoded string) error {
+	length := len(name) + len("=") + len(encoded)
+	if s.maxLength > 0 && length > s.maxLength {
+		return wrapError(
+			ErrCookieTooLong,
+			fmt.Errorf("%d bytes, the maximum is %d", length, s.maxLength),
+		)
+	}
+
+	return nil
+}
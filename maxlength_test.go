@@ -0,0 +1,38 @@
+package mongostore_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestMaxLength(t *testing.T) {
+	store := newTestStore(t, "sessions_maxlength_test")
+	store.MaxLength(32)
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	err = store.Save(req, res, session)
+	if !errors.Is(err, mongostore.ErrCookieTooLong) {
+		t.Fatalf("expected ErrCookieTooLong, got %v", err)
+	}
+	if res.Header().Get("Set-Cookie") != "" {
+		t.Fatal("expected no cookie")
+	}
+
+	// zero disables the check
+	store.MaxLength(0)
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+}
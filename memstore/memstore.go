@@ -0,0 +1,543 @@
+// Package memstore keeps sessions in memory behind the same methods as
+// mongostore.Store, so applications can unit test their handlers without a
+// running MongoDB.
+//
+// The clock of the store can be moved forward with Advance to expire
+// sessions, and Fail makes every operation fail to test how handlers cope
+// with an unavailable store.
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+// metaKey is the type of the keys the store uses to keep its own metadata in
+// session.Values.
+type metaKey int
+
+const (
+	// ownerKey holds the owner (user id) of the session.
+	ownerKey metaKey = iota
+
+	// persistentKey flags a "remember me" session.
+	persistentKey
+)
+
+// record is a saved session.
+type record struct {
+	values     map[interface{}]interface{}
+	userID     string
+	persistent bool
+	created    time.Time
+	modified   time.Time
+	expires    time.Time
+}
+
+// Store stores sessions in memory, it is safe for concurrent use.
+//
+// It implements mongostore.SessionStore.
+type Store struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+
+	// PersistentMaxAge is the lifetime in seconds of "remember me" sessions,
+	// zero uses the MaxAge of Options.
+	PersistentMaxAge int
+
+	// DecodePolicy is what New does with a cookie that can not be decoded,
+	// like mongostore.Options.DecodePolicy. mongostore.DecodeLenient by
+	// default.
+	DecodePolicy mongostore.DecodePolicy
+
+	mu       sync.Mutex
+	offset   time.Duration // how far Advance moved the clock
+	failure  error         // the error set with Fail
+	sessions map[string]*record
+}
+
+var _ mongostore.SessionStore = (*Store)(nil)
+
+// NewStore returns an empty store using cookie as the default cookie options
+// and the keys like mongostore.NewStore.
+func NewStore(cookie http.Cookie, keyPairs ...[]byte) *Store {
+	return &Store{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     cookie.Path,
+			Domain:   cookie.Domain,
+			MaxAge:   cookie.MaxAge,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+			SameSite: cookie.SameSite,
+		},
+		sessions: make(map[string]*record),
+	}
+}
+
+// Now returns the time of the store clock.
+func (s *Store) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.now()
+}
+
+func (s *Store) now() time.Time {
+	return time.Now().Add(s.offset)
+}
+
+// Advance moves the clock of the store forward, sessions expire as if d had
+// passed.
+func (s *Store) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.offset += d
+}
+
+// Fail makes every following operation of the store fail with err, such as
+// mongostore.ErrStoreUnavailable. A nil err makes the store work again.
+func (s *Store) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failure = err
+}
+
+// Get returns a session for the given name after adding it to the registry,
+// see mongostore.Store.Get.
+func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the
+// registry, see mongostore.Store.New. A cookie that can not be decoded gives
+// a new session, see DecodePolicy.
+func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var id string
+	err = securecookie.DecodeMulti(name, cookie.Value, &id, s.Codecs...)
+	if err != nil {
+		err = fmt.Errorf("%w: %v", mongostore.ErrCookieDecode, err)
+		switch s.DecodePolicy {
+		case mongostore.DecodeStrict:
+			return nil, err
+		case mongostore.DecodeReport:
+			return session, err
+		}
+		return session, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	rec, ok := s.load(id)
+	if !ok {
+		return session, nil
+	}
+
+	session.ID = id
+	for k, v := range rec.values {
+		session.Values[k] = v
+	}
+	if rec.userID != "" {
+		session.Values[ownerKey] = rec.userID
+	}
+	if rec.persistent {
+		session.Values[persistentKey] = true
+	}
+	session.Options.MaxAge = s.tierMaxAge(session)
+	session.IsNew = false
+
+	return session, nil
+}
+
+// Save stores the session and writes its cookie, a session with a negative
+// MaxAge is deleted.
+func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if session.Options.MaxAge < 0 {
+		delete(s.sessions, session.ID)
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	now := s.now()
+	rec, ok := s.sessions[session.ID]
+	if !ok || session.ID == "" {
+		session.ID = primitive.NewObjectIDFromTimestamp(now).Hex()
+		rec = &record{created: now}
+		s.sessions[session.ID] = rec
+	}
+
+	rec.values = make(map[interface{}]interface{}, len(session.Values))
+	for k, v := range session.Values {
+		if _, ok := k.(metaKey); ok {
+			continue
+		}
+		rec.values[k] = v
+	}
+	rec.userID = s.Owner(session)
+	rec.persistent = s.IsPersistent(session)
+	rec.modified = now
+	rec.expires = now.Add(time.Duration(session.Options.MaxAge) * time.Second)
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+
+	return nil
+}
+
+// load returns the session with the given id, removing it if it expired
+// like the time to live index.
+func (s *Store) load(id string) (*record, bool) {
+	rec, ok := s.sessions[id]
+	if ok && rec.expires.Before(s.now()) {
+		delete(s.sessions, id)
+		return nil, false
+	}
+	return rec, ok
+}
+
+// SetOwner associates the session with a user, see
+// mongostore.Store.SetOwner.
+func (s *Store) SetOwner(session *sessions.Session, userID string) {
+	session.Values[ownerKey] = userID
+}
+
+// Owner returns the user associated with the session.
+func (s *Store) Owner(session *sessions.Session) string {
+	userID, _ := session.Values[ownerKey].(string)
+	return userID
+}
+
+// SetPersistent moves the session to the "remember me" tier.
+func (s *Store) SetPersistent(session *sessions.Session, persistent bool) {
+	session.Values[persistentKey] = persistent
+	session.Options.MaxAge = s.tierMaxAge(session)
+}
+
+// IsPersistent reports if the session is a "remember me" session.
+func (s *Store) IsPersistent(session *sessions.Session) bool {
+	persistent, _ := session.Values[persistentKey].(bool)
+	return persistent
+}
+
+// tierMaxAge returns the lifetime of the tier of the session.
+func (s *Store) tierMaxAge(session *sessions.Session) int {
+	if s.IsPersistent(session) && s.PersistentMaxAge > 0 {
+		return s.PersistentMaxAge
+	}
+	return s.Options.MaxAge
+}
+
+// Middleware returns a middleware loading the session of the given name into
+// the request context and saving it if the handler modified it, see
+// mongostore.Store.Middleware. Handlers get the session with
+// mongostore.FromContext.
+func (s *Store) Middleware(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, err := s.Get(r, name)
+			if errors.Is(err, mongostore.ErrCookieDecode) {
+				session = sessions.NewSession(s, name)
+				opts := *s.Options
+				session.Options = &opts
+				session.IsNew = true
+				err = nil
+			}
+			if err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			r = r.WithContext(mongostore.NewContext(r.Context(), session))
+
+			sw := &sessionWriter{
+				ResponseWriter: w,
+				store:          s,
+				request:        r,
+				session:        session,
+				values:         copyValues(session.Values),
+				options:        *session.Options,
+			}
+
+			next.ServeHTTP(sw, r)
+			sw.save()
+		})
+	}
+}
+
+// sessionWriter saves the session before the response headers are written.
+type sessionWriter struct {
+	http.ResponseWriter
+
+	store   *Store
+	request *http.Request
+	session *sessions.Session
+
+	// the session when it was loaded
+	values  map[interface{}]interface{}
+	options sessions.Options
+	saved   bool
+}
+
+// save saves the session once, if it was modified.
+func (w *sessionWriter) save() {
+	if w.saved {
+		return
+	}
+	w.saved = true
+
+	if reflect.DeepEqual(w.values, w.session.Values) && w.options == *w.session.Options {
+		return
+	}
+	_ = w.store.Save(w.request, w.ResponseWriter, w.session)
+}
+
+func (w *sessionWriter) WriteHeader(code int) {
+	w.save()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write(b)
+}
+
+// copyValues returns a shallow copy of session values.
+func copyValues(values map[interface{}]interface{}) map[interface{}]interface{} {
+	c := make(map[interface{}]interface{}, len(values))
+	for k, v := range values {
+		c[k] = v
+	}
+	return c
+}
+
+// mongoSession returns a saved session as it would be stored in mongo.
+func mongoSession(id string, rec *record) *mongostore.MongoSession {
+	objectID, _ := primitive.ObjectIDFromHex(id)
+
+	data := make(primitive.M, len(rec.values))
+	for k, v := range rec.values {
+		if key, ok := k.(string); ok {
+			data[key] = v
+		}
+	}
+
+	return &mongostore.MongoSession{
+		ID:         objectID,
+		Data:       data,
+		Modified:   primitive.NewDateTimeFromTime(rec.modified),
+		Expires:    primitive.NewDateTimeFromTime(rec.expires),
+		Created:    primitive.NewDateTimeFromTime(rec.created),
+		UserID:     rec.userID,
+		Persistent: rec.persistent,
+	}
+}
+
+// ListSessions returns the sessions, newest first.
+func (s *Store) ListSessions(ctx context.Context, opts mongostore.ListOptions) ([]*mongostore.MongoSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	var last *mongostore.MongoSession
+	if opts.After != "" {
+		var err error
+		last, err = mongostore.ParsePageToken(opts.After)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// before reports if a is listed before b
+	before := func(a, b *mongostore.MongoSession) bool {
+		newer := a.Created > b.Created
+		if a.Created == b.Created {
+			newer = a.ID.(primitive.ObjectID).Hex() > b.ID.(primitive.ObjectID).Hex()
+		}
+		return newer == (opts.Sort == mongostore.ListNewest)
+	}
+
+	var list []*mongostore.MongoSession
+	for id, rec := range s.sessions {
+		if opts.UserID != "" && rec.userID != opts.UserID {
+			continue
+		}
+		session := mongoSession(id, rec)
+		if last != nil && !before(last, session) {
+			continue
+		}
+		list = append(list, session)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return before(list[i], list[j])
+	})
+
+	if opts.Skip >= int64(len(list)) {
+		return nil, nil
+	}
+	list = list[opts.Skip:]
+	if opts.Limit > 0 && opts.Limit < int64(len(list)) {
+		list = list[:opts.Limit]
+	}
+
+	return list, nil
+}
+
+// FindSession returns the session with the given id, or
+// mongostore.ErrSessionNotFound.
+func (s *Store) FindSession(ctx context.Context, id string) (*mongostore.MongoSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return nil, s.failure
+	}
+
+	rec, ok := s.load(id)
+	if !ok {
+		return nil, mongostore.ErrSessionNotFound
+	}
+
+	return mongoSession(id, rec), nil
+}
+
+// DeleteSession deletes the session with the given id, or returns
+// mongostore.ErrSessionNotFound.
+func (s *Store) DeleteSession(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return s.failure
+	}
+
+	if _, ok := s.sessions[id]; !ok {
+		return mongostore.ErrSessionNotFound
+	}
+	delete(s.sessions, id)
+
+	return nil
+}
+
+// DeleteUserSessions deletes every session of a user, and returns how many
+// were deleted.
+func (s *Store) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return 0, s.failure
+	}
+
+	var deleted int64
+	for id, rec := range s.sessions {
+		if rec.userID == userID {
+			delete(s.sessions, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// PurgeExpired deletes the expired sessions, and returns how many were
+// deleted.
+func (s *Store) PurgeExpired(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return 0, s.failure
+	}
+
+	var deleted int64
+	for id, rec := range s.sessions {
+		if rec.expires.Before(s.now()) {
+			delete(s.sessions, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// CountSessions returns the number of sessions.
+func (s *Store) CountSessions(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failure != nil {
+		return 0, s.failure
+	}
+
+	return int64(len(s.sessions)), nil
+}
+
+// Stats counts the sessions.
+func (s *Store) Stats(ctx context.Context) (mongostore.Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var stats mongostore.Stats
+	if s.failure != nil {
+		return stats, s.failure
+	}
+
+	for _, rec := range s.sessions {
+		stats.Sessions++
+		if rec.userID != "" {
+			stats.Owned++
+		}
+		if rec.persistent {
+			stats.Persistent++
+		}
+		if rec.expires.Before(s.now()) {
+			stats.Expired++
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,145 @@
+package memstore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"github.com/glezjose/mongostore"
+	"github.com/glezjose/mongostore/memstore"
+)
+
+func newTestStore() *memstore.Store {
+	return memstore.NewStore(
+		http.Cookie{Path: "/", MaxAge: 240, HttpOnly: true},
+		securecookie.GenerateRandomKey(32),
+	)
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	store := newTestStore()
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["foo"] = "bar"
+	store.SetOwner(session, "mem-user")
+
+	res := httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	loaded, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if loaded.IsNew || loaded.Values["foo"] != "bar" || store.Owner(loaded) != "mem-user" {
+		t.Fatalf("expected the saved session, got %+v", loaded.Values)
+	}
+
+	mongoSession, err := store.FindSession(context.Background(), loaded.ID)
+	if err != nil || mongoSession.UserID != "mem-user" {
+		t.Fatalf("expected to find the session, got %v", err)
+	}
+
+	// the session expires when the clock moves past its max age
+	store.Advance(5 * time.Minute)
+	expired, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if !expired.IsNew {
+		t.Fatal("expected the session to have expired")
+	}
+}
+
+func TestFail(t *testing.T) {
+	store := newTestStore()
+	store.Fail(mongostore.ErrStoreUnavailable)
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if !errors.Is(err, mongostore.ErrStoreUnavailable) {
+		t.Fatalf("expected ErrStoreUnavailable, got %v", err)
+	}
+
+	store.Fail(nil)
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	store := newTestStore()
+
+	handler := store.Middleware("test-session")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := mongostore.FromContext(r.Context())
+		if r.URL.Query().Get("set") != "" {
+			session.Values["foo"] = r.URL.Query().Get("set")
+		}
+	}))
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/?set=bar", nil))
+	cookie := res.Header().Get("Set-Cookie")
+	if cookie == "" {
+		t.Fatal("expected the session to be saved")
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Cookie", cookie)
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	if res.Header().Get("Set-Cookie") != "" {
+		t.Fatal("expected the unmodified session not to be saved")
+	}
+
+	count, err := store.CountSessions(context.Background())
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 session, got %d %v", count, err)
+	}
+}
+
+func TestDecodePolicy(t *testing.T) {
+	store := newTestStore()
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: "tampered"})
+
+	// like mongostore a new session comes back without an error
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if session == nil || !session.IsNew {
+		t.Fatalf("expected a new session, got %v", session)
+	}
+
+	store.DecodePolicy = mongostore.DecodeReport
+	session, err = store.New(req, "test-session")
+	if !errors.Is(err, mongostore.ErrCookieDecode) || session == nil {
+		t.Fatalf("expected a new session with ErrCookieDecode, got %v, %v", session, err)
+	}
+
+	store.DecodePolicy = mongostore.DecodeStrict
+	session, err = store.New(req, "test-session")
+	if !errors.Is(err, mongostore.ErrCookieDecode) || session != nil {
+		t.Fatalf("expected ErrCookieDecode without a session, got %v, %v", session, err)
+	}
+}
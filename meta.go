@@ -0,0 +1,78 @@
+package mongostore
+
+// metaKey is the type of the keys the store uses to keep its own metadata in
+// session.Values, values stored under a metaKey are never written to Data.
+type metaKey int
+
+const (
+	// ownerKey holds the owner (user id) of the session.
+	ownerKey metaKey = iota
+
+	// ownerChangedKey flags that the owner was set during this request.
+	ownerChangedKey
+
+	// persistentKey flags a "remember me" session.
+	persistentKey
+
+	// transportKey holds the index of the transport the session came in on.
+	transportKey
+
+	// tenantKey holds the tenant of the request that loaded the session.
+	tenantKey
+
+	// shardKeyKey holds the value of the shard key stored in mongo.
+	shardKeyKey
+
+	// fingerprintKey holds the fingerprint of the session when it was
+	// loaded or last saved.
+	fingerprintKey
+
+	// overflowKey holds the id of the overflow chunks holding the data of
+	// the session.
+	overflowKey
+
+	// overflowPendingKey flags a session whose overflow chunks were not
+	// read yet, see Load.
+	overflowPendingKey
+
+	// lazyKey flags a session not read from mongo yet, with
+	// Options.LazyLoad.
+	lazyKey
+
+	// reissueKey flags a session whose cookie was decoded by an older key
+	// pair.
+	reissueKey
+
+	// clientKey holds the ClientInfo of the request that loaded the
+	// session, with Options.ClientBinding.
+	clientKey
+
+	// mismatchKey holds the BindingMismatch of a session loaded by another
+	// client with the FlagMismatch action.
+	mismatchKey
+
+	// csrfKey holds the CSRF token of the session.
+	csrfKey
+
+	// expiringKey holds the expiries of the values set with SetExpiring.
+	expiringKey
+
+	// namespacesKey holds the namespaces of the session read so far.
+	namespacesKey
+
+	// badCookieKey flags a new session replacing a cookie that could not be
+	// decoded, with DecodeLenient.
+	badCookieKey
+
+	// degradedKey flags an empty session standing in for one that could
+	// not be read, with ReadFailOpen.
+	degradedKey
+
+	// versionKey holds the version of the session as loaded, with
+	// Options.ConflictResolver.
+	versionKey
+
+	// oidcSIDKey holds the session id of the OpenID Connect provider, see
+	// SetOIDCSession.
+	oidcSIDKey
+)
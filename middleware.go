@@ -0,0 +1,175 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// sessionContextKey is the context key of the session loaded by the
+// innermost Middleware.
+type sessionContextKey struct{}
+
+// namedContextKey is the context key of the session loaded by Middleware
+// for a name.
+type namedContextKey string
+
+// Middleware returns a middleware loading the session of the given name
+// into the request context before calling the next handler, and saving it
+// before the response is written if the handler modified it. Handlers get
+// the session with FromContext and never call Save.
+//
+// A cookie that can not be decoded is replaced with a new session, other
+// errors loading the session fail the request with a 500, or a 503 while
+// mongo is unavailable.
+func (s *Store) Middleware(name string) func(http.Handler) http.Handler {
+	return s.MiddlewareMany(name)
+}
+
+// MiddlewareMany is like Middleware for sessions of several names, the
+// modified sessions are saved together with SaveAll. FromContext returns the
+// session of the first name.
+func (s *Store) MiddlewareMany(names ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			sw := &sessionWriter{
+				ResponseWriter: w,
+				store:          s,
+			}
+
+			for _, name := range names {
+				session, err := s.Get(r, name)
+				if errors.Is(err, ErrCookieDecode) && s.route(name).MongoStore.DecodePolicy == DecodeStrict {
+					s.log(r.Context(), slog.LevelWarn, "rejecting session", slog.String("name", name), errorAttr(err))
+					http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+					return
+				}
+				if errors.Is(err, ErrCookieDecode) {
+					s.log(r.Context(), slog.LevelWarn, "replacing session", slog.String("name", name), errorAttr(err))
+					session, err = s.route(name).newSession(r, name), nil
+				}
+				if err != nil {
+					s.log(r.Context(), slog.LevelError, "loading session", slog.String("name", name), errorAttr(err))
+					status := http.StatusInternalServerError
+					if errors.Is(err, ErrStoreUnavailable) {
+						status = http.StatusServiceUnavailable
+					}
+					http.Error(w, http.StatusText(status), status)
+					return
+				}
+
+				loaded, _ := s.fingerprint(session)
+				sw.sessions = append(sw.sessions, session)
+				sw.loaded = append(sw.loaded, loaded)
+			}
+
+			// the first session is the innermost
+			ctx := r.Context()
+			for i := len(sw.sessions) - 1; i >= 0; i-- {
+				ctx = NewContext(ctx, sw.sessions[i])
+			}
+			r = r.WithContext(ctx)
+			sw.request = r
+
+			next.ServeHTTP(sw, r)
+			sw.save()
+		})
+	}
+}
+
+// NewContext returns a copy of ctx carrying session, as Middleware does, for
+// FromContext and NamedFromContext.
+func NewContext(ctx context.Context, session *sessions.Session) context.Context {
+	ctx = context.WithValue(ctx, sessionContextKey{}, session)
+	return context.WithValue(ctx, namedContextKey(session.Name()), session)
+}
+
+// FromContext returns the session loaded by the innermost Middleware, or nil
+// if the request did not go through one.
+func FromContext(ctx context.Context) *sessions.Session {
+	session, _ := ctx.Value(sessionContextKey{}).(*sessions.Session)
+	return session
+}
+
+// NamedFromContext returns the session of the given name loaded by
+// Middleware, or nil if the request did not go through one for the name.
+func NamedFromContext(ctx context.Context, name string) *sessions.Session {
+	session, _ := ctx.Value(namedContextKey(name)).(*sessions.Session)
+	return session
+}
+
+// sessionWriter saves the sessions before the response headers are written,
+// so the cookies are still sent.
+type sessionWriter struct {
+	http.ResponseWriter
+
+	store    *Store
+	request  *http.Request
+	sessions []*sessions.Session
+
+	// loaded are the fingerprints of the sessions when they were loaded
+	loaded []string
+	saved  bool
+}
+
+// save saves the modified sessions once.
+func (w *sessionWriter) save() {
+	if w.saved {
+		return
+	}
+	w.saved = true
+
+	var modified []*sessions.Session
+	for i, session := range w.sessions {
+		fp, err := w.store.fingerprint(session)
+		if err == nil && fp == w.loaded[i] && !mustReissue(session) {
+			w.store.route(session.Name()).expireBadCookie(w.ResponseWriter, session)
+			continue
+		}
+		modified = append(modified, session)
+	}
+
+	switch len(modified) {
+	case 0:
+		return
+	case 1:
+		err := w.store.Save(w.request, w.ResponseWriter, modified[0])
+		if err != nil {
+			w.store.log(w.request.Context(), slog.LevelError, "saving session", slog.String("name", modified[0].Name()), errorAttr(err))
+		}
+	default:
+		err := w.store.SaveAll(w.request, w.ResponseWriter, modified...)
+		if err != nil {
+			w.store.log(w.request.Context(), slog.LevelError, "saving sessions", slog.Int("count", len(modified)), errorAttr(err))
+		}
+	}
+}
+
+// WriteHeader saves the sessions and writes the header.
+func (w *sessionWriter) WriteHeader(code int) {
+	w.save()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write saves the sessions and writes the body.
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush saves the sessions and flushes the response, if the underlying
+// writer supports it.
+func (w *sessionWriter) Flush() {
+	w.save()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,57 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := newTestStore(t, "sessions_middleware_test")
+
+	handler := store.Middleware("test-session")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session := mongostore.FromContext(r.Context())
+		if session != mongostore.NamedFromContext(r.Context(), "test-session") {
+			t.Fatal("expected the same session by name")
+		}
+
+		if r.URL.Query().Get("set") != "" {
+			session.Values["foo"] = r.URL.Query().Get("set")
+		}
+		_, _ = w.Write([]byte(session.Values["foo"].(string) + "|"))
+	}))
+
+	serve := func(target string, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, req)
+		return res
+	}
+
+	// a modified session is saved before the body is written
+	res := serve("/?set=bar", "")
+	cookie := res.Header().Get("Set-Cookie")
+	if cookie == "" {
+		t.Fatal("expected the session to be saved")
+	}
+
+	// an unmodified session is not saved again
+	res = serve("/", cookie)
+	if res.Body.String() != "bar|" {
+		t.Fatalf("expected the saved value, got %q", res.Body.String())
+	}
+	if res.Header().Get("Set-Cookie") != "" {
+		t.Fatal("expected the unmodified session not to be saved")
+	}
+
+	// a cookie that can not be decoded is replaced
+	res = serve("/?set=baz", "test-session=garbage")
+	if res.Code != http.StatusOK || res.Header().Get("Set-Cookie") == "" {
+		t.Fatalf("expected a new session, got %d", res.Code)
+	}
+}
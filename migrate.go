@@ -0,0 +1,130 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MigrationResult counts the sessions read by a migration.
+type MigrationResult struct {
+	// Migrated sessions were written to the collection of the store.
+	Migrated int64
+
+	// Expired sessions were past their MaxAge and left out.
+	Expired int64
+
+	// Failed sessions could not be decoded, usually because a type stored
+	// in the session values is not registered with gob.Register.
+	Failed int64
+}
+
+// kidstuffSession is a session stored by kidstuff/mongostore.
+type kidstuffSession struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Data     string             `bson:"data"`
+	Modified time.Time          `bson:"modified"`
+}
+
+// MigrateKidstuff copies the sessions of a kidstuff/mongostore collection to
+// the collection of the store, so users stay logged in when switching stores.
+//
+// kidstuff/mongostore encodes session values with securecookie, so the store
+// must use the same keys, and name is the name of the session. The cookies
+// hold the same ObjectID, which needs the default ObjectIDGenerator and no
+// OpaqueTokens. Running the migration again overwrites the migrated sessions.
+func (s *Store) MigrateKidstuff(ctx context.Context, src *mongo.Collection, name string) (MigrationResult, error) {
+	var result MigrationResult
+	if s.MongoStore.OpaqueTokens {
+		return result, errors.New("mongostore: migrating sessions with opaque tokens")
+	}
+	if _, ok := s.idGenerator().(ObjectIDGenerator); !ok {
+		return result, errors.New("mongostore: migrating sessions needs the ObjectIDGenerator")
+	}
+
+	cursor, err := src.Find(ctx, bson.M{})
+	if err != nil {
+		return result, fmt.Errorf("mongostore: finding sessions to migrate: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	maxAge := time.Duration(s.ttlSeconds()) * time.Second
+	for cursor.Next(ctx) {
+		old := &kidstuffSession{}
+		err = cursor.Decode(old)
+		if err != nil {
+			s.log(ctx, slog.LevelWarn, "decoding session to migrate", errorAttr(err))
+			result.Failed++
+			continue
+		}
+
+		if maxAge > 0 && old.Modified.Add(maxAge).Before(s.now()) {
+			result.Expired++
+			continue
+		}
+
+		values := make(map[interface{}]interface{})
+		err = securecookie.DecodeMulti(name, old.Data, &values, s.codecs()...)
+		if err != nil {
+			s.log(ctx, slog.LevelWarn, "decoding values of session", slog.String("document_id", old.ID.Hex()), errorAttr(err))
+			result.Failed++
+			continue
+		}
+
+		data := make(primitive.M, len(values))
+		for k, v := range values {
+			key, ok := s.encodeKey(k)
+			if !ok {
+				s.log(ctx, slog.LevelWarn, "skipping session key, register its type to store it", slog.String("document_id", old.ID.Hex()), slog.Any("key", k), slog.String("type", fmt.Sprintf("%T", k)))
+				continue
+			}
+			data[key] = s.encodeValue(v)
+		}
+
+		// the session lives MaxAge seconds after it was last modified, like
+		// it did in kidstuff/mongostore
+		mongoSession := &MongoSession{
+			ID:       old.ID,
+			Data:     data,
+			Modified: primitive.NewDateTimeFromTime(old.Modified),
+			Expires:  primitive.NewDateTimeFromTime(old.Modified.Add(maxAge)),
+			TTL:      primitive.NewDateTimeFromTime(old.Modified),
+			Created:  primitive.NewDateTimeFromTime(old.Modified),
+		}
+
+		err = s.migrateSession(ctx, mongoSession)
+		if err != nil {
+			return result, err
+		}
+		result.Migrated++
+	}
+
+	return result, cursor.Err()
+}
+
+// migrateSession writes a migrated session, replacing the session with the
+// same _id.
+func (s *Store) migrateSession(ctx context.Context, mongoSession *MongoSession) error {
+	filter := bson.M{"_id": mongoSession.ID}
+
+	_, err := s.writeCollection().ReplaceOne(ctx, filter, mongoSession, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("mongostore: writing migrated session: %w", err)
+	}
+
+	s.replicate(func(col *mongo.Collection) error {
+		_, err := col.ReplaceOne(ctx, filter, mongoSession, options.Replace().SetUpsert(true))
+		return err
+	})
+
+	return nil
+}
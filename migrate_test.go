@@ -0,0 +1,131 @@
+package mongostore_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestMigrateKidstuff(t *testing.T) {
+	store := newTestStore(t, "sessions_migrate_test")
+
+	src := mongoclient.Database("test-database").Collection("sessions_kidstuff_test")
+	err := src.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop source collection: %v\n", err)
+	}
+
+	// a session as written by kidstuff/mongostore
+	id := primitive.NewObjectID()
+	values := map[interface{}]interface{}{"user": "gopher"}
+	data, err := securecookie.EncodeMulti("test-session", values, store.CookieStore.Codecs...)
+	if err != nil {
+		t.Fatalf("failed to encode values: %v\n", err)
+	}
+	_, err = src.InsertMany(context.TODO(), []interface{}{
+		bson.M{"_id": id, "data": data, "modified": time.Now()},
+		bson.M{"_id": primitive.NewObjectID(), "data": data, "modified": time.Now().Add(-time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("failed to insert old sessions: %v\n", err)
+	}
+
+	result, err := store.MigrateKidstuff(context.TODO(), src, "test-session")
+	if err != nil {
+		t.Fatalf("failed to migrate sessions: %v\n", err)
+	}
+	if result.Migrated != 1 || result.Expired != 1 {
+		t.Fatalf("expected 1 migrated and 1 expired session, got %+v", result)
+	}
+
+	// the old cookie finds the migrated session
+	cookie, err := securecookie.EncodeMulti("test-session", id.Hex(), store.CookieStore.Codecs...)
+	if err != nil {
+		t.Fatalf("failed to encode cookie: %v\n", err)
+	}
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: cookie})
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["user"] != "gopher" {
+		t.Fatalf("expected the migrated session, got %v", session.Values)
+	}
+}
+
+// memoryRedis is a RedisReader over a map.
+type memoryRedis map[string][]byte
+
+func (m memoryRedis) Keys(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	for k := range m {
+		if strings.HasPrefix(k, strings.TrimSuffix(pattern, "*")) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, nil
+}
+
+func (m memoryRedis) Get(ctx context.Context, key string) ([]byte, error) {
+	return m[key], nil
+}
+
+func (m memoryRedis) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return time.Minute, nil
+}
+
+func TestMigrateRedistore(t *testing.T) {
+	store := newTestStore(t, "sessions_migrate_test")
+	store.MongoStore.IDGenerator = mongostore.Base32IDGenerator{}
+
+	// a session as written by redistore with the gob serializer
+	id, err := mongostore.Base32IDGenerator{}.NewID()
+	if err != nil {
+		t.Fatalf("failed to generate id: %v\n", err)
+	}
+	var buf bytes.Buffer
+	err = gob.NewEncoder(&buf).Encode(map[interface{}]interface{}{"user": "gopher"})
+	if err != nil {
+		t.Fatalf("failed to encode values: %v\n", err)
+	}
+	client := memoryRedis{
+		"session_" + id:      buf.Bytes(),
+		"session_not-base32": buf.Bytes(),
+	}
+
+	result, err := store.MigrateRedistore(context.TODO(), client, "session_", mongostore.RedisGob)
+	if err != nil {
+		t.Fatalf("failed to migrate sessions: %v\n", err)
+	}
+	if result.Migrated != 1 || result.Failed != 1 {
+		t.Fatalf("expected 1 migrated and 1 failed session, got %+v", result)
+	}
+
+	// the redistore cookie finds the migrated session
+	cookie, err := securecookie.EncodeMulti("test-session", id, store.CookieStore.Codecs...)
+	if err != nil {
+		t.Fatalf("failed to encode cookie: %v\n", err)
+	}
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: cookie})
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["user"] != "gopher" {
+		t.Fatalf("expected the migrated session, got %v", session.Values)
+	}
+}
@@ -4,32 +4,474 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 // MongoSession is how sessions are stored in MongoDB.
 type MongoSession struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	ID       interface{}        `bson:"_id,omitempty"`
 	Data     primitive.M        `bson:"data,omitempty"`
 	Modified primitive.DateTime `bson:"modified_at,omitempty"`
 	Expires  primitive.DateTime `bson:"expires_at,omitempty"`
 	TTL      primitive.DateTime `bson:"ttl,omitemtpy"`
+	Created  primitive.DateTime `bson:"created_at,omitempty"`
+	UserID   string             `bson:"user_id,omitempty"`
+	TenantID string             `bson:"tenant_id,omitempty"`
+
+	// Owned is when the owner was set with Store.SetOwner, a revocation of
+	// the user applies to the sessions owned before it
+	Owned primitive.DateTime `bson:"owned_at,omitempty"`
+
+	// TokenHash is the hash of the cookie token when Options.OpaqueTokens is set
+	TokenHash string `bson:"token_hash,omitempty"`
+
+	// Persistent flags a "remember me" session
+	Persistent bool `bson:"persistent"`
+
+	// Cookie holds the cookie attributes of the session when they differ
+	// from the store defaults
+	Cookie *CookieAttributes `bson:"cookie,omitempty"`
+
+	// Overflow points to the chunks holding Data when it is larger than
+	// Options.OverflowThreshold
+	Overflow primitive.ObjectID `bson:"overflow,omitempty"`
+
+	// client metadata, only stored when Options.RecordClientInfo is set
+	IP        string `bson:"ip,omitempty"`
+	UserAgent string `bson:"user_agent,omitempty"`
+
+	// LastSeen is when the session was last read or saved, only stored when
+	// Options.LastSeenInterval is set
+	LastSeen primitive.DateTime `bson:"last_seen,omitempty"`
+
+	// Deleted is when the session was tombstoned with Options.SoftDelete
+	Deleted primitive.DateTime `bson:"deleted_at,omitempty"`
+
+	// CSRFToken is the token generated by Store.CSRFToken
+	CSRFToken string `bson:"csrf_token,omitempty"`
+
+	// OIDCSID is the session id of the OpenID Connect provider set
+	// with Store.SetOIDCSession
+	OIDCSID string `bson:"oidc_sid,omitempty"`
+
+	// Binding holds the hashes of the client that created the session,
+	// only stored when Options.ClientBinding is set
+	Binding *BindingHashes `bson:"binding,omitempty"`
+
+	// KeyExpires holds when the values set with Store.SetExpiring expire,
+	// by Data key
+	KeyExpires map[string]primitive.DateTime `bson:"key_expires,omitempty"`
+
+	// Namespaces holds the values of the namespaces of the session, by
+	// name, see Store.Namespace
+	Namespaces map[string]primitive.M `bson:"ns,omitempty"`
+
+	// Version is incremented by each update, and KeyModified holds when
+	// each Data key was last set or deleted, only stored when
+	// Options.ConflictResolver is set
+	Version     int64                         `bson:"version,omitempty"`
+	KeyModified map[string]primitive.DateTime `bson:"key_modified,omitempty"`
 }
 
 // Options required for storing data in MongoDB.
 type Options struct {
 	Context    context.Context
 	Collection *mongo.Collection
+
+	// RecordClientInfo stores the remote IP and User-Agent of the request
+	// that created the session.
+	RecordClientInfo bool
+
+	// ClientInfoFunc customizes how client info is extracted from the
+	// request, DefaultClientInfo is used when it is nil.
+	ClientInfoFunc func(r *http.Request) ClientInfo
+
+	// ClientBinding rejects or flags sessions loaded by another client than
+	// the one that created them. Bound sessions are always stored in mongo,
+	// HybridThreshold does not apply.
+	ClientBinding *ClientBinding
+
+	// MaxSessionsPerUser limits the number of sessions a user (see SetOwner)
+	// can have at the same time, zero means no limit.
+	MaxSessionsPerUser int
+
+	// SessionLimitPolicy decides what happens when a user goes over
+	// MaxSessionsPerUser, the default is EvictOldest.
+	SessionLimitPolicy SessionLimitPolicy
+
+	// PersistentMaxAge is the lifetime in seconds of "remember me" sessions
+	// (see SetPersistent), zero disables the longer tier.
+	PersistentMaxAge int
+
+	// IDGenerator creates session ids, the default is ObjectIDGenerator.
+	IDGenerator IDGenerator
+
+	// OpaqueTokens puts a random 256-bit token in the cookie instead of the
+	// _id, mongo only stores the hash of the token so the cookie can not be
+	// forged from the contents of the database. Set it before calling
+	// NewStoreWithOptions so the token_hash index is created.
+	OpaqueTokens bool
+
+	// Transports carry the session id between the client and the store, the
+	// first one that finds a session id in the request is used to answer.
+	// The default is a CookieTransport, add a BearerTransport or a
+	// HeaderTransport after it to serve API clients alongside browsers.
+	// Without a CookieTransport the store never sets cookies, sessions
+	// are kept server side and only the opaque token is exchanged.
+	Transports []Transport
+
+	// JWTKey switches the session id encoding from securecookie to a HS256
+	// signed JWT holding only the session id and expiry, so edge proxies
+	// sharing the key can verify sessions without calling mongo.
+	JWTKey []byte
+
+	// Encoder encodes the session id in the cookie instead of securecookie,
+	// see NewPASETOEncoder. The key pairs are then optional, they still
+	// encode the client side sessions of HybridThreshold when given.
+	Encoder Encoder
+
+	// HybridThreshold keeps sessions whose encoded cookie is at most this
+	// many bytes entirely in the cookie, without a round trip to mongo.
+	// Larger sessions, and sessions with an owner, are stored in mongo and
+	// move between the two on Save. Zero stores every session in mongo. The
+	// values of client side sessions are encoded with gob, so their types
+	// must be registered with gob.Register.
+	HybridThreshold int
+
+	// Fallback keeps sessions while mongo is unavailable, according to the
+	// FallbackPolicy.
+	Fallback FallbackBackend
+
+	// FallbackPolicy decides how the store behaves while mongo is
+	// unavailable, the default is FailClosed.
+	FallbackPolicy FallbackPolicy
+
+	// Secondary receives a copy of every write, and is read when a session
+	// is missing from Collection or Collection is unavailable. Use it to
+	// migrate sessions to another collection or cluster, or for disaster
+	// recovery.
+	Secondary *mongo.Collection
+
+	// ReadPreference and ReadConcern are used to load sessions, for example
+	// readpref.SecondaryPreferred() when sessions can be slightly stale. The
+	// collection defaults are used when they are nil.
+	ReadPreference *readpref.ReadPref
+	ReadConcern    *readconcern.ReadConcern
+
+	// WriteConcern is used to insert and update sessions, DeleteWriteConcern
+	// to delete them, for example writeconcern.Majority() so a logout is not
+	// lost in a failover. The collection default is used when they are nil.
+	WriteConcern       *writeconcern.WriteConcern
+	DeleteWriteConcern *writeconcern.WriteConcern
+
+	// MaxRetries is the number of times an operation is retried when mongo
+	// is unavailable, with a jittered exponential backoff starting at
+	// RetryBackoff (50ms by default) and capped at RetryMaxBackoff (2s by
+	// default). Operations that still fail return ErrStoreUnavailable.
+	MaxRetries      int
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+
+	// BreakerThreshold is the number of consecutive failed operations after
+	// which the store fails fast with ErrStoreUnavailable for
+	// BreakerCooldown, 30 seconds by default. Zero disables the circuit
+	// breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// SkipIndexCreation stops NewStoreWithOptions from creating indexes, for
+	// deployments where the application user lacks the createIndex
+	// privilege. Create them with EnsureIndexes from a privileged context.
+	SkipIndexCreation bool
+
+	// SchemaValidation makes NewStoreWithOptions create the session
+	// collection with a $jsonSchema validator, see EnsureSchema.
+	SchemaValidation bool
+
+	// CollectionOptions create the session collections when they do not
+	// exist, for example with a collation or the compression of the storage
+	// engine, instead of the defaults of the database. See
+	// EnsureCollections.
+	CollectionOptions *options.CreateCollectionOptions
+
+	// TTLIndexName names the time to live index, mongo picks the name when
+	// it is empty.
+	TTLIndexName string
+
+	// TTLIndex sets the field and the filter of the time to live index.
+	// They only apply when the index is created, drop the index created
+	// with the previous definition when changing them.
+	TTLIndex TTLIndex
+
+	// ExpireAt puts the time to live index on expires_at with an
+	// expireAfterSeconds of 0, so each session is removed at its own
+	// expiry instead of a fixed time after its ttl field, and changing the
+	// MaxAge does not rebuild the index. It takes precedence over
+	// TTLIndex.Field, drop the previous time to live index when turning it
+	// on.
+	ExpireAt bool
+
+	// Indexes are the secondary indexes created by EnsureIndexes, for example
+	// UserIDIndex, with IndexCollation if it is set and the backend
+	// supports it.
+	Indexes        []Index
+	IndexCollation *options.Collation
+
+	// Registry encodes and decodes sessions, for example with codecs for
+	// application types stored in session.Values. Values of types passed to
+	// Store.RegisterType keep their Go type across a save and load. When it
+	// is nil the collection registry writes sessions and the default
+	// registry decodes registered types. A Secondary collection must be
+	// created with a registry handling the same types.
+	Registry *bsoncodec.Registry
+
+	// SkipUnchanged makes Save skip the mongo write and the Set-Cookie
+	// header of sessions that did not change since they were loaded, so
+	// read-only requests stay cacheable. Sessions then expire MaxAge seconds
+	// after their last change instead of their last use.
+	SkipUnchanged bool
+
+	// TenantFunc returns the tenant of a request. The tenant is stored in the
+	// tenant_id field of new sessions, and every query for a session is
+	// restricted to the tenant of the request, so a session id of one
+	// tenant is never valid for another. Add TenantIndex to Indexes.
+	TenantFunc func(r *http.Request) string
+
+	// ShardKey declares the shard key of a sharded collection, so updates
+	// and deletes are sent to the shard holding the session. See
+	// Store.ShardCollection.
+	ShardKey ShardKey
+
+	// AuditCollection receives a record of every session created, updated,
+	// deleted, expired or evicted. Use a capped or time series collection to
+	// bound its size, and Store.AuditLog to query it.
+	AuditCollection *mongo.Collection
+
+	// AuditCollectionOptions create the audit collection when it does not
+	// exist, for example capped to bound its size.
+	AuditCollectionOptions *options.CreateCollectionOptions
+
+	// ErasurePolicy decides if Store.EraseUser deletes or anonymizes the
+	// sessions of a user, the default is EraseDelete.
+	ErasurePolicy ErasurePolicy
+
+	// OverflowThreshold moves the Data of sessions whose encoded size is
+	// over this many bytes to chunks in OverflowCollection, keeping the
+	// session documents small and far from the 16MB document limit. The
+	// chunks expire with the session, and are only read by Load or Values,
+	// like a session of LazyLoad, or before the session is saved. Zero
+	// keeps Data in the session document.
+	OverflowThreshold int
+
+	// OverflowCollection holds the overflow chunks, the default is the
+	// session collection name followed by ".chunks" in the same database.
+	OverflowCollection *mongo.Collection
+
+	// MaxDataSize makes Save return ErrSessionTooLarge instead of writing a
+	// session whose encoded Data is over this many bytes, zero means no
+	// limit.
+	MaxDataSize int
+
+	// LazyLoad makes New only decode the cookie, the session is read from
+	// mongo by Load or Values, or before it is saved. Handlers that never
+	// use the session data skip the read.
+	LazyLoad bool
+
+	// WriteBehind makes Save queue the updates of existing sessions and
+	// return, the queue is written with a bulk write every
+	// WriteBehindInterval (one second by default) and by Close. New
+	// sessions, deletes and logins are still written by Save. Other
+	// instances read the previous version of a session until its update is
+	// written.
+	WriteBehind         bool
+	WriteBehindInterval time.Duration
+
+	// Clock tells the store the current time, tests set it to simulate time
+	// passing without sleeping. The default is the system clock.
+	Clock Clock
+
+	// KeyProvider supplies the key pairs instead of the keyPairs of
+	// NewStore, they are fetched when the store is created and then every
+	// KeyRefreshInterval (five minutes by default) until Close.
+	KeyProvider        KeyProvider
+	KeyRefreshInterval time.Duration
+
+	// GenerateKeys generates a key pair on first use and stores it in
+	// KeysCollection, so every instance shares it without configuring keys.
+	// The keyPairs of NewStore are ignored, and the stored keys are
+	// refreshed like those of a KeyProvider so replacing the document rolls
+	// the keys over. KeysCollection defaults to the session collection name
+	// followed by ".keys" in the same database, restrict access to it.
+	GenerateKeys   bool
+	KeysCollection *mongo.Collection
+
+	// SoftDelete tombstones deleted sessions with a deleted_at field instead
+	// of removing them, the time to live index removes them once this grace
+	// window is over. Reads ignore tombstoned sessions, while support can
+	// still inspect the sessions that just logged out. Zero removes
+	// sessions right away.
+	SoftDelete time.Duration
+
+	// CheckRevocations makes New check the revocation epochs set by
+	// Store.RevokeUser and Store.RevokeTenant, one more read of the
+	// primary for each session of a user or tenant. The epochs are kept in
+	// RevocationCollection, the default is the session collection name
+	// followed by ".revocations" in the same database.
+	CheckRevocations     bool
+	RevocationCollection *mongo.Collection
+
+	// LastSeenInterval records when sessions are used in a last_seen field,
+	// for "active in the last 15 minutes" dashboards. Saves update it, and
+	// reads at most once per interval so reads stay cheap. Zero does not
+	// record it.
+	LastSeenInterval time.Duration
+
+	// CoalesceWindow skips the update of a session saved with the same
+	// values, owner, tier and cookie attributes by this instance less than
+	// this long ago, for handlers saving a session several times per
+	// request or per second. The expiry is not extended by skipped writes.
+	CoalesceWindow time.Duration
+
+	// ReadYourWrites reads a session from the primary for this long after
+	// this instance saved or deleted it, whatever the ReadPreference, so the
+	// next request of the user does not read it from a lagging secondary.
+	// Set it to the replication lag the application tolerates, such as a few
+	// seconds. Other instances do not know about the write, route the
+	// requests of a user to the same instance, or read from the primary,
+	// when they must read their writes across instances.
+	ReadYourWrites time.Duration
+
+	// ConflictResolver enables optimistic locking: the update of a session
+	// only succeeds if no other request saved it since it was loaded, and
+	// the resolver decides what to save when one did. LastWriteWins,
+	// FailOnConflict and MergeKeys are provided. Nil disables the check,
+	// the request saving last overwrites the others. Options.WriteBehind
+	// is not used with it, and SaveAll saves the sessions one by one.
+	ConflictResolver ConflictResolver
+
+	// ArchiveCollection receives a copy of each session before it is
+	// deleted, by Save, the admin methods, the session limit or the time to
+	// live index, for retention analytics: its owner, tenant and times,
+	// without its data. The janitor archives the sessions the index is about
+	// to remove every PurgeInterval, a minute by default. Archived sessions
+	// are removed ArchiveTTL after they were archived, a year by default.
+	// EraseUser deletes the archived sessions of the user.
+	ArchiveCollection *mongo.Collection
+	ArchiveTTL        time.Duration
+
+	// SensitiveKeys are the AES keys, of 16, 24 or 32 bytes, encrypting the
+	// values of the keys marked with MarkSensitive. The first encrypts, the
+	// others only decrypt values encrypted before a key rotation.
+	SensitiveKeys [][]byte
+
+	// Redact are globs of Data keys, such as "*_token", whose values are
+	// masked in the sessions shown to operators by AdminHandler, ExportUser
+	// and the cli, see Redact. The stored values are left intact.
+	Redact []string
+
+	// RawData decodes the stored Data straight into session.Values with
+	// Registry, element by element, instead of through a primitive.M.
+	// Nested documents keep their field order as primitive.D, so a loaded
+	// session saved again writes back the same BSON.
+	RawData bool
+
+	// Namespaces lists the namespaces read with the session, the other
+	// namespaces are read when Store.Namespace first returns them. All the
+	// namespaces are read with the session when it is nil.
+	Namespaces []string
+
+	// SessionCollections stores the sessions of the given names in their
+	// own collection, with their own lifetime, instead of Collection. The
+	// sessions share the keys and the other options of the store, but
+	// Secondary. The admin functions only see the sessions in Collection.
+	SessionCollections map[string]SessionCollection
+
+	// BrowserSessionRetention is how long sessions with a MaxAge of 0 are
+	// kept in mongo, 24 hours by default. Their cookie has no Max-Age and
+	// is dropped when the browser closes, the document outlives it.
+	BrowserSessionRetention time.Duration
+
+	// ServerTTL is how long every session lives in mongo after its last
+	// save, whatever the MaxAge of its cookie. Longer than the cookie, the
+	// documents stay for audits after the browser dropped the cookie;
+	// shorter, users log in again before their cookie expires. Zero derives
+	// the lifetime from the MaxAge of each session.
+	ServerTTL time.Duration
+
+	// Backend is the server behind the mongo protocol, detected when the
+	// store is created by default.
+	Backend Backend
+
+	// PurgeInterval runs PurgeExpired every interval until Close, for
+	// backends whose time to live index does not remove each session on
+	// time. Zero disables it, except on BackendCosmos and backends without
+	// time to live indexes where it defaults to a minute.
+	PurgeInterval time.Duration
+
+	// Capabilities are the features of the backend, probed when the store
+	// is created when nil.
+	Capabilities *Capabilities
+
+	// ServerAPI pins the Stable API version of the client connected by
+	// NewStoreFromURIWithOptions, such as
+	// options.ServerAPI(options.ServerAPIVersion1).SetStrict(true), so the
+	// commands of the store keep working across server upgrades. In strict
+	// mode the backend can not be detected, set Options.Backend on other
+	// backends than MongoDB.
+	ServerAPI *options.ServerAPIOptions
+
+	// DecodePolicy is what New does with a cookie that can not be decoded,
+	// after a key rotation or tampering. DecodeLenient by default.
+	DecodePolicy DecodePolicy
+
+	// ReadPolicy is what New does when reading a session fails, for example
+	// while mongo is unavailable without a Fallback. ReadFailClosed by
+	// default.
+	ReadPolicy ReadPolicy
+
+	// Logger receives the logs of the store, slog.Default() when nil. A
+	// logger set on the request context with WithLogger takes precedence.
+	Logger *slog.Logger
+
+	// LogLevel drops the records below it, whatever the level of the
+	// handler. All records are passed to the handler when nil.
+	LogLevel slog.Leveler
+
+	// LogSessionIDs logs session ids as they are. Session ids are bearer
+	// credentials, by default only a short hash of them is logged.
+	LogSessionIDs bool
+
+	// RequestIDFunc extracts the id of a request from its context, it is
+	// added to the logs, the audit records and the ClientInfo passed to
+	// ClientBinding.Decide. DefaultRequestID is used when it is nil.
+	RequestIDFunc func(ctx context.Context) string
+
+	// Publisher receives the events of the sessions created and destroyed
+	// with Save and SaveAll, once they are written. Sessions removed by the
+	// time to live index or the admin functions are seen with WatchDeletes.
+	Publisher Publisher
+
+	// OutboxCollection delivers the events of the Publisher at least once,
+	// they are inserted in the outbox in the same transaction as the
+	// session and published by RelayOutbox. Transactions need a replica set
+	// or a sharded cluster.
+	OutboxCollection *mongo.Collection
 }
 
 // MongoStore stores sessions in MongoDB
@@ -40,8 +482,46 @@ type MongoStore struct {
 // Store stores sessions in Secure Cookies and MongoDB.
 type Store struct {
 	defaultCookie http.Cookie // default cookie settings
+	maxLength     int         // maximum length of the cookie, see MaxLength
 	sessions.CookieStore
 	MongoStore
+
+	fallbackMu      sync.Mutex
+	fallbackPending map[string]bool // sessions queued while mongo was unavailable
+
+	breaker breaker // opens when mongo keeps failing
+
+	types     map[string]reflect.Type // types registered with RegisterType
+	typeNames map[reflect.Type]string
+
+	sensitive map[string]bool // keys marked with MarkSensitive
+
+	auditMu    sync.Mutex
+	auditChain primitive.ObjectID // the audit records written by this store
+	auditSeq   int64
+	auditHash  string
+
+	writeBehind writeBehind // updates queued with Options.WriteBehind
+
+	logoutMu      sync.Mutex
+	logoutIndexed map[string]bool // replay collections with their index, see BackchannelLogout
+
+	keysMu     sync.RWMutex // guards CookieStore.Codecs, see Rotate
+	keyRefresh keyRefresh   // keys fetched from Options.KeyProvider
+
+	coalescer coalescer // writes remembered with Options.CoalesceWindow
+
+	recentWrites recentWrites // writes remembered with Options.ReadYourWrites
+
+	routes map[string]*Store // stores of Options.SessionCollections, by name
+
+	counters DebugStats // updated atomically, see DebugStats
+
+	client *mongo.Client // connected by NewStoreFromURI, disconnected by Close
+
+	backend      Backend      // Options.Backend, or the detected one
+	capabilities Capabilities // Options.Capabilities, or the probed ones
+	janitor      janitor      // purges expired sessions, see Options.PurgeInterval
 }
 
 // NewStore uses cookies and mongo to store sessions.
@@ -53,14 +533,146 @@ type Store struct {
 // encryption. The encryption key can be set to nil or omitted in the last
 // pair, but the authentication key is required in all pairs.
 //
-// It is recommended to use an authentication key with 32 or 64 bytes.
+// The authentication key must have at least 32 bytes, 64 are recommended.
 // The encryption key, if set, must be either 16, 24, or 32 bytes to select
-// AES-128, AES-192, or AES-256 modes.
+// AES-128, AES-192, or AES-256 modes. NewStore returns ErrWeakKey for other
+// keys.
 func NewStore(col *mongo.Collection, cookie http.Cookie, keyPairs ...[]byte) (*Store, error) {
+	return NewStoreWithOptions(
+		&Options{
+			Context:    context.Background(),
+			Collection: col,
+		},
+		cookie,
+		keyPairs...,
+	)
+}
+
+// NewStoreFromClient is like NewStore, with the session collection given by
+// the names of the database and the collection. The Stable API version of
+// the client is set on its options, see Options.ServerAPI.
+func NewStoreFromClient(client *mongo.Client, database, collection string, cookie http.Cookie, keyPairs ...[]byte) (*Store, error) {
+	return NewStore(client.Database(database).Collection(collection), cookie, keyPairs...)
+}
+
+// NewStoreFromURI connects to mongo with the connection string and is like
+// NewStoreFromClient. The store owns the client, it is pinged before the
+// store is created and disconnected by Close.
+func NewStoreFromURI(ctx context.Context, uri, database, collection string, cookie http.Cookie, keyPairs ...[]byte) (*Store, error) {
+	return NewStoreFromURIWithOptions(ctx, uri, database, collection, &Options{Context: context.Background()}, cookie, keyPairs...)
+}
+
+// NewStoreFromURIWithOptions is like NewStoreFromURI, but takes the options
+// of the store. opts.Collection is set to the session collection, and the
+// client is connected with opts.ServerAPI.
+func NewStoreFromURIWithOptions(ctx context.Context, uri, database, collection string, opts *Options, cookie http.Cookie, keyPairs ...[]byte) (*Store, error) {
+	clientOpts := options.Client().ApplyURI(uri)
+	if opts.ServerAPI != nil {
+		clientOpts.SetServerAPIOptions(opts.ServerAPI)
+	}
+
+	client, err := mongo.Connect(ctx, clientOpts)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: connecting to mongo: %w", err)
+	}
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, wrapError(ErrStoreUnavailable, err)
+	}
+
+	opts.Collection = client.Database(database).Collection(collection)
+	s, err := NewStoreWithOptions(opts, cookie, keyPairs...)
+	if err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, err
+	}
+	s.client = client
+
+	return s, nil
+}
+
+// NewStoreWithOptions is like NewStore, but takes the options for storing
+// data in MongoDB, opts.Collection is required.
+func NewStoreWithOptions(opts *Options, cookie http.Cookie, keyPairs ...[]byte) (*Store, error) {
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+
+	// the keys of a provider are fetched once the store is created
+	var codecs []securecookie.Codec
+	if opts.KeyProvider == nil && !opts.GenerateKeys && (opts.Encoder == nil || len(keyPairs) > 0) {
+		var err error
+		codecs, err = codecsFromPairs(keyPairs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := checkSensitiveKeys(opts.SensitiveKeys)
+	if err != nil {
+		return nil, err
+	}
+	err = checkRedact(opts.Redact)
+	if err != nil {
+		return nil, err
+	}
+
+	s := newStore(opts, cookie, codecs)
+
+	// the backend decides how the indexes are created
+	s.backend = opts.Backend
+	if s.backend == BackendAuto {
+		s.backend = detectBackend(opts.Context, opts.Collection.Database())
+	}
+	if opts.Capabilities != nil {
+		s.capabilities = *opts.Capabilities
+	} else {
+		s.capabilities = s.probeCapabilities(opts.Context)
+	}
+
+	// the stores of Options.SessionCollections share the keys of the store
+	err = s.addRoutes(keyPairs)
+	if err != nil {
+		return nil, err
+	}
+
+	// fetch the keys before the first cookie is decoded
+	if s.keyProvider() != nil {
+		err := s.RefreshKeys(opts.Context)
+		if err != nil {
+			return nil, err
+		}
+		s.startKeyRefresh()
+	}
+
+	// create the collections before the indexes create them with the
+	// defaults
+	err = s.EnsureCollections(opts.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	// add TTL index if it does not exist, and the other indexes
+	if !opts.SkipIndexCreation {
+		err := s.EnsureIndexes(opts.Context)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.startJanitor()
+
+	return s, nil
+}
+
+// newStore returns a store with the given codecs, without keys to fetch or
+// indexes to create.
+func newStore(opts *Options, cookie http.Cookie, codecs []securecookie.Codec) *Store {
 	s := &Store{
 		defaultCookie: cookie,
 		CookieStore: sessions.CookieStore{
-			Codecs: securecookie.CodecsFromPairs(keyPairs...),
+			Codecs: codecs,
 			Options: &sessions.Options{
 				Path:     cookie.Path,
 				Domain:   cookie.Domain,
@@ -71,20 +683,24 @@ func NewStore(col *mongo.Collection, cookie http.Cookie, keyPairs ...[]byte) (*S
 			},
 		},
 		MongoStore: MongoStore{
-			Options: &Options{
-				Context:    context.Background(),
-				Collection: col,
-			},
+			Options: opts,
 		},
 	}
+	s.MaxLength(defaultMaxLength)
 
-	// add TTL index if it does not exist
-	err := s.insertTTL()
-	if err != nil {
-		return nil, fmt.Errorf("[ERROR] adding time to live index: %v", err)
-	}
+	// until probed
+	s.capabilities = Capabilities{TTLIndexes: true, Transactions: true, ChangeStreams: true}
 
-	return s, nil
+	return s
+}
+
+// sessionOptions returns a copy of the default cookie options, each session
+// gets its own copy so a handler changing the options of its session does not
+// change them for other requests.
+func (s *Store) sessionOptions() *sessions.Options {
+	opts := *s.CookieStore.Options
+	opts.MaxAge = s.defaultCookie.MaxAge
+	return &opts
 }
 
 // Get returns a session for the given name after adding it to the registry.
@@ -92,8 +708,8 @@ func NewStore(col *mongo.Collection, cookie http.Cookie, keyPairs ...[]byte) (*S
 // It returns a new session if the sessions doesn't exist. Access IsNew on
 // the session to check if it is an existing session or a new one.
 //
-// It returns a new session and an error if the session exists but could
-// not be decoded.
+// A cookie that can not be decoded gives a new session, with an error only
+// under DecodeReport or DecodeStrict, see Options.DecodePolicy.
 func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
 	return sessions.GetRegistry(r).Get(s, name)
 }
@@ -103,117 +719,343 @@ func (s *Store) Get(r *http.Request, name string) (*sessions.Session, error) {
 // The difference between New() and Get() is that calling New() twice will
 // decode the session data twice, while Get() registers and reuses the same
 // decoded session after the first call.
+//
+// A cookie that can not be decoded gives a new session, see
+// Options.DecodePolicy.
 func (s *Store) New(r *http.Request, name string) (*sessions.Session, error) {
-	session := sessions.NewSession(s, name)
-	session.Options = s.CookieStore.Options
-	session.Options.MaxAge = s.defaultCookie.MaxAge
-	session.IsNew = true
+	if route := s.route(name); route != s {
+		return route.New(r, name)
+	}
+
+	session := s.newSession(r, name)
 
-	// get session cookie
-	c, err := r.Cookie(name)
+	// get session cookie, or header
+	value, ok := s.readTransport(r, session)
 
 	// no cookie
-	if errors.Is(err, http.ErrNoCookie) {
-		log.Printf("[INFO] no cookie: %s", err.Error())
+	if !ok {
+		s.log(r.Context(), slog.LevelDebug, "no cookie", slog.String("name", name))
+		return session, nil
+	}
+
+	// the cookie could not have been sent by the store
+	if s.maxLength > 0 && len(value) > s.maxLength {
+		return s.decodeFailed(r, session, fmt.Errorf("%d bytes, the maximum is %d", len(value), s.maxLength))
+	}
+
+	// the whole session is in the cookie
+	if isClientSide(value) {
+		err := s.decodeClientSide(session, value)
+		if err != nil {
+			return s.decodeFailed(r, session, err)
+		}
+
+		session.IsNew = false
+		session.Options.MaxAge = s.tierMaxAge(session)
+		s.markUnchanged(session)
+
 		return session, nil
 	}
 
 	// decode the session.ID in the cookie and use it to find the existing session in mongo
-	err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.CookieStore.Codecs...)
+	id, stale, err := s.decodeID(name, value)
+	if err != nil {
+		return s.decodeFailed(r, session, err)
+	}
+	session.ID = id
+	if stale {
+		session.Values[reissueKey] = true
+	}
+
+	// read the session from mongo on first use
+	if s.MongoStore.LazyLoad {
+		session.Values[lazyKey] = true
+		session.IsNew = false
+		return session, nil
+	}
+
+	err = s.load(r, session)
 	if err != nil {
-		return nil, fmt.Errorf("[ERROR] decoding cookie: %w", err)
+		return s.readFailed(r, session, err)
 	}
 
+	return session, nil
+}
+
+// load reads the session with the decoded id from mongo, it stays new if it
+// does not exist. Other errors are returned, see Options.ReadPolicy.
+func (s *Store) load(r *http.Request, session *sessions.Session) error {
 	// if the session does not exist in mongo, expire the cookies and mark the session as new
-	err = s.findOne(session)
-	if errors.Is(err, mongo.ErrNoDocuments) {
-		log.Printf("[INFO] no session in mongo: %s", err.Error())
-		return session, nil
+	err := s.findOne(session)
+	if errors.Is(err, ErrSessionExpired) {
+		s.audit(r, session, AuditExpire)
+	}
+	if errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrSessionExpired) || errors.Is(err, ErrClientMismatch) {
+		s.log(s.logContext(r), slog.LevelInfo, "no session in mongo", slog.String("name", session.Name()), s.sessionIDAttr(session.ID), errorAttr(err))
+		s.count(&s.counters.LoadMisses)
+		return nil
+	}
+
+	// serve the session from the fallback while mongo is unavailable
+	if err != nil && s.MongoStore.Fallback != nil && s.MongoStore.FallbackPolicy != FailClosed && isUnavailable(err) {
+		s.log(s.logContext(r), slog.LevelWarn, "mongo unavailable, using fallback", slog.String("name", session.Name()), errorAttr(err))
+		s.loadFallback(session)
+		s.count(&s.counters.FallbackLoads)
+		return nil
+	}
+	if err == nil {
+		s.count(&s.counters.Loads)
 	}
 
 	// flag as an existing session
 	session.IsNew = false
+	session.Options.MaxAge = s.tierMaxAge(session)
+	s.markUnchanged(session)
 
-	return session, nil
+	return err
+}
+
+// newSession returns a new session with the options of the store.
+func (s *Store) newSession(r *http.Request, name string) *sessions.Session {
+	session := sessions.NewSession(s, name)
+	session.Options = s.sessionOptions()
+	applyCookiePrefix(name, session.Options)
+	session.IsNew = true
+	s.setTenant(r, session)
+	s.bindClient(r, session)
+
+	return session
 }
 
 // Save adds a single session to the response.
 func (s *Store) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
-	// expired session
-	if session.Options.MaxAge == -1 {
-		res, err := s.deleteOne(session)
+	_, err := s.SaveWithResult(r, w, session)
+	return err
+}
+
+// prepareSave handles the sessions Save does not write to mongo, it reports
+// if the session has to be persisted and records what it did in res.
+func (s *Store) prepareSave(r *http.Request, w http.ResponseWriter, session *sessions.Session, res *SaveResult) (bool, error) {
+	// browsers drop prefixed cookies without the required attributes
+	err := checkCookiePrefix(session.Name(), session.Options)
+	if err != nil {
+		return false, err
+	}
+
+	// sessions not created by New
+	if _, ok := session.Values[tenantKey]; !ok {
+		s.setTenant(r, session)
+	}
+
+	// the stored session could not be read, keep it untouched
+	if Degraded(session) && session.Options.MaxAge >= 0 {
+		res.Op = SaveSkipped
+		return false, nil
+	}
+
+	// an empty session replacing an undecodable cookie only expires it
+	if s.expireBadCookie(w, session) {
+		res.Op = SaveSkipped
+		return false, nil
+	}
+
+	// read a lazy session before writing it, unless it is deleted
+	if isLazy(session) && session.Options.MaxAge >= 0 {
+		err = s.Load(session)
 		if err != nil {
-			return fmt.Errorf("[ERROR] deleting mongo session: %v", err)
+			return false, err
 		}
-		log.Printf("[INFO] %d session(s) deleted", res.DeletedCount)
+	}
+
+	// expired values are not written
+	s.pruneExpired(session)
 
+	// nothing to write for a session that was only read
+	if s.unchanged(session) {
+		res.Op = SaveSkipped
+		return false, nil
 	}
 
-	// new session
-	if session.IsNew && session.Options.MaxAge != -1 {
-		res, err := s.insertOne(session)
-		if err != nil {
-			return fmt.Errorf("[ERROR] inserting mongo session: %v", err)
+	// small sessions are kept in the cookie, removing them from mongo if they
+	// were stored there
+	if encoded, ok := s.encodeClientSide(session); ok {
+		if session.ID != "" {
+			_, err := s.deleteOne(s.MongoStore.Context, session)
+			if err != nil {
+				return false, fmt.Errorf("mongostore: deleting session: %w", err)
+			}
+			session.ID = ""
 		}
-		log.Printf("[INFO] session id: %s, inserted", res.InsertedID.(primitive.ObjectID).Hex())
-		session.ID = res.InsertedID.(primitive.ObjectID).Hex()
+
+		delete(session.Values, reissueKey)
+		s.writeTransport(w, session, encoded, session.Options)
+		s.markUnchanged(session)
+
+		res.Op = SaveClientSide
+		res.Cookie = encoded
+
+		return false, nil
 	}
 
-	// existing session
-	if !session.IsNew && session.Options.MaxAge != -1 {
-		res, err := s.updateOne(session)
-		if err != nil {
-			return fmt.Errorf("[ERROR] updating mongo session: %v", err)
+	return true, nil
+}
+
+// finishSave writes the cookie of a session once it was persisted, err is
+// the error persisting it. The cookie is recorded in res.
+func (s *Store) finishSave(w http.ResponseWriter, session *sessions.Session, err error, res *SaveResult) error {
+	// keep the site going while mongo is unavailable
+	if err != nil && s.MongoStore.Fallback != nil && isUnavailable(err) {
+		switch s.MongoStore.FallbackPolicy {
+		case FailOpenReadOnly:
+			// leave the cookie untouched, the session is lost
+			s.log(s.MongoStore.Context, slog.LevelWarn, "mongo unavailable, session not saved", slog.String("name", session.Name()), errorAttr(err))
+			return nil
+		case QueueWrites:
+			s.log(s.MongoStore.Context, slog.LevelWarn, "mongo unavailable, session queued", slog.String("name", session.Name()), errorAttr(err))
+			err = s.saveFallback(session)
 		}
-		log.Printf("[INFO] %d session(s) updated", res.ModifiedCount)
 	}
+	if err != nil {
+		return err
+	}
+
+	// mongo is back, replay the sessions saved while it was unavailable
+	s.flushFallback()
+
+	delete(session.Values, ownerChangedKey)
+	delete(session.Values, reissueKey)
+	delete(session.Values, badCookieKey)
 
 	// encode the cookie with only the session.ID, session.Values are never encoded with
 	// to the cookie (client side) they are only stored in mongo (server side)
-	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.CookieStore.Codecs...)
+	encoded, err := s.encodeID(session.Name(), session.ID, session.Options.MaxAge)
 	if err != nil {
-		return fmt.Errorf("[ERROR] saving cookie: %v", err)
+		return fmt.Errorf("mongostore: encoding cookie: %w", err)
 	}
 
-	// update the cookie
-	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, s.CookieStore.Options))
+	// browsers silently drop cookies that are too long
+	err = s.checkLength(session.Name(), encoded)
+	if err != nil {
+		return err
+	}
+
+	// update the cookie, using the options of the session
+	s.writeTransport(w, session, encoded, session.Options)
+	s.markUnchanged(session)
+
+	res.SessionID = session.ID
+	res.Cookie = encoded
 
 	return nil
 }
 
-func (s *Store) insertTTL() error {
-	var foundTTLIndex bool
+// persist writes the session to mongo, recording the write in result.
+func (s *Store) persist(r *http.Request, session *sessions.Session, result *SaveResult) error {
+	// a client side session has no id, it is inserted when it outgrows the
+	// cookie
+	isNew := session.IsNew || session.ID == ""
+	start := time.Now()
 
-	// get indexes from mongo into the cursor
-	cursor, err := s.MongoStore.Collection.Indexes().List(s.MongoStore.Context)
-	if err != nil {
-		return err
-	}
+	// expired session
+	if session.Options.MaxAge == -1 && session.ID != "" {
+		s.dropQueued(session.ID)
+		s.forgetWrite(session.ID)
+		var res *mongo.DeleteResult
+		err := s.writeEvents(r, func(ctx context.Context) ([]SessionEvent, error) {
+			var err error
+			res, err = s.deleteOne(ctx, session)
+			if err != nil || !s.publishes() {
+				return nil, err
+			}
+			return []SessionEvent{s.sessionEvent(r, session, SessionDestroyed)}, nil
+		})
+		if err != nil {
+			return fmt.Errorf("mongostore: deleting session: %w", err)
+		}
+		s.log(r.Context(), slog.LevelInfo, "session deleted", slog.String("op", "delete"), slog.String("name", session.Name()), s.sessionIDAttr(session.ID), slog.Int64("count", res.DeletedCount), slog.Duration("duration", time.Since(start)))
+		s.audit(r, session, AuditDelete)
+		result.Op = SaveDelete
+		result.DeletedCount = res.DeletedCount
 
-	// use the cursor to iterate each index
-	for cursor.Next(s.MongoStore.Context) {
+	}
 
-		// decode the current index
-		var index bson.D
-		err := cursor.Decode(&index)
+	// new session
+	if isNew && session.Options.MaxAge != -1 {
+		err := s.writeEvents(r, func(ctx context.Context) ([]SessionEvent, error) {
+			_, err := s.insertOne(ctx, r, session)
+			if err != nil || !s.publishes() {
+				return nil, err
+			}
+			return []SessionEvent{s.sessionEvent(r, session, SessionCreated)}, nil
+		})
 		if err != nil {
-			return err
+			return fmt.Errorf("mongostore: inserting session: %w", err)
 		}
+		s.log(r.Context(), slog.LevelInfo, "session inserted", slog.String("op", "insert"), slog.String("name", session.Name()), s.sessionIDAttr(session.ID), slog.Duration("duration", time.Since(start)))
+		s.audit(r, session, AuditCreate)
+		s.recordWrite(session)
+		result.Op = SaveInsert
 
-		// is the index empty
-		if len(index) > 0 {
+		// a new session of a user can push the user over the session limit
+		if s.Owner(session) != "" {
+			err = s.enforceSessionLimit(session)
+			if err != nil {
+				return fmt.Errorf("mongostore: enforcing session limit: %w", err)
+			}
+		}
+	}
 
-			// does index contain a key
-			key := index.Map()["key"]
+	// existing session
+	if !isNew && session.Options.MaxAge != -1 {
+		// the same session was written moments ago
+		if s.coalesced(session) {
+			s.log(r.Context(), slog.LevelDebug, "session write coalesced", slog.String("op", "update"), slog.String("name", session.Name()), s.sessionIDAttr(session.ID))
+			result.Op = SaveSkipped
+			return nil
+		}
 
-			if key != nil {
-				// does the key contain ttl
-				if key.(bson.D).Map()["ttl"] != nil {
-					foundTTLIndex = true
-				}
+		queued, err := s.queueUpdate(session)
+		if err != nil {
+			return fmt.Errorf("mongostore: queueing session: %w", err)
+		}
+		if queued {
+			s.audit(r, session, AuditUpdate)
+			result.Op = SaveQueued
+			return nil
+		}
+
+		res, err := s.updateOne(session)
+		if err != nil {
+			return fmt.Errorf("mongostore: updating session: %w", err)
+		}
+		s.log(r.Context(), slog.LevelInfo, "session updated", slog.String("op", "update"), slog.String("name", session.Name()), s.sessionIDAttr(session.ID), slog.Int64("count", res.ModifiedCount), slog.Duration("duration", time.Since(start)))
+		s.audit(r, session, AuditUpdate)
+		s.recordWrite(session)
+		result.Op = SaveUpdate
+		result.MatchedCount = res.MatchedCount
+		result.ModifiedCount = res.ModifiedCount
+
+		// an existing session that was just given an owner (a login) can push
+		// the user over the session limit
+		if ownerChanged(session) {
+			err = s.enforceSessionLimit(session)
+			if err != nil {
+				return fmt.Errorf("mongostore: enforcing session limit: %w", err)
 			}
 		}
 	}
+	return nil
+}
+
+func (s *Store) insertTTL(ctx context.Context, col *mongo.Collection) error {
+	if !s.capabilities.TTLIndexes {
+		return nil
+	}
+
+	foundTTLIndex, expireAfterSeconds, err := findTTLIndex(ctx, col, s.ttlField())
+	if err != nil {
+		return err
+	}
 
 	//https://docs.mongodb.com/manual/core/index-ttl/
 	//
@@ -233,141 +1075,646 @@ func (s *Store) insertTTL() error {
 	//
 	// The _id field does not support TTL indexes.
 	if !foundTTLIndex {
-		_, err = s.MongoStore.Collection.Indexes().CreateOne(
-			s.MongoStore.Context,
+		indexOptions := options.Index().
+			SetExpireAfterSeconds(int32(s.indexExpireAfterSeconds()))
+		if s.MongoStore.TTLIndex.PartialFilter != nil {
+			// a partial index can not be sparse
+			indexOptions.SetPartialFilterExpression(s.MongoStore.TTLIndex.PartialFilter)
+		} else if s.backend != BackendCosmos {
+			// every Cosmos DB document has a _ts
+			indexOptions.SetSparse(true)
+		}
+		if s.MongoStore.TTLIndexName != "" {
+			indexOptions.SetName(s.MongoStore.TTLIndexName)
+		}
+
+		_, err = col.Indexes().CreateOne(
+			ctx,
 			mongo.IndexModel{
 				Keys: bson.D{
-					{Key: "ttl", Value: 1}, // Use bson.D instead of bsonx.Doc
+					{Key: s.ttlField(), Value: 1}, // Use bson.D instead of bsonx.Doc
 				},
-				Options: options.Index().
-					SetSparse(true).
-					SetExpireAfterSeconds(int32(s.defaultCookie.MaxAge)),
+				Options: indexOptions,
 			},
 		)
+		if isUnsupported(err) {
+			// the janitor purges the expired sessions instead
+			s.log(ctx, slog.LevelWarn, "time to live indexes not supported", slog.String("backend", s.backend.String()), errorAttr(err))
+			s.capabilities.TTLIndexes = false
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 	}
 
+	// the MaxAge changed since the index was created, without this the
+	// server side expiry would not follow the cookie
+	if foundTTLIndex && expireAfterSeconds != int64(s.indexExpireAfterSeconds()) {
+		s.log(ctx, slog.LevelInfo, "updating time to live index", slog.String("collection", col.Name()), slog.Int64("from", expireAfterSeconds), slog.Int("to", s.indexExpireAfterSeconds()))
+		return s.modifyTTL(ctx, col)
+	}
+
 	return nil
 }
 
+// modifyTTL sets the expireAfterSeconds of the existing time to live index to
+// the server side lifetime of the default MaxAge, or 0 with
+// Options.ExpireAt.
+func (s *Store) modifyTTL(ctx context.Context, col *mongo.Collection) error {
+	return col.Database().RunCommand(
+		ctx,
+		bson.D{
+			{Key: "collMod", Value: col.Name()},
+			{Key: "index", Value: bson.D{
+				{Key: "keyPattern", Value: bson.D{{Key: s.ttlField(), Value: 1}}},
+				{Key: "expireAfterSeconds", Value: int32(s.indexExpireAfterSeconds())},
+			}},
+		},
+	).Err()
+}
+
+// findTTLIndex reports if the collection has an index on the field, and its
+// expireAfterSeconds.
+func findTTLIndex(ctx context.Context, col *mongo.Collection, field string) (bool, int64, error) {
+	var foundTTLIndex bool
+	var expireAfterSeconds int64
+
+	// get indexes from mongo into the cursor
+	cursor, err := col.Indexes().List(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+
+	// use the cursor to iterate each index
+	for cursor.Next(ctx) {
+
+		// decode the current index
+		var index bson.D
+		err := cursor.Decode(&index)
+		if err != nil {
+			return false, 0, err
+		}
+
+		// is the index empty
+		if len(index) > 0 {
+
+			// does index contain a key
+			key := index.Map()["key"]
+
+			if key != nil {
+				// does the key contain the field
+				if key.(bson.D).Map()[field] != nil {
+					// an index without expireAfterSeconds, such as
+					// ExpiresAtIndex, does not expire documents
+					seconds := int64(-1)
+
+					// the server returns int32, int64 or double
+					switch v := index.Map()["expireAfterSeconds"].(type) {
+					case int32:
+						seconds = int64(v)
+					case int64:
+						seconds = v
+					case float64:
+						seconds = int64(v)
+					}
+
+					if !foundTTLIndex || seconds >= 0 {
+						expireAfterSeconds = seconds
+					}
+					foundTTLIndex = true
+				}
+			}
+		}
+	}
+
+	return foundTTLIndex, expireAfterSeconds, nil
+}
+
+// findOneProjection only reads the fields of the session document findOne
+// uses, but the namespaces.
+var findOneProjection = bson.D{
+	{Key: "data", Value: 1},
+	{Key: "expires_at", Value: 1},
+	{Key: "overflow", Value: 1},
+	{Key: "user_id", Value: 1},
+	{Key: "owned_at", Value: 1},
+	{Key: "persistent", Value: 1},
+	{Key: "cookie", Value: 1},
+	{Key: "binding", Value: 1},
+	{Key: "csrf_token", Value: 1},
+	{Key: "oidc_sid", Value: 1},
+	{Key: "deleted_at", Value: 1},
+	{Key: "created_at", Value: 1},
+	{Key: "tenant_id", Value: 1},
+	{Key: "last_seen", Value: 1},
+	{Key: "key_expires", Value: 1},
+	{Key: "version", Value: 1},
+	{Key: "key_modified", Value: 1},
+}
+
+// findOneOptions returns the options of the find reading a session, with
+// the namespaces in Options.Namespaces.
+func (s *Store) findOneOptions() *options.FindOneOptions {
+	if s.MongoStore.Namespaces == nil {
+		return findAllOptions
+	}
+
+	projection := append(bson.D{}, findOneProjection...)
+	for _, name := range s.MongoStore.Namespaces {
+		projection = append(projection, bson.E{Key: "ns." + name, Value: 1})
+	}
+
+	return options.FindOne().SetProjection(projection)
+}
+
+// findAllOptions reads a session with all its namespaces.
+var findAllOptions = options.FindOne().SetProjection(
+	append(append(bson.D{}, findOneProjection...), bson.E{Key: "ns", Value: 1}),
+)
+
+// mongoSessionPool reuses the documents findOne decodes, the values are
+// copied to the session so the struct can be reused once it returns.
+var mongoSessionPool = sync.Pool{
+	New: func() interface{} {
+		return &sessionDocument{}
+	},
+}
+
+// findOne loads the session from mongo into session.Values.
 func (s *Store) findOne(session *sessions.Session) error {
-	// get the mongo _id from the cookie
-	oid, err := primitive.ObjectIDFromHex(session.ID)
+	return s.findOneIn(session, s.sessionReadCollection(session.ID))
+}
+
+// findOneIn is findOne reading from the given collection.
+func (s *Store) findOneIn(session *sessions.Session, col *mongo.Collection) error {
+	// get the mongo filter from the cookie
+	filter, err := s.sessionFilter(session)
 	if err != nil {
 		return err
 	}
 
-	// initialize an empty struct for FindOne to fill
-	mongoSession := &MongoSession{}
+	// get an empty struct for FindOne to fill, Data is kept raw with
+	// Options.RawData
+	doc := mongoSessionPool.Get().(*sessionDocument)
+	defer func() {
+		*doc = sessionDocument{}
+		mongoSessionPool.Put(doc)
+	}()
+	mongoSession := &doc.MongoSession
+	var target interface{} = mongoSession
+	if s.MongoStore.RawData {
+		target = doc
+	}
 
-	// find the session in mongo using the _id and put the result in the empty struct
-	err = s.MongoStore.Collection.FindOne(
-		s.MongoStore.Context,
-		bson.M{
-			"_id": oid,
-		},
-	).Decode(mongoSession)
+	// find the session in mongo using the filter and put the result in the empty struct
+	err = s.retry(func() error {
+		return col.FindOne(
+			s.MongoStore.Context,
+			filter,
+			s.findOneOptions(),
+		).Decode(target)
+	})
+
+	// fall back to the secondary collection
+	if s.MongoStore.Secondary != nil && (errors.Is(err, mongo.ErrNoDocuments) || (err != nil && isUnavailable(err))) {
+		err = s.MongoStore.Secondary.FindOne(
+			s.MongoStore.Context,
+			filter,
+			s.findOneOptions(),
+		).Decode(target)
+	}
 
 	// no session found
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return fmt.Errorf("[INFO] no session found: %w", err)
+		return wrapError(ErrSessionNotFound, err)
 	}
 
 	// something went wrong with the mongo search
 	if err != nil {
-		return fmt.Errorf("[ERROR] finding session: %w", err)
+		return fmt.Errorf("mongostore: finding session: %w", err)
+	}
+
+	// the session was tombstoned with Options.SoftDelete
+	if mongoSession.Deleted != 0 {
+		return wrapError(ErrSessionNotFound, errors.New("session deleted"))
+	}
+
+	// an update queued with Options.WriteBehind is newer than mongo
+	queued := s.queuedSession(session.ID)
+	if queued != nil {
+		stored := *mongoSession
+		*mongoSession = *queued
+
+		// the fields an update does not set
+		mongoSession.ID = stored.ID
+		mongoSession.Binding = stored.Binding
+		mongoSession.Created = stored.Created
+		mongoSession.TenantID = stored.TenantID
+		mongoSession.Namespaces = stored.Namespaces
+	}
+
+	// the session expired but the TTL monitor did not remove it yet
+	if mongoSession.Expires != 0 && mongoSession.Expires.Time().Before(s.now()) {
+		return ErrSessionExpired
+	}
+
+	// the session was created by another client
+	if !s.checkBinding(session, mongoSession.Binding) {
+		return ErrClientMismatch
+	}
+
+	// the user or tenant revoked their sessions
+	revoked, err := s.revoked(mongoSession)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return wrapError(ErrSessionNotFound, errors.New("session revoked"))
+	}
+
+	// the data that did not fit in the session document is read on first
+	// access, or now to compare it with Options.ConflictResolver
+	if !mongoSession.Overflow.IsZero() {
+		session.Values[overflowKey] = mongoSession.Overflow
+		session.Values[overflowPendingKey] = true
+		if s.MongoStore.ConflictResolver != nil {
+			err = s.fillOverflow(session)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// decode the stored data straight into session.Values
+	if s.MongoStore.RawData && queued == nil {
+		err = s.decodeRawData(session, doc.Data)
+		if err != nil {
+			return fmt.Errorf("mongostore: decoding session data: %w", err)
+		}
 	}
 
 	// fill session.Values from mongo
-	for k, v := range mongoSession.Data {
-		session.Values[k] = v
+	s.fillData(session, mongoSession.Data)
+
+	// restore the owner of the session
+	if mongoSession.UserID != "" {
+		session.Values[ownerKey] = mongoSession.UserID
 	}
 
+	// the shard key of the stored session
+	s.recordShardKey(session)
+
+	// restore the session tier
+	if mongoSession.Persistent {
+		session.Values[persistentKey] = true
+	}
+
+	if mongoSession.CSRFToken != "" {
+		session.Values[csrfKey] = mongoSession.CSRFToken
+	}
+	if mongoSession.OIDCSID != "" {
+		session.Values[oidcSIDKey] = mongoSession.OIDCSID
+	}
+	s.restoreKeyExpiries(session, mongoSession.KeyExpires)
+	s.restoreNamespaces(session, mongoSession.Namespaces)
+
+	// restore the cookie attributes set for this session
+	applyCookieAttributes(session, mongoSession.Cookie)
+
+	s.recordVersion(session, mongoSession)
+	s.touch(filter, mongoSession.LastSeen)
+
 	return nil
 }
 
-func (s *Store) insertOne(session *sessions.Session) (*mongo.InsertOneResult, error) {
-	// initialize a mongo session to insert
-	mongoSession := &MongoSession{
-		Data:     make(map[string]interface{}, len(session.Values)),
-		Modified: primitive.NewDateTimeFromTime(time.Now()),
-		Expires:  primitive.NewDateTimeFromTime(time.Now().Add(time.Duration(s.defaultCookie.MaxAge) * time.Second)),
-		TTL:      primitive.NewDateTimeFromTime(time.Now()),
+// fillData sets the stored data on session.Values.
+func (s *Store) fillData(session *sessions.Session, data primitive.M) {
+	for k, v := range data {
+		v, ok := s.openSensitive(k, v)
+		if !ok {
+			continue
+		}
+		session.Values[s.decodeKey(k)] = s.decodeValue(v)
 	}
+}
 
-	// get current session.Values
+// sessionData returns the session.Values to store in mongo, leaving out the
+// metadata the store keeps in session.Values.
+func (s *Store) sessionData(session *sessions.Session) primitive.M {
+	data := make(primitive.M, len(session.Values))
 	for k, v := range session.Values {
-		mongoSession.Data[k.(string)] = v
+		if _, ok := k.(metaKey); ok {
+			continue
+		}
+		key, ok := s.encodeKey(k)
+		if !ok {
+			s.log(s.MongoStore.Context, slog.LevelWarn, "skipping session key, register its type to store it", slog.Any("key", k), slog.String("type", fmt.Sprintf("%T", k)))
+			continue
+		}
+		data[key] = s.encodeValue(v)
 	}
 
-	// insert the mongo session
-	res, err := s.MongoStore.Collection.InsertOne(
-		s.MongoStore.Context,
-		mongoSession,
-	)
+	return data
+}
+
+func (s *Store) insertOne(ctx context.Context, r *http.Request, session *sessions.Session) (*mongo.InsertOneResult, error) {
+	mongoSession, sessionID, err := s.insertDocument(r, session)
+	if err != nil {
+		return nil, err
+	}
+
+	// insert the mongo session, a retry finding the id taken means an
+	// attempt that failed on the network was applied
+	var res *mongo.InsertOneResult
+	attempts := 0
+	err = s.retry(func() error {
+		attempts++
+		res, err = s.writeCollection().InsertOne(
+			ctx,
+			mongoSession,
+		)
+		if attempts > 1 && mongo.IsDuplicateKeyError(err) {
+			res, err = &mongo.InsertOneResult{InsertedID: mongoSession.ID}, nil
+		}
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	session.ID = sessionID
+	s.deleteOverflow(session, mongoSession.Overflow)
+	s.recordShardKey(session)
+
+	s.replicate(func(col *mongo.Collection) error {
+		_, err := col.InsertOne(s.MongoStore.Context, mongoSession)
+		return err
+	})
 
 	return res, nil
 }
 
-func (s *Store) updateOne(session *sessions.Session) (*mongo.UpdateResult, error) {
-	// get the mongo _id from the cookie
-	oid, err := primitive.ObjectIDFromHex(session.ID)
+// insertDocument returns the document inserting a new session, and the id
+// of the session to encode in the cookie.
+func (s *Store) insertDocument(r *http.Request, session *sessions.Session) (*MongoSession, string, error) {
+	// generate the session id
+	sessionID, err := s.idGenerator().NewID()
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	id, err := s.documentID(sessionID)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// initialize a mongo session to insert
+	expires, ttl := s.expiry(session)
+	data, overflow, err := s.documentData(session, expires)
+	if err != nil {
+		return nil, "", err
+	}
 	mongoSession := &MongoSession{
-		Data:     make(map[string]interface{}, len(session.Values)),
-		Modified: primitive.NewDateTimeFromTime(time.Now()),
-		Expires:  primitive.NewDateTimeFromTime(time.Now().Add(time.Duration(s.defaultCookie.MaxAge) * time.Second)),
-		TTL:      primitive.NewDateTimeFromTime(time.Now()),
+		ID:         id,
+		Data:       data,
+		Modified:   primitive.NewDateTimeFromTime(s.now()),
+		Expires:    expires,
+		TTL:        ttl,
+		Persistent: s.IsPersistent(session),
+		Cookie:     s.cookieAttributes(session),
+		Overflow:   overflow,
+		Created:    primitive.NewDateTimeFromTime(s.now()),
+		UserID:     s.Owner(session),
+		Owned:      s.ownedAt(session),
+		TenantID:   tenant(session),
+		CSRFToken:  csrfToken(session),
+		OIDCSID:    s.OIDCSession(session),
+		LastSeen:   s.lastSeen(),
+		KeyExpires: s.keyExpiries(session),
+		Namespaces: s.namespaceData(session),
 	}
 
-	// get current session.Values
-	for k, v := range session.Values {
-		mongoSession.Data[k.(string)] = v
+	// the cookie holds a random token instead of the session id
+	if s.MongoStore.OpaqueTokens {
+		sessionID, err = newToken()
+		if err != nil {
+			return nil, "", err
+		}
+		mongoSession.TokenHash = hashToken(sessionID)
 	}
 
-	// update session.Values in mongo usig the object id
-	res, err := s.MongoStore.Collection.UpdateOne(
-		s.MongoStore.Context,
-		bson.M{
-			"_id": oid,
-		},
-		bson.M{
-			"$set": mongoSession,
-		},
-	)
+	// bind the session to the client that created it
+	mongoSession.Binding = s.bindingHashes(r)
+
+	// record who created the session
+	if s.MongoStore.RecordClientInfo {
+		info := s.clientInfo(r)
+		mongoSession.IP = info.IP
+		mongoSession.UserAgent = info.UserAgent
+	}
+
+	return mongoSession, sessionID, nil
+}
+
+func (s *Store) updateOne(session *sessions.Session, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	// a session read from the secondary collection is copied back to the primary
+	if s.MongoStore.Secondary != nil {
+		opts = append(opts, options.Update().SetUpsert(true))
+	}
+
+	for attempt := 0; ; attempt++ {
+		filter, update, overflow, err := s.sessionUpdate(session)
+		if err != nil {
+			return nil, err
+		}
+
+		// the update only matches the version the session was loaded at
+		primaryFilter := filter
+		if s.MongoStore.ConflictResolver != nil {
+			primaryFilter = versionFilter(session, filter)
+		}
+
+		// update session.Values in mongo usig the filter
+		var res *mongo.UpdateResult
+		err = s.retry(func() error {
+			res, err = s.writeCollection().UpdateOne(
+				s.MongoStore.Context,
+				primaryFilter,
+				update,
+				opts...,
+			)
+			return err
+		})
+
+		// another request saved the session since it was loaded
+		if s.MongoStore.ConflictResolver != nil && isConflict(res, err) {
+			s.deleteOverflow(session, overflow)
+			if attempt == maxConflictRetries {
+				return nil, ErrConflict
+			}
+
+			err = s.resolveConflict(session)
+			if errors.Is(err, ErrSessionNotFound) {
+				// deleted meanwhile, the update is dropped as without
+				// Options.ConflictResolver
+				return &mongo.UpdateResult{}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			s.log(s.MongoStore.Context, slog.LevelDebug, "session conflict resolved", slog.String("op", "update"), slog.String("name", session.Name()), s.sessionIDAttr(session.ID), slog.Int("attempt", attempt+1))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.deleteOverflow(session, overflow)
+		s.recordShardKey(session)
+		if s.MongoStore.ConflictResolver != nil {
+			savedVersion(session)
+		}
+
+		// upsert so the secondary catches up on sessions created before it was added
+		s.replicate(func(col *mongo.Collection) error {
+			_, err := col.UpdateOne(
+				s.MongoStore.Context,
+				filter,
+				update,
+				options.Update().SetUpsert(true),
+			)
+			return err
+		})
+
+		return res, nil
+	}
+}
+
+func (s *Store) sessionUpdate(session *sessions.Session) (bson.M, bson.M, primitive.ObjectID, error) {
+	// get the mongo filter from the cookie
+	filter, err := s.sessionFilter(session)
 	if err != nil {
-		return nil, err
+		return nil, nil, primitive.NilObjectID, err
 	}
 
-	return res, nil
+	// initialize a mongo session to insert
+	expires, ttl := s.expiry(session)
+	data, overflow, err := s.documentData(session, expires)
+	if err != nil {
+		return nil, nil, primitive.NilObjectID, err
+	}
+	mongoSession := &MongoSession{
+		Data:       data,
+		Modified:   primitive.NewDateTimeFromTime(s.now()),
+		Expires:    expires,
+		TTL:        ttl,
+		Persistent: s.IsPersistent(session),
+		Cookie:     s.cookieAttributes(session),
+		Overflow:   overflow,
+		UserID:     s.Owner(session),
+		Owned:      s.ownedAt(session),
+		CSRFToken:  csrfToken(session),
+		OIDCSID:    s.OIDCSession(session),
+		LastSeen:   s.lastSeen(),
+		KeyExpires: s.keyExpiries(session),
+	}
+	if s.MongoStore.ConflictResolver != nil {
+		s.nextVersion(session, mongoSession)
+	}
+
+	// empty fields are omitted from $set, remove them from mongo
+	update := bson.M{
+		"$set": mongoSession,
+	}
+	unset := bson.M{}
+	if len(mongoSession.Data) == 0 {
+		unset["data"] = ""
+	}
+	if mongoSession.Cookie == nil {
+		unset["cookie"] = ""
+	}
+	if mongoSession.Overflow.IsZero() {
+		unset["overflow"] = ""
+	}
+	if mongoSession.UserID == "" {
+		unset["user_id"] = ""
+		unset["owned_at"] = ""
+	}
+	if mongoSession.CSRFToken == "" {
+		unset["csrf_token"] = ""
+	}
+	if mongoSession.OIDCSID == "" {
+		unset["oidc_sid"] = ""
+	}
+	if len(mongoSession.KeyExpires) == 0 {
+		unset["key_expires"] = ""
+	}
+	if s.MongoStore.ConflictResolver != nil && len(mongoSession.KeyModified) == 0 {
+		unset["key_modified"] = ""
+	}
+
+	// namespaces are written one by one, the ones not read are kept
+	if namespaces(session) != nil {
+		set, err := s.namespaceUpdate(session, mongoSession, unset)
+		if err != nil {
+			return nil, nil, primitive.NilObjectID, err
+		}
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	return filter, update, overflow, nil
 }
 
-func (s *Store) deleteOne(session *sessions.Session) (*mongo.DeleteResult, error) {
-	// convert session id to a mongo object id
-	oid, err := primitive.ObjectIDFromHex(session.ID)
+func (s *Store) deleteOne(ctx context.Context, session *sessions.Session) (*mongo.DeleteResult, error) {
+	// convert session id to a mongo filter
+	filter, err := s.sessionFilter(session)
 	if err != nil {
 		return nil, err
 	}
+	s.archive(ctx, filter)
 
-	// delete session using the object id
-	res, err := s.MongoStore.Collection.DeleteOne(
-		s.MongoStore.Context,
-		bson.M{
-			"_id": oid,
-		},
-	)
+	// tombstone the session for the grace window
+	if s.MongoStore.SoftDelete > 0 {
+		update := s.softDeleteUpdate()
+
+		var res *mongo.UpdateResult
+		err = s.retry(func() error {
+			res, err = s.deleteCollection().UpdateOne(ctx, filter, update)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.releaseOverflow(session)
+
+		s.replicate(func(col *mongo.Collection) error {
+			_, err := col.UpdateOne(s.MongoStore.Context, filter, update)
+			return err
+		})
+
+		return &mongo.DeleteResult{DeletedCount: res.MatchedCount}, nil
+	}
+
+	// delete session using the filter
+	var res *mongo.DeleteResult
+	err = s.retry(func() error {
+		res, err = s.deleteCollection().DeleteOne(
+			ctx,
+			filter,
+		)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	s.releaseOverflow(session)
+
+	s.replicate(func(col *mongo.Collection) error {
+		_, err := col.DeleteOne(s.MongoStore.Context, filter)
+		return err
+	})
 
 	return res, nil
 }
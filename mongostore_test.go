@@ -2,6 +2,7 @@ package mongostore_test
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -60,6 +61,27 @@ func testsSetup() {
 	}
 }
 
+// testBackend returns the backend the tests run against, set with the
+// MONGODB_BACKEND environment variable to "documentdb" or "cosmos" when
+// MONGODB_URI points to one of them. It is "mongodb" by default.
+func testBackend() string {
+	if backend := os.Getenv("MONGODB_BACKEND"); backend != "" {
+		return backend
+	}
+	return "mongodb"
+}
+
+// skipOnBackends skips tests of features the backends do not support.
+func skipOnBackends(t testing.TB, backends ...string) {
+	t.Helper()
+
+	for _, backend := range backends {
+		if testBackend() == backend {
+			t.Skipf("not supported by %s", backend)
+		}
+	}
+}
+
 func testsTeardown() {
 	err = mongoclient.Disconnect(context.Background())
 	if err != nil {
@@ -67,6 +89,38 @@ func testsTeardown() {
 	}
 }
 
+// newTestStore returns a store using random keys and its own collection, so
+// tests can change store options without affecting each other.
+func newTestStore(t testing.TB, collection string) *mongostore.Store {
+	t.Helper()
+
+	// start from an empty collection
+	col := mongoclient.Database("test-database").Collection(collection)
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	s, err := mongostore.NewStore(
+		col,
+		http.Cookie{
+			Path:     "/",
+			Domain:   "",
+			MaxAge:   240,
+			Secure:   false,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		},
+		securecookie.GenerateRandomKey(32),
+		securecookie.GenerateRandomKey(16),
+	)
+	if err != nil {
+		t.Fatalf("failed to create test store: %v\n", err)
+	}
+
+	return s
+}
+
 func TestNewStore(t *testing.T) {
 	// without environment variables
 	// os.Clearenv()
@@ -88,12 +142,8 @@ func TestNewStore(t *testing.T) {
 		[]byte(os.Getenv("GORILLA_SESSION_ENC_KEY")),
 	)
 
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	if store == nil {
-		t.Fatal("expected to fail with no environment variables")
+	if !errors.Is(err, mongostore.ErrWeakKey) {
+		t.Fatalf("expected ErrWeakKey with no environment variables, got %v", err)
 	}
 
 	// without TTL index
@@ -226,9 +276,94 @@ func TestSave(t *testing.T) {
 
 }
 
-// func TestMaxAge(t *testing.T) {
-// 	store.MaxAge(7357)
-// 	if store.Options.MaxAge != 7357 {
-// 		t.Fatalf("failed to set MaxAge: %v\n", err)
-// 	}
-// }
+func TestMaxAge(t *testing.T) {
+	store := newTestStore(t, "sessions_maxage_test")
+
+	err := store.MaxAge(7357)
+	if err != nil {
+		t.Fatalf("failed to set MaxAge: %v\n", err)
+	}
+	if store.CookieStore.Options.MaxAge != 7357 {
+		t.Fatalf("failed to set MaxAge: %v\n", store.CookieStore.Options.MaxAge)
+	}
+
+	// the TTL index follows the new MaxAge
+	if got := ttlExpireAfterSeconds(t, store.MongoStore.Collection); got != 7357 {
+		t.Fatalf("expected expireAfterSeconds 7357, got %v", got)
+	}
+}
+
+func TestTTLIndexDrift(t *testing.T) {
+	store := newTestStore(t, "sessions_drift_test")
+
+	// a new deploy with a different MaxAge
+	_, err := mongostore.NewStore(
+		store.MongoStore.Collection,
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 600,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	if got := ttlExpireAfterSeconds(t, store.MongoStore.Collection); got != 600 {
+		t.Fatalf("expected expireAfterSeconds 600, got %v", got)
+	}
+}
+
+// ttlExpireAfterSeconds returns the expireAfterSeconds of the TTL index.
+func ttlExpireAfterSeconds(t *testing.T, col *mongo.Collection) int64 {
+	t.Helper()
+
+	cursor, err := col.Indexes().List(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to list indexes: %v\n", err)
+	}
+
+	var indexes []struct {
+		ExpireAfterSeconds *int64 `bson:"expireAfterSeconds"`
+	}
+	err = cursor.All(context.TODO(), &indexes)
+	if err != nil {
+		t.Fatalf("failed to decode indexes: %v\n", err)
+	}
+
+	for _, index := range indexes {
+		if index.ExpireAfterSeconds != nil {
+			return *index.ExpireAfterSeconds
+		}
+	}
+
+	t.Fatal("no TTL index")
+	return 0
+}
+
+func BenchmarkLoadSession(b *testing.B) {
+	store := newTestStore(b, "sessions_bench_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		b.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["foo"] = "bar"
+
+	res := httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		b.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := store.New(req, "test-session")
+		if err != nil {
+			b.Fatalf("failed to load session: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package mongov2 creates a mongostore.Store from the client options of
+// mongo-driver v2, for applications that moved to the v2 driver.
+//
+//	opts := options.Client().ApplyURI("mongodb://localhost:27017")
+//	store, err := mongov2.NewStore(ctx, opts, "app", "sessions", cookie, keyPairs...)
+//
+// The store is built on the v1 driver, the two drivers have different
+// module paths and live side by side in one binary. The store keeps its own
+// connection pool, configured from the v2 options, so the application
+// never touches the v1 API.
+//
+// This is a limitation, not a port to v2: go.mongodb.org/mongo-driver v1
+// stays in the build as a dependency of this module, it is not required by
+// the go.mod of the application. The store can not share a v2 *mongo.Client,
+// the v2 bson types are not accepted by its options or filters, and
+// mongostore.MongoStore.Collection is a v1 collection.
+//
+// It is a separate module so the store does not depend on the v2 driver.
+package mongov2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	v1 "go.mongodb.org/mongo-driver/mongo"
+	v1options "go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/glezjose/mongostore"
+)
+
+// ClientOptions converts the client options of the v2 driver to the v1
+// driver. The connection string is applied first, then the options set
+// after it. Monitors, loggers, registries and auto encryption have
+// different types in the two drivers and are not converted.
+func ClientOptions(opts *options.ClientOptions) *v1options.ClientOptions {
+	converted := v1options.Client()
+	if uri := opts.GetURI(); uri != "" {
+		converted.ApplyURI(uri)
+	}
+
+	if len(opts.Hosts) > 0 {
+		converted.SetHosts(opts.Hosts)
+	}
+	if opts.AppName != nil {
+		converted.SetAppName(*opts.AppName)
+	}
+	if opts.Auth != nil {
+		converted.SetAuth(v1options.Credential{
+			AuthMechanism:           opts.Auth.AuthMechanism,
+			AuthMechanismProperties: opts.Auth.AuthMechanismProperties,
+			AuthSource:              opts.Auth.AuthSource,
+			Username:                opts.Auth.Username,
+			Password:                opts.Auth.Password,
+			PasswordSet:             opts.Auth.PasswordSet,
+		})
+	}
+	if opts.TLSConfig != nil {
+		converted.SetTLSConfig(opts.TLSConfig)
+	}
+	if opts.ReplicaSet != nil {
+		converted.SetReplicaSet(*opts.ReplicaSet)
+	}
+	if opts.Direct != nil {
+		converted.SetDirect(*opts.Direct)
+	}
+	if opts.LoadBalanced != nil {
+		converted.SetLoadBalanced(*opts.LoadBalanced)
+	}
+	if opts.Timeout != nil {
+		converted.SetTimeout(*opts.Timeout)
+	}
+	if opts.ConnectTimeout != nil {
+		converted.SetConnectTimeout(*opts.ConnectTimeout)
+	}
+	if opts.ServerSelectionTimeout != nil {
+		converted.SetServerSelectionTimeout(*opts.ServerSelectionTimeout)
+	}
+	if opts.MaxPoolSize != nil {
+		converted.SetMaxPoolSize(*opts.MaxPoolSize)
+	}
+	if opts.MinPoolSize != nil {
+		converted.SetMinPoolSize(*opts.MinPoolSize)
+	}
+	if opts.RetryWrites != nil {
+		converted.SetRetryWrites(*opts.RetryWrites)
+	}
+	if opts.RetryReads != nil {
+		converted.SetRetryReads(*opts.RetryReads)
+	}
+	if len(opts.Compressors) > 0 {
+		converted.SetCompressors(opts.Compressors)
+	}
+	if opts.ServerAPIOptions != nil {
+		api := v1options.ServerAPI(v1options.ServerAPIVersion(opts.ServerAPIOptions.ServerAPIVersion))
+		if opts.ServerAPIOptions.Strict != nil {
+			api.SetStrict(*opts.ServerAPIOptions.Strict)
+		}
+		if opts.ServerAPIOptions.DeprecationErrors != nil {
+			api.SetDeprecationErrors(*opts.ServerAPIOptions.DeprecationErrors)
+		}
+		converted.SetServerAPIOptions(api)
+	}
+
+	return converted
+}
+
+// NewStore connects to mongo with the v2 client options and returns a store
+// of the sessions in the given database and collection, see
+// mongostore.NewStore. The store owns the connection, call Disconnect after
+// closing the store.
+func NewStore(ctx context.Context, opts *options.ClientOptions, database, collection string, cookie http.Cookie, keyPairs ...[]byte) (*mongostore.Store, error) {
+	client, err := v1.Connect(ctx, ClientOptions(opts))
+	if err != nil {
+		return nil, fmt.Errorf("mongov2: connecting: %w", err)
+	}
+
+	store, err := mongostore.NewStore(client.Database(database).Collection(collection), cookie, keyPairs...)
+	if err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Disconnect closes the connection of a store created with NewStore.
+func Disconnect(ctx context.Context, store *mongostore.Store) error {
+	return store.MongoStore.Collection.Database().Client().Disconnect(ctx)
+}
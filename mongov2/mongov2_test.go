@@ -0,0 +1,31 @@
+package mongov2_test
+
+import (
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/glezjose/mongostore/mongov2"
+)
+
+func TestClientOptions(t *testing.T) {
+	opts := options.Client().
+		ApplyURI("mongodb://localhost:27017/?replicaSet=rs0").
+		SetAppName("sessions").
+		SetTimeout(5 * time.Second)
+
+	converted := mongov2.ClientOptions(opts)
+	if converted.GetURI() != opts.GetURI() {
+		t.Fatalf("expected uri %s, got %s", opts.GetURI(), converted.GetURI())
+	}
+	if converted.ReplicaSet == nil || *converted.ReplicaSet != "rs0" {
+		t.Fatalf("expected replica set rs0, got %v", converted.ReplicaSet)
+	}
+	if converted.AppName == nil || *converted.AppName != "sessions" {
+		t.Fatalf("expected app name sessions, got %v", converted.AppName)
+	}
+	if converted.Timeout == nil || *converted.Timeout != 5*time.Second {
+		t.Fatalf("expected a 5s timeout, got %v", converted.Timeout)
+	}
+}
@@ -0,0 +1,240 @@
+package mongostore
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Namespace is a named view of the values of a session, such as "auth" or
+// "cart", stored in its own subdocument of the session document. Each
+// namespace is written, read and cleared on its own.
+type Namespace struct {
+	name   string
+	values map[string]interface{}
+}
+
+// namespaceSet holds the namespaces of a session read so far.
+type namespaceSet struct {
+	values map[string]map[string]interface{}
+
+	// all is set when every namespace stored in mongo was read
+	all bool
+}
+
+// Namespace returns the namespace of the session with the given name. With
+// Options.Namespaces only the listed namespaces are read with the session,
+// the others are read from mongo the first time they are used.
+func (s *Store) Namespace(session *sessions.Session, name string) (*Namespace, error) {
+	if name == "" || strings.ContainsAny(name, ".$\x00") {
+		return nil, fmt.Errorf("mongostore: invalid namespace name %q", name)
+	}
+	s = s.route(session.Name())
+
+	err := s.Load(session)
+	if err != nil {
+		return nil, err
+	}
+
+	set := s.namespaceSet(session)
+	values, ok := set.values[name]
+	if !ok {
+		values, err = s.readNamespace(session, set, name)
+		if err != nil {
+			return nil, err
+		}
+		set.values[name] = values
+	}
+
+	return &Namespace{name: name, values: values}, nil
+}
+
+// Name returns the name of the namespace.
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// Get returns the value stored under key, or nil.
+func (n *Namespace) Get(key string) interface{} {
+	return n.values[key]
+}
+
+// Set stores value under key.
+func (n *Namespace) Set(key string, value interface{}) {
+	n.values[key] = value
+}
+
+// Delete removes the value stored under key.
+func (n *Namespace) Delete(key string) {
+	delete(n.values, key)
+}
+
+// Clear removes all the values of the namespace, the other namespaces and
+// session.Values are kept.
+func (n *Namespace) Clear() {
+	for key := range n.values {
+		delete(n.values, key)
+	}
+}
+
+// Values returns the values of the namespace, changes to the map are saved.
+func (n *Namespace) Values() map[string]interface{} {
+	return n.values
+}
+
+// namespaceSet returns the namespaces of the session, adding them to the
+// session.
+func (s *Store) namespaceSet(session *sessions.Session) *namespaceSet {
+	set, ok := session.Values[namespacesKey].(*namespaceSet)
+	if !ok {
+		set = &namespaceSet{
+			values: make(map[string]map[string]interface{}),
+			all:    session.IsNew || session.ID == "" || s.MongoStore.Namespaces == nil,
+		}
+		session.Values[namespacesKey] = set
+	}
+
+	return set
+}
+
+// namespaces returns the namespaces of the session, or nil if it has none.
+func namespaces(session *sessions.Session) *namespaceSet {
+	set, _ := session.Values[namespacesKey].(*namespaceSet)
+	return set
+}
+
+// readNamespace reads a namespace that was not read with the session.
+func (s *Store) readNamespace(session *sessions.Session, set *namespaceSet, name string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if set.all {
+		return values, nil
+	}
+
+	filter, err := s.sessionFilter(session)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Namespaces map[string]primitive.M `bson:"ns"`
+	}
+	err = s.retry(func() error {
+		return s.sessionReadCollection(session.ID).FindOne(
+			s.MongoStore.Context,
+			filter,
+			options.FindOne().SetProjection(bson.D{{Key: "ns." + name, Value: 1}}),
+		).Decode(&doc)
+	})
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("mongostore: reading namespace %s: %w", name, err)
+	}
+
+	s.decodeNamespace(values, doc.Namespaces[name])
+	return values, nil
+}
+
+// restoreNamespaces sets the namespaces read by findOne on the session.
+func (s *Store) restoreNamespaces(session *sessions.Session, stored map[string]primitive.M) {
+	if len(stored) == 0 && s.MongoStore.Namespaces == nil {
+		return
+	}
+
+	set := &namespaceSet{
+		values: make(map[string]map[string]interface{}),
+		all:    s.MongoStore.Namespaces == nil,
+	}
+	for _, name := range s.MongoStore.Namespaces {
+		set.values[name] = make(map[string]interface{})
+	}
+	for name, data := range stored {
+		values := make(map[string]interface{}, len(data))
+		s.decodeNamespace(values, data)
+		set.values[name] = values
+	}
+	session.Values[namespacesKey] = set
+}
+
+// decodeNamespace fills values from the stored data of a namespace.
+func (s *Store) decodeNamespace(values map[string]interface{}, data primitive.M) {
+	for k, v := range data {
+		key, ok := s.decodeKey(k).(string)
+		if !ok {
+			continue
+		}
+		values[key] = s.decodeValue(v)
+	}
+}
+
+// namespaceData returns the namespaces to store in mongo, by name, leaving
+// out the empty ones.
+func (s *Store) namespaceData(session *sessions.Session) map[string]primitive.M {
+	set := namespaces(session)
+	if set == nil {
+		return nil
+	}
+
+	var stored map[string]primitive.M
+	for name, values := range set.values {
+		if len(values) == 0 {
+			continue
+		}
+		data := make(primitive.M, len(values))
+		for k, v := range values {
+			key, _ := s.encodeKey(k)
+			data[key] = s.encodeValue(v)
+		}
+		if stored == nil {
+			stored = make(map[string]primitive.M)
+		}
+		stored[name] = data
+	}
+
+	return stored
+}
+
+// namespaceUpdate returns the $set of an update writing the namespaces of
+// the session one by one, so the namespaces that were not read are left
+// untouched. Empty namespaces are added to unset.
+func (s *Store) namespaceUpdate(session *sessions.Session, mongoSession *MongoSession, unset bson.M) (bson.D, error) {
+	raw, err := s.marshalData(mongoSession)
+	if err != nil {
+		return nil, err
+	}
+	elements, err := bson.Raw(raw).Elements()
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(bson.D, 0, len(elements))
+	for _, e := range elements {
+		set = append(set, bson.E{Key: e.Key(), Value: e.Value()})
+	}
+
+	data := s.namespaceData(session)
+	for name := range namespaces(session).values {
+		if stored, ok := data[name]; ok {
+			set = append(set, bson.E{Key: "ns." + name, Value: stored})
+		} else {
+			unset["ns."+name] = ""
+		}
+	}
+
+	return set, nil
+}
+
+// namespaceDocs returns the namespaces as a document, for the fingerprint.
+func namespaceDocs(stored map[string]primitive.M) primitive.M {
+	doc := make(primitive.M, len(stored))
+	for name, data := range stored {
+		doc[name] = data
+	}
+
+	return doc
+}
@@ -0,0 +1,77 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNamespace(t *testing.T) {
+	store := newTestStore(t, "sessions_namespace_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	auth, err := store.Namespace(session, "auth")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v\n", err)
+	}
+	auth.Set("user", "gopher")
+	cart, err := store.Namespace(session, "cart")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v\n", err)
+	}
+	cart.Set("items", int32(3))
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	// only auth is read with the session, clearing it keeps the cart
+	store.MongoStore.Namespaces = []string{"auth"}
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	auth, err = store.Namespace(session, "auth")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v\n", err)
+	}
+	if auth.Get("user") != "gopher" {
+		t.Fatalf("expected the auth namespace, got %v", auth.Values())
+	}
+	auth.Clear()
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	auth, err = store.Namespace(session, "auth")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v\n", err)
+	}
+	if len(auth.Values()) != 0 {
+		t.Fatalf("expected the auth namespace to be cleared, got %v", auth.Values())
+	}
+	cart, err = store.Namespace(session, "cart")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v\n", err)
+	}
+	if cart.Get("items") != int32(3) {
+		t.Fatalf("expected the cart namespace to be kept, got %v", cart.Values())
+	}
+
+	_, err = store.Namespace(session, "a.b")
+	if err == nil {
+		t.Fatal("expected an invalid namespace name to fail")
+	}
+}
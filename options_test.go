@@ -0,0 +1,90 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSessionOptionsCopy(t *testing.T) {
+	store := newTestStore(t, "sessions_options_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	first, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	second, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	// changing the options of one session leaves the others alone
+	first.Options.MaxAge = 7357
+	first.Options.Path = "/admin"
+
+	if second.Options.MaxAge != 240 || second.Options.Path != "/" {
+		t.Fatalf("expected default options, got %+v", second.Options)
+	}
+	if store.CookieStore.Options.MaxAge != 240 || store.CookieStore.Options.Path != "/" {
+		t.Fatalf("expected default store options, got %+v", store.CookieStore.Options)
+	}
+
+	// the cookie follows the options of the session
+	res := httptest.NewRecorder()
+	err = store.Save(req, res, first)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	cookie := res.Header()["Set-Cookie"][0]
+	if !strings.Contains(cookie, "Max-Age=7357") || !strings.Contains(cookie, "Path=/admin") {
+		t.Fatalf("expected the session options in the cookie, got %s", cookie)
+	}
+}
+
+func TestCookieAttributesOverride(t *testing.T) {
+	store := newTestStore(t, "sessions_options_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	// a cross-site callback needs SameSite=None
+	session.Options.SameSite = http.SameSiteNoneMode
+	session.Options.Secure = true
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the next request loads the attributes with the session
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.Options.SameSite != http.SameSiteNoneMode || !session.Options.Secure {
+		t.Fatalf("expected the overridden attributes, got %+v", session.Options)
+	}
+
+	res = httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v\n", err)
+	}
+
+	cookie := res.Header().Get("Set-Cookie")
+	if !strings.Contains(cookie, "SameSite=None") || !strings.Contains(cookie, "Secure") {
+		t.Fatalf("expected the overridden attributes in the cookie, got %s", cookie)
+	}
+	if store.CookieStore.Options.SameSite == http.SameSiteNoneMode {
+		t.Fatal("expected the store options to be unchanged")
+	}
+}
@@ -0,0 +1,246 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// overflowChunkSize is the size of the chunks of an overflowing Data, the
+// GridFS default.
+const overflowChunkSize = 255 * 1024
+
+// overflowChunk is a piece of the Data of a session stored in the overflow
+// collection.
+type overflowChunk struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	Overflow primitive.ObjectID `bson:"overflow"`
+	N        int                `bson:"n"`
+	Data     []byte             `bson:"data"`
+	Expires  primitive.DateTime `bson:"expires_at"`
+}
+
+// overflowCollection returns the collection holding the chunks of
+// overflowing sessions.
+func (s *Store) overflowCollection() *mongo.Collection {
+	if s.MongoStore.OverflowCollection != nil {
+		return s.MongoStore.OverflowCollection
+	}
+
+	col := s.MongoStore.Collection
+	return col.Database().Collection(col.Name() + ".chunks")
+}
+
+// writeOverflow stores the encoded data of a session in the overflow
+// collection, and returns the id of the chunks.
+func (s *Store) writeOverflow(raw []byte, expires primitive.DateTime) (primitive.ObjectID, error) {
+	// the chunks of every save get a new id, so a failed save never leaves
+	// the session pointing at partially written chunks
+	id := primitive.NewObjectID()
+
+	var chunks []interface{}
+	for n := 0; len(raw) > 0; n++ {
+		size := overflowChunkSize
+		if len(raw) < size {
+			size = len(raw)
+		}
+
+		chunks = append(chunks, overflowChunk{
+			Overflow: id,
+			N:        n,
+			Data:     raw[:size],
+			Expires:  expires,
+		})
+		raw = raw[size:]
+	}
+
+	err := s.retry(func() error {
+		_, err := s.overflowCollection().InsertMany(s.MongoStore.Context, chunks)
+		return err
+	})
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return id, nil
+}
+
+// fillOverflow reads the overflow chunks of a loaded session into
+// session.Values, keeping the values set since it was loaded. It returns
+// ErrSessionExpired if the chunks are gone, removed by their time to live
+// index or never fully written.
+func (s *Store) fillOverflow(session *sessions.Session) error {
+	id, ok := session.Values[overflowKey].(primitive.ObjectID)
+	if _, pending := session.Values[overflowPendingKey]; !pending || !ok {
+		return nil
+	}
+	delete(session.Values, overflowPendingKey)
+
+	set := make(map[interface{}]interface{})
+	for k, v := range session.Values {
+		if _, ok := k.(metaKey); !ok {
+			set[k] = v
+		}
+	}
+
+	if s.MongoStore.RawData {
+		raw, err := s.readOverflowRaw(id)
+		if err != nil {
+			return err
+		}
+		err = s.decodeRawData(session, raw)
+		if err != nil {
+			return fmt.Errorf("mongostore: decoding session data: %w", err)
+		}
+	} else {
+		data, err := s.readOverflow(id)
+		if err != nil {
+			return err
+		}
+		s.fillData(session, data)
+	}
+
+	for k, v := range set {
+		session.Values[k] = v
+	}
+
+	return nil
+}
+
+// loadOverflow reads the pending overflow chunks of a session on first
+// access. A session whose chunks are gone becomes new, like an expired
+// session, keeping the values set since it was loaded.
+func (s *Store) loadOverflow(session *sessions.Session) error {
+	unchanged := s.unchanged(session)
+
+	err := s.fillOverflow(session)
+	if errors.Is(err, ErrSessionExpired) {
+		s.log(s.MongoStore.Context, slog.LevelInfo, "no session overflow in mongo", slog.String("name", session.Name()), s.sessionIDAttr(session.ID), errorAttr(err))
+		s.count(&s.counters.LoadMisses)
+
+		// the metadata of the stored session
+		for _, k := range []metaKey{ownerKey, persistentKey, shardKeyKey, fingerprintKey, overflowKey, csrfKey, oidcSIDKey, expiringKey, namespacesKey, versionKey} {
+			delete(session.Values, k)
+		}
+		session.IsNew = true
+		session.Options.MaxAge = s.tierMaxAge(session)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// the chunks are part of the session as loaded
+	if unchanged {
+		s.markUnchanged(session)
+	}
+
+	return nil
+}
+
+// readOverflow loads the data of a session from the overflow collection.
+func (s *Store) readOverflow(id primitive.ObjectID) (primitive.M, error) {
+	raw, err := s.readOverflowRaw(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: decoding session overflow: %w", err)
+	}
+	err = dec.SetRegistry(s.registry())
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: decoding session overflow: %w", err)
+	}
+
+	var data primitive.M
+	err = dec.Decode(&data)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: decoding session overflow: %w", err)
+	}
+
+	return data, nil
+}
+
+// readOverflowRaw loads the encoded data of a session from the overflow
+// collection.
+func (s *Store) readOverflowRaw(id primitive.ObjectID) (bson.Raw, error) {
+	var chunks []overflowChunk
+	err := s.retry(func() error {
+		cursor, err := s.overflowCollection().Find(
+			s.MongoStore.Context,
+			bson.M{"overflow": id},
+			options.Find().SetSort(bson.D{{Key: "n", Value: 1}}),
+		)
+		if err != nil {
+			return err
+		}
+		return cursor.All(s.MongoStore.Context, &chunks)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: reading session overflow: %w", err)
+	}
+	var raw []byte
+	for n, chunk := range chunks {
+		if chunk.N != n {
+			break
+		}
+		raw = append(raw, chunk.Data...)
+	}
+
+	// the chunks expired, or were not all written
+	if len(chunks) == 0 || bson.Raw(raw).Validate() != nil {
+		return nil, wrapError(ErrSessionExpired, errors.New("overflow chunks missing"))
+	}
+
+	return raw, nil
+}
+
+// deleteOverflow removes the chunks the session pointed to when it was
+// loaded, unless they are still in use. The chunks are left to the time to
+// live index when they can not be deleted.
+func (s *Store) deleteOverflow(session *sessions.Session, current primitive.ObjectID) {
+	previous, ok := session.Values[overflowKey].(primitive.ObjectID)
+	if current.IsZero() {
+		delete(session.Values, overflowKey)
+	} else {
+		session.Values[overflowKey] = current
+	}
+	if !ok || previous == current {
+		return
+	}
+
+	_, err := s.overflowCollection().DeleteMany(s.MongoStore.Context, bson.M{"overflow": previous})
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelWarn, "deleting overflow", slog.String("overflow", previous.Hex()), errorAttr(err))
+	}
+}
+
+// insertOverflowIndexes adds the indexes of the overflow collection: the
+// chunks of a session are found by overflow id, and removed when the session
+// expires.
+func (s *Store) insertOverflowIndexes(ctx context.Context) error {
+	_, err := s.overflowCollection().Indexes().CreateMany(
+		ctx,
+		[]mongo.IndexModel{
+			{
+				Keys: bson.D{{Key: "overflow", Value: 1}, {Key: "n", Value: 1}},
+			},
+			{
+				Keys:    bson.D{{Key: "expires_at", Value: 1}},
+				Options: options.Index().SetExpireAfterSeconds(0),
+			},
+		},
+	)
+	return err
+}
@@ -0,0 +1,134 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestOverflow(t *testing.T) {
+	store := newTestStore(t, "sessions_overflow_test")
+	store.MongoStore.OverflowThreshold = 1024
+
+	chunks := store.MongoStore.Collection.Database().Collection("sessions_overflow_test.chunks")
+	err := chunks.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop chunks: %v\n", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	// large enough to need several chunks
+	big := strings.Repeat("x", 600*1024)
+	session.Values["big"] = big
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	mongoSession := &mongostore.MongoSession{}
+	err = store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Decode(mongoSession)
+	if err != nil {
+		t.Fatalf("failed to find session: %v\n", err)
+	}
+	if mongoSession.Overflow.IsZero() || len(mongoSession.Data) != 0 {
+		t.Fatal("expected the data to overflow")
+	}
+
+	count, err := chunks.CountDocuments(context.TODO(), bson.M{"overflow": mongoSession.Overflow})
+	if err != nil {
+		t.Fatalf("failed to count chunks: %v\n", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 chunks, got %d", count)
+	}
+
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if _, ok := session.Values["big"]; ok {
+		t.Fatal("expected the overflow data to be read on first access")
+	}
+	values, err := store.Values(session)
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if values["big"] != big {
+		t.Fatal("expected the overflow data to be loaded")
+	}
+
+	// shrinking removes the chunks
+	session.Values["big"] = "small"
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	count, err = chunks.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to count chunks: %v\n", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no chunks, got %d", count)
+	}
+}
+
+func TestOverflowMissingChunks(t *testing.T) {
+	store := newTestStore(t, "sessions_overflow_test")
+	store.MongoStore.OverflowThreshold = 1024
+
+	chunks := store.MongoStore.Collection.Database().Collection("sessions_overflow_test.chunks")
+	err := chunks.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop chunks: %v\n", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["big"] = strings.Repeat("x", 4096)
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	// the chunks expired before the session document
+	_, err = chunks.DeleteMany(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to delete chunks: %v\n", err)
+	}
+
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	values, err := store.Values(session)
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatal("expected a session without chunks to be new")
+	}
+	if _, ok := values["big"]; ok {
+		t.Fatal("expected no overflow data")
+	}
+}
@@ -0,0 +1,67 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SetOwner associates the session with a user, the owner is stored in the
+// indexed user_id field of the mongo session when the session is saved. An
+// empty userID removes the owner.
+func (s *Store) SetOwner(session *sessions.Session, userID string) {
+	session.Values[ownerKey] = userID
+	session.Values[ownerChangedKey] = true
+}
+
+// Owner returns the user associated with the session, or an empty string if
+// the session has no owner.
+func (s *Store) Owner(session *sessions.Session) string {
+	userID, _ := session.Values[ownerKey].(string)
+	return userID
+}
+
+// ownedAt returns the owned_at of a session saved now: now when SetOwner
+// was called since the session was loaded, zero to keep the stored one.
+func (s *Store) ownedAt(session *sessions.Session) primitive.DateTime {
+	if s.Owner(session) == "" || !ownerChanged(session) {
+		return 0
+	}
+	return primitive.NewDateTimeFromTime(s.now())
+}
+
+// ownerChanged reports if SetOwner was called since the session was loaded.
+func ownerChanged(session *sessions.Session) bool {
+	changed, _ := session.Values[ownerChangedKey].(bool)
+	return changed
+}
+
+// SessionsByOwner returns the live sessions of a user, oldest first, using
+// the index on user_id. With Options.TenantFunc, only the sessions of the
+// tenant set on ctx with WithTenant are returned.
+func (s *Store) SessionsByOwner(ctx context.Context, userID string) ([]*MongoSession, error) {
+	cursor, err := s.readCollection().Find(
+		ctx,
+		s.queryScope(ctx, bson.M{
+			"user_id": userID,
+		}),
+		options.Find().
+			SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: finding sessions of %s: %w", userID, err)
+	}
+
+	var mongoSessions []*MongoSession
+	err = cursor.All(ctx, &mongoSessions)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: decoding sessions of %s: %w", userID, err)
+	}
+
+	return mongoSessions, nil
+}
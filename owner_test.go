@@ -0,0 +1,68 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionsByOwner(t *testing.T) {
+	store := newTestStore(t, "sessions_owner_test")
+
+	for i := 0; i < 2; i++ {
+		err := login(t, store, "owner-user")
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	mongoSessions, err := store.SessionsByOwner(context.TODO(), "owner-user")
+	if err != nil {
+		t.Fatalf("failed to find sessions: %v\n", err)
+	}
+	if len(mongoSessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(mongoSessions))
+	}
+
+	// a new session remembers its owner
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(session, "owner-user")
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", res.Header()["Set-Cookie"][0])
+	res = httptest.NewRecorder()
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if store.Owner(session) != "owner-user" {
+		t.Fatalf("expected owner-user, got %q", store.Owner(session))
+	}
+
+	// removing the owner
+	store.SetOwner(session, "")
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v\n", err)
+	}
+
+	mongoSessions, err = store.SessionsByOwner(context.TODO(), "owner-user")
+	if err != nil {
+		t.Fatalf("failed to find sessions: %v\n", err)
+	}
+	if len(mongoSessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(mongoSessions))
+	}
+}
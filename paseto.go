@@ -0,0 +1,241 @@
+package mongostore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+)
+
+// pasetoHeader is the header of every PASETO token, only v4.local is
+// supported.
+const pasetoHeader = "v4.local."
+
+// pasetoKeyLength is the length of a v4.local key.
+const pasetoKeyLength = 32
+
+// errInvalidPASETO is returned by PASETOEncoder.Decode when a token is
+// malformed, tampered with or expired.
+var errInvalidPASETO = errors.New("mongostore: invalid paseto")
+
+// PASETOEncoder encodes the session id as a PASETO v4.local token, a
+// symmetric token encrypted with XChaCha20 and authenticated with BLAKE2b.
+// The token holds the session id, the name of the session as audience and
+// the time it was issued.
+type PASETOEncoder struct {
+	keys [][]byte
+
+	// MaxAge is how long the tokens are valid, they have an expiry when it
+	// is set. Zero leaves the expiry to the session.
+	MaxAge time.Duration
+
+	now func() time.Time
+}
+
+// pasetoClaims are the claims of a token, it only references the session.
+type pasetoClaims struct {
+	SessionID string `json:"sid"`
+	Audience  string `json:"aud"`
+	IssuedAt  string `json:"iat"`
+	Expires   string `json:"exp,omitempty"`
+}
+
+// NewPASETOEncoder returns an encoder of PASETO v4.local tokens. Like the
+// key pairs of NewStore the first key encodes the tokens and the other keys
+// only decode them, pass the new key first when rotating. It returns
+// ErrWeakKey if there are no keys or a key is not 32 bytes.
+func NewPASETOEncoder(keys ...[]byte) (*PASETOEncoder, error) {
+	if len(keys) == 0 {
+		return nil, wrapError(ErrWeakKey, errors.New("no paseto keys"))
+	}
+	for i, key := range keys {
+		if len(key) != pasetoKeyLength {
+			return nil, wrapError(ErrWeakKey, fmt.Errorf(
+				"paseto key %d is %d bytes, v4.local requires %d", i+1, len(key), pasetoKeyLength,
+			))
+		}
+	}
+
+	return &PASETOEncoder{keys: keys, now: time.Now}, nil
+}
+
+// Encode returns a token for the session id, encrypted with the first key.
+func (e *PASETOEncoder) Encode(name string, id string) (string, error) {
+	now := e.now().UTC()
+	claims := pasetoClaims{
+		SessionID: id,
+		Audience:  name,
+		IssuedAt:  now.Format(time.RFC3339),
+	}
+	if e.MaxAge > 0 {
+		claims.Expires = now.Add(e.MaxAge).Format(time.RFC3339)
+	}
+	message, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, 32)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	return pasetoEncrypt(e.keys[0], nonce, message)
+}
+
+// Decode validates the token with each key and returns the session id it
+// references.
+func (e *PASETOEncoder) Decode(name string, value string) (string, error) {
+	for _, key := range e.keys {
+		message, err := pasetoDecrypt(key, value)
+		if err != nil {
+			continue
+		}
+
+		var claims pasetoClaims
+		err = json.Unmarshal(message, &claims)
+		if err != nil || claims.Audience != name || claims.SessionID == "" {
+			return "", errInvalidPASETO
+		}
+
+		if claims.Expires != "" {
+			expires, err := time.Parse(time.RFC3339, claims.Expires)
+			if err != nil {
+				return "", errInvalidPASETO
+			}
+			if e.now().After(expires) {
+				return "", wrapError(ErrSessionExpired, errInvalidPASETO)
+			}
+		}
+
+		return claims.SessionID, nil
+	}
+
+	return "", errInvalidPASETO
+}
+
+// pasetoEncrypt encrypts the message as a v4.local token, without footer
+// or implicit assertion.
+func pasetoEncrypt(key []byte, nonce []byte, message []byte) (string, error) {
+	encKey, counterNonce, authKey, err := pasetoKeys(key, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	stream, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(message))
+	stream.XORKeyStream(ciphertext, message)
+
+	tag, err := pasetoTag(authKey, nonce, ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	payload := make([]byte, 0, len(nonce)+len(ciphertext)+len(tag))
+	payload = append(payload, nonce...)
+	payload = append(payload, ciphertext...)
+	payload = append(payload, tag...)
+
+	return pasetoHeader + base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// pasetoDecrypt authenticates and decrypts a v4.local token.
+func pasetoDecrypt(key []byte, token string) ([]byte, error) {
+	if !strings.HasPrefix(token, pasetoHeader) || strings.Contains(token[len(pasetoHeader):], ".") {
+		return nil, errInvalidPASETO
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(token[len(pasetoHeader):])
+	if err != nil || len(payload) < 32+32 {
+		return nil, errInvalidPASETO
+	}
+
+	nonce := payload[:32]
+	ciphertext := payload[32 : len(payload)-32]
+	tag := payload[len(payload)-32:]
+
+	encKey, counterNonce, authKey, err := pasetoKeys(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// check the tag before decrypting
+	expected, err := pasetoTag(authKey, nonce, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(tag, expected) != 1 {
+		return nil, errInvalidPASETO
+	}
+
+	stream, err := chacha20.NewUnauthenticatedCipher(encKey, counterNonce)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, len(ciphertext))
+	stream.XORKeyStream(message, ciphertext)
+
+	return message, nil
+}
+
+// pasetoKeys derives the encryption key, the XChaCha20 nonce and the
+// authentication key of a token from the key and the random nonce.
+func pasetoKeys(key []byte, nonce []byte) (encKey []byte, counterNonce []byte, authKey []byte, err error) {
+	h, err := blake2b.New(56, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	h.Write([]byte("paseto-encryption-key"))
+	h.Write(nonce)
+	tmp := h.Sum(nil)
+
+	h, err = blake2b.New(32, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	h.Write([]byte("paseto-auth-key-for-aead"))
+	h.Write(nonce)
+
+	return tmp[:32], tmp[32:], h.Sum(nil), nil
+}
+
+// pasetoTag returns the authentication tag of the header, nonce and
+// ciphertext, with an empty footer and implicit assertion.
+func pasetoTag(authKey []byte, nonce []byte, ciphertext []byte) ([]byte, error) {
+	h, err := blake2b.New(32, authKey)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(pae([]byte(pasetoHeader), nonce, ciphertext, nil, nil))
+	return h.Sum(nil), nil
+}
+
+// pae is the pre-authentication encoding of PASETO, the count and each
+// piece prefixed with its length as a little endian 64-bit integer.
+func pae(pieces ...[]byte) []byte {
+	var buf bytes.Buffer
+	le64 := func(n int) {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], uint64(n)&^(1<<63))
+		buf.Write(b[:])
+	}
+
+	le64(len(pieces))
+	for _, piece := range pieces {
+		le64(len(piece))
+		buf.Write(piece)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,107 @@
+package mongostore_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestPASETOEncoder(t *testing.T) {
+	oldKey := securecookie.GenerateRandomKey(32)
+	encoder, err := mongostore.NewPASETOEncoder(oldKey)
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v\n", err)
+	}
+
+	token, err := encoder.Encode("test-session", "session-id")
+	if err != nil {
+		t.Fatalf("failed to encode token: %v\n", err)
+	}
+	if !strings.HasPrefix(token, "v4.local.") {
+		t.Fatalf("expected a v4.local token, got %s", token)
+	}
+
+	id, err := encoder.Decode("test-session", token)
+	if err != nil {
+		t.Fatalf("failed to decode token: %v\n", err)
+	}
+	if id != "session-id" {
+		t.Fatalf("expected session-id, got %s", id)
+	}
+
+	// the token is bound to the session name
+	_, err = encoder.Decode("other-session", token)
+	if err == nil {
+		t.Fatal("expected the token of another session to be rejected")
+	}
+
+	// a tampered token is rejected
+	tampered := token[:len(token)-2] + "AA"
+	if tampered == token {
+		tampered = token[:len(token)-2] + "BB"
+	}
+	_, err = encoder.Decode("test-session", tampered)
+	if err == nil {
+		t.Fatal("expected a tampered token to be rejected")
+	}
+
+	// tokens of the old key are decoded after a rotation
+	rotated, err := mongostore.NewPASETOEncoder(securecookie.GenerateRandomKey(32), oldKey)
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v\n", err)
+	}
+	id, err = rotated.Decode("test-session", token)
+	if err != nil || id != "session-id" {
+		t.Fatalf("expected the token decoded with the old key, got %q, %v", id, err)
+	}
+
+	_, err = mongostore.NewPASETOEncoder([]byte("short"))
+	if !errors.Is(err, mongostore.ErrWeakKey) {
+		t.Fatalf("expected ErrWeakKey, got %v", err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	store := newTestStore(t, "sessions_encoder_test")
+	encoder, err := mongostore.NewPASETOEncoder(securecookie.GenerateRandomKey(32))
+	if err != nil {
+		t.Fatalf("failed to create encoder: %v\n", err)
+	}
+	store.MongoStore.Encoder = encoder
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the cookie is a PASETO token
+	token := res.Result().Cookies()[0].Value
+	if !strings.HasPrefix(token, "v4.local.") {
+		t.Fatalf("expected a paseto token, got %s", token)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: token})
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the stored session, got %v", session.Values)
+	}
+}
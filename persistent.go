@@ -0,0 +1,91 @@
+package mongostore
+
+import (
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SetPersistent moves the session to the "remember me" tier, which lasts
+// Options.PersistentMaxAge seconds instead of the default cookie MaxAge.
+// The new lifetime applies to the cookie and mongo on the next Save.
+func (s *Store) SetPersistent(session *sessions.Session, persistent bool) {
+	session.Values[persistentKey] = persistent
+	session.Options.MaxAge = s.tierMaxAge(session)
+}
+
+// IsPersistent reports if the session is a "remember me" session.
+func (s *Store) IsPersistent(session *sessions.Session) bool {
+	persistent, _ := session.Values[persistentKey].(bool)
+	return persistent
+}
+
+// tierMaxAge returns the lifetime of the session in seconds, depending on its
+// tier.
+func (s *Store) tierMaxAge(session *sessions.Session) int {
+	if s.IsPersistent(session) && s.MongoStore.PersistentMaxAge > 0 {
+		return s.MongoStore.PersistentMaxAge
+	}
+	return s.defaultCookie.MaxAge
+}
+
+// defaultBrowserSessionRetention is how long browser sessions are kept in
+// mongo when Options.BrowserSessionRetention is zero.
+const defaultBrowserSessionRetention = 24 * time.Hour
+
+// serverMaxAge returns how long in seconds a session with the given MaxAge
+// lives in mongo, Options.ServerTTL when it is set. A MaxAge of 0 makes a
+// browser session cookie, without Max-Age or Expires, which lives in mongo
+// for Options.BrowserSessionRetention.
+func (s *Store) serverMaxAge(maxAge int) int {
+	if s.MongoStore.ServerTTL > 0 {
+		return int(s.MongoStore.ServerTTL / time.Second)
+	}
+	if maxAge != 0 {
+		return maxAge
+	}
+
+	retention := s.MongoStore.BrowserSessionRetention
+	if retention <= 0 {
+		retention = defaultBrowserSessionRetention
+	}
+	return int(retention / time.Second)
+}
+
+// ttlSeconds returns the expireAfterSeconds of the time to live index, the
+// server side lifetime of the default MaxAge.
+func (s *Store) ttlSeconds() int {
+	return s.serverMaxAge(s.defaultCookie.MaxAge)
+}
+
+// indexExpireAfterSeconds returns the expireAfterSeconds of the time to live
+// index, 0 with Options.ExpireAt as each document has its own expiry. On
+// Cosmos DB the index is on the time of the last write and keeps sessions
+// for the longest lifetime, the janitor purges the others.
+func (s *Store) indexExpireAfterSeconds() int {
+	if s.backend == BackendCosmos {
+		return s.longestServerMaxAge()
+	}
+	if s.MongoStore.ExpireAt {
+		return 0
+	}
+	return s.ttlSeconds()
+}
+
+// expiry returns the expires_at and ttl values of the session, which lives
+// for session.Options.MaxAge seconds, or Options.BrowserSessionRetention
+// for a browser session.
+//
+// The TTL index removes documents MaxAge seconds after their ttl field, so
+// sessions living longer than the default MaxAge get a ttl in the future.
+func (s *Store) expiry(session *sessions.Session) (expires primitive.DateTime, ttl primitive.DateTime) {
+	now := s.now()
+	maxAge := s.serverMaxAge(session.Options.MaxAge)
+
+	expires = primitive.NewDateTimeFromTime(now.Add(time.Duration(maxAge) * time.Second))
+	ttl = primitive.NewDateTimeFromTime(now.Add(time.Duration(maxAge-s.ttlSeconds()) * time.Second))
+
+	return expires, ttl
+}
@@ -0,0 +1,210 @@
+package mongostore_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestSetPersistent(t *testing.T) {
+	store := newTestStore(t, "sessions_persistent_test")
+	store.MongoStore.PersistentMaxAge = 30 * 24 * 60 * 60
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetPersistent(session, true)
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the cookie follows the persistent tier
+	cookies := res.Header()["Set-Cookie"]
+	if len(cookies) != 1 || !strings.Contains(cookies[0], "Max-Age=2592000") {
+		t.Fatalf("expected a persistent cookie, got %v", cookies)
+	}
+
+	// mongo follows the persistent tier
+	mongoSession := &mongostore.MongoSession{}
+	err = store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Decode(mongoSession)
+	if err != nil {
+		t.Fatalf("failed to find session: %v\n", err)
+	}
+	if !mongoSession.Persistent {
+		t.Fatal("expected a persistent session")
+	}
+	if mongoSession.Expires.Time().Before(time.Now().Add(29 * 24 * time.Hour)) {
+		t.Fatalf("expected a long expiry, got %v", mongoSession.Expires.Time())
+	}
+
+	// the tier survives a reload
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", cookies[0])
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if !store.IsPersistent(session) {
+		t.Fatal("expected the session to stay persistent")
+	}
+}
+
+func TestBrowserSession(t *testing.T) {
+	store := newTestStore(t, "sessions_browser_test")
+	store.MongoStore.BrowserSessionRetention = time.Hour
+	err := store.MaxAge(0)
+	if err != nil {
+		t.Fatalf("failed to set MaxAge: %v\n", err)
+	}
+
+	// the document outlives the cookie
+	if got := ttlExpireAfterSeconds(t, store.MongoStore.Collection); got != 3600 {
+		t.Fatalf("expected expireAfterSeconds 3600, got %v", got)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	cookie := res.Header().Get("Set-Cookie")
+	if strings.Contains(cookie, "Max-Age") || strings.Contains(cookie, "Expires") {
+		t.Fatalf("expected a browser session cookie, got %s", cookie)
+	}
+
+	req.Header.Set("Cookie", cookie)
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the stored session, got %v", session.Values)
+	}
+}
+
+func TestServerTTL(t *testing.T) {
+	store := newTestStore(t, "sessions_server_ttl_test")
+	store.MongoStore.ServerTTL = time.Minute
+	err := store.MaxAge(3600)
+	if err != nil {
+		t.Fatalf("failed to set MaxAge: %v\n", err)
+	}
+
+	// the document expires before the cookie
+	if got := ttlExpireAfterSeconds(t, store.MongoStore.Collection); got != 60 {
+		t.Fatalf("expected expireAfterSeconds 60, got %v", got)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if cookie := res.Header().Get("Set-Cookie"); !strings.Contains(cookie, "Max-Age=3600") {
+		t.Fatalf("expected the cookie to keep its MaxAge, got %s", cookie)
+	}
+
+	var doc struct {
+		Expires time.Time `bson:"expires_at"`
+	}
+	err = store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Decode(&doc)
+	if err != nil {
+		t.Fatalf("failed to find session: %v\n", err)
+	}
+	if until := time.Until(doc.Expires); until > time.Minute || until < 50*time.Second {
+		t.Fatalf("expected the session to expire in a minute, got %v", until)
+	}
+}
+
+func TestPersistentCookieAge(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_persistent_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	// an authentication key only, so the test can sign an old cookie
+	hashKey := securecookie.GenerateRandomKey(32)
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:       col,
+			PersistentMaxAge: 90 * 24 * 60 * 60,
+		},
+		http.Cookie{Path: "/", MaxAge: 240},
+		hashKey,
+	)
+	if err != nil {
+		t.Fatalf("failed to create test store: %v\n", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetPersistent(session, true)
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the cookie of a remember me session issued 40 days ago, past the 30
+	// days securecookie accepts by default
+	var id bytes.Buffer
+	err = gob.NewEncoder(&id).Encode(session.ID)
+	if err != nil {
+		t.Fatalf("failed to encode id: %v\n", err)
+	}
+	signed := fmt.Sprintf("test-session|%d|%s", time.Now().Add(-40*24*time.Hour).Unix(), base64.URLEncoding.EncodeToString(id.Bytes()))
+	mac := hmac.New(sha256.New, hashKey)
+	mac.Write([]byte(signed))
+	value := base64.URLEncoding.EncodeToString(append([]byte(signed[len("test-session|"):]+"|"), mac.Sum(nil)...))
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.AddCookie(&http.Cookie{Name: "test-session", Value: value})
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || !store.IsPersistent(session) {
+		t.Fatal("expected the old remember me cookie to be decoded")
+	}
+}
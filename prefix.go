@@ -0,0 +1,46 @@
+package mongostore
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+// cookie name prefixes, browsers only accept prefixed cookies with the
+// required attributes.
+const (
+	hostPrefix   = "__Host-"
+	securePrefix = "__Secure-"
+)
+
+// applyCookiePrefix sets the attributes required by the prefix of the cookie
+// name: __Secure- cookies must be Secure, __Host- cookies must also have Path
+// "/" and no Domain.
+func applyCookiePrefix(name string, options *sessions.Options) {
+	switch {
+	case strings.HasPrefix(name, hostPrefix):
+		options.Secure = true
+		options.Path = "/"
+		options.Domain = ""
+	case strings.HasPrefix(name, securePrefix):
+		options.Secure = true
+	}
+}
+
+// checkCookiePrefix returns ErrCookiePrefix if the options of a session were
+// changed in a way browsers reject for the prefix of the cookie name.
+func checkCookiePrefix(name string, options *sessions.Options) error {
+	switch {
+	case strings.HasPrefix(name, hostPrefix):
+		if !options.Secure || options.Path != "/" || options.Domain != "" {
+			return wrapError(ErrCookiePrefix, errors.New(name+" requires Secure, Path=/ and no Domain"))
+		}
+	case strings.HasPrefix(name, securePrefix):
+		if !options.Secure {
+			return wrapError(ErrCookiePrefix, errors.New(name+" requires Secure"))
+		}
+	}
+
+	return nil
+}
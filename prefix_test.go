@@ -0,0 +1,60 @@
+package mongostore_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestHostPrefix(t *testing.T) {
+	store := newTestStore(t, "sessions_prefix_test")
+	store.CookieStore.Options.Domain = "example.com"
+	store.CookieStore.Options.Path = "/app"
+
+	req, _ := http.NewRequest("GET", "https://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "__Host-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	if !session.Options.Secure || session.Options.Path != "/" || session.Options.Domain != "" {
+		t.Fatalf("expected __Host- attributes, got %+v", session.Options)
+	}
+
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	// a handler breaking the prefix rules
+	session.Options.Domain = "example.com"
+	err = store.Save(req, res, session)
+	if !errors.Is(err, mongostore.ErrCookiePrefix) {
+		t.Fatalf("expected ErrCookiePrefix, got %v", err)
+	}
+}
+
+func TestSecurePrefix(t *testing.T) {
+	store := newTestStore(t, "sessions_prefix_test")
+
+	req, _ := http.NewRequest("GET", "https://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "__Secure-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	if !session.Options.Secure {
+		t.Fatal("expected a secure cookie")
+	}
+
+	session.Options.Secure = false
+	err = store.Save(req, res, session)
+	if !errors.Is(err, mongostore.ErrCookiePrefix) {
+		t.Fatalf("expected ErrCookiePrefix, got %v", err)
+	}
+}
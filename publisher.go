@@ -0,0 +1,224 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionEventType is what happened to a session.
+type SessionEventType string
+
+const (
+	// SessionCreated is published when a new session is inserted.
+	SessionCreated SessionEventType = "session.created"
+
+	// SessionDestroyed is published when a session is deleted with Save.
+	SessionDestroyed SessionEventType = "session.destroyed"
+)
+
+// defaultOutboxInterval is how often RelayOutbox polls the outbox when
+// given no interval.
+const defaultOutboxInterval = time.Second
+
+// outboxBatch is the number of events RelayOutbox reads at once.
+const outboxBatch = 100
+
+// SessionEvent is a session lifecycle event passed to the Publisher.
+type SessionEvent struct {
+	// ID identifies the event, consumers use it to drop the duplicates of
+	// an at least once delivery.
+	ID primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+
+	Type SessionEventType `bson:"type" json:"type"`
+
+	// SessionID is the session id as recorded in the audit log, the hash of
+	// the token with Options.OpaqueTokens.
+	SessionID string `bson:"session_id" json:"session_id"`
+
+	UserID    string    `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	TenantID  string    `bson:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	RequestID string    `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	At        time.Time `bson:"at" json:"at"`
+}
+
+// Publisher publishes session events to an event bus, such as NATS or
+// Kafka, see the adapters in the contrib module.
+type Publisher interface {
+	Publish(ctx context.Context, event SessionEvent) error
+}
+
+// PublisherFunc adapts a function to the Publisher interface.
+type PublisherFunc func(ctx context.Context, event SessionEvent) error
+
+// Publish calls f.
+func (f PublisherFunc) Publish(ctx context.Context, event SessionEvent) error {
+	return f(ctx, event)
+}
+
+// sessionEvent returns the event of a session written by the request.
+func (s *Store) sessionEvent(r *http.Request, session *sessions.Session, typ SessionEventType) SessionEvent {
+	event := SessionEvent{
+		ID:        primitive.NewObjectID(),
+		Type:      typ,
+		SessionID: s.auditID(session),
+		UserID:    s.Owner(session),
+		TenantID:  tenant(session),
+		At:        s.now().UTC(),
+	}
+	if r != nil {
+		event.RequestID = s.requestID(r.Context())
+	}
+	return event
+}
+
+// publishes reports whether session events are published.
+func (s *Store) publishes() bool {
+	return s.MongoStore.Publisher != nil || s.MongoStore.OutboxCollection != nil
+}
+
+// writeEvents runs the write of sessions, then publishes the events it
+// returns. With Options.OutboxCollection the events are inserted in the
+// outbox in the same transaction as the write, or right after it on
+// backends without transactions, and RelayOutbox publishes them. Without it they are published once the write succeeded, a failed
+// publish is logged and the event is lost.
+func (s *Store) writeEvents(r *http.Request, write func(ctx context.Context) ([]SessionEvent, error)) error {
+	if s.MongoStore.OutboxCollection == nil {
+		events, err := write(s.MongoStore.Context)
+		if err != nil {
+			return err
+		}
+		if s.MongoStore.Publisher == nil {
+			return nil
+		}
+
+		for _, event := range events {
+			err = s.MongoStore.Publisher.Publish(s.MongoStore.Context, event)
+			if err != nil {
+				s.log(s.logContext(r), slog.LevelError, "publishing session event", slog.String("type", string(event.Type)), s.sessionIDAttr(event.SessionID), errorAttr(err))
+			}
+		}
+		return nil
+	}
+
+	// without transactions the events are written once the sessions are
+	if !s.capabilities.Transactions {
+		events, err := write(s.MongoStore.Context)
+		if err != nil {
+			return err
+		}
+		return s.insertOutbox(s.MongoStore.Context, events)
+	}
+
+	txn, err := s.MongoStore.Collection.Database().Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("mongostore: starting transaction: %w", err)
+	}
+	defer txn.EndSession(s.MongoStore.Context)
+
+	_, err = txn.WithTransaction(s.MongoStore.Context, func(ctx mongo.SessionContext) (interface{}, error) {
+		events, err := write(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.insertOutbox(ctx, events)
+	})
+
+	return err
+}
+
+// insertOutbox inserts the events in Options.OutboxCollection.
+func (s *Store) insertOutbox(ctx context.Context, events []SessionEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(events))
+	for i := range events {
+		docs[i] = events[i]
+	}
+	_, err := s.MongoStore.OutboxCollection.InsertMany(ctx, docs)
+	if err != nil {
+		return fmt.Errorf("mongostore: writing outbox: %w", err)
+	}
+	return nil
+}
+
+// RelayOutbox publishes the events of Options.OutboxCollection to
+// Options.Publisher every interval, oldest first, and removes them once
+// published. An event is published again if the relay stops before removing
+// it, consumers drop duplicates by SessionEvent.ID. Several instances can
+// relay the same outbox, at the cost of more duplicates.
+//
+// It blocks until ctx is done, run it in a goroutine.
+func (s *Store) RelayOutbox(ctx context.Context, interval time.Duration) error {
+	if s.MongoStore.OutboxCollection == nil || s.MongoStore.Publisher == nil {
+		return fmt.Errorf("mongostore: relaying outbox: OutboxCollection and Publisher are required")
+	}
+	if interval <= 0 {
+		interval = defaultOutboxInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := s.relayOutbox(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.log(ctx, slog.LevelError, "relaying outbox", errorAttr(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// relayOutbox publishes the pending events until the outbox is empty, or a
+// publish fails.
+func (s *Store) relayOutbox(ctx context.Context) error {
+	outbox := s.MongoStore.OutboxCollection
+
+	for {
+		cursor, err := outbox.Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}).SetLimit(outboxBatch))
+		if err != nil {
+			return fmt.Errorf("mongostore: reading outbox: %w", err)
+		}
+
+		var events []SessionEvent
+		err = cursor.All(ctx, &events)
+		if err != nil {
+			return fmt.Errorf("mongostore: reading outbox: %w", err)
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		for _, event := range events {
+			err = s.MongoStore.Publisher.Publish(ctx, event)
+			if err != nil {
+				return fmt.Errorf("mongostore: publishing session event %s: %w", event.ID.Hex(), err)
+			}
+
+			_, err = outbox.DeleteOne(ctx, bson.M{"_id": event.ID})
+			if err != nil {
+				return fmt.Errorf("mongostore: removing published event %s: %w", event.ID.Hex(), err)
+			}
+		}
+
+		if len(events) < outboxBatch {
+			return nil
+		}
+	}
+}
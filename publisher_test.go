@@ -0,0 +1,125 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+// recordingPublisher keeps the published events.
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []mongostore.SessionEvent
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event mongostore.SessionEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) published() []mongostore.SessionEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]mongostore.SessionEvent(nil), p.events...)
+}
+
+func TestPublisher(t *testing.T) {
+	store := newTestStore(t, "sessions_publisher_test")
+
+	publisher := &recordingPublisher{}
+	store.MongoStore.Publisher = publisher
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(session, "publisher-user")
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	session.Values["step"] = 2
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v\n", err)
+	}
+
+	session.Options.MaxAge = -1
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v\n", err)
+	}
+
+	events := publisher.published()
+	if len(events) != 2 || events[0].Type != mongostore.SessionCreated || events[1].Type != mongostore.SessionDestroyed {
+		t.Fatalf("expected a create and a destroy event, got %+v", events)
+	}
+	if events[0].SessionID != session.ID || events[0].UserID != "publisher-user" || events[0].ID == events[1].ID {
+		t.Fatalf("unexpected event %+v", events[0])
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	store := newTestStore(t, "sessions_outbox_test")
+
+	outbox := mongoclient.Database("test-database").Collection("sessions_outbox_events_test")
+	err := outbox.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop outbox collection: %v\n", err)
+	}
+	publisher := &recordingPublisher{}
+	store.MongoStore.Publisher = publisher
+	store.MongoStore.OutboxCollection = outbox
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		// transactions need a replica set
+		t.Skipf("transactions unavailable: %v", err)
+	}
+
+	// events wait in the outbox for the relay
+	if len(publisher.published()) != 0 {
+		t.Fatal("expected no event before the relay")
+	}
+	count, err := outbox.CountDocuments(context.TODO(), bson.M{})
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 event in the outbox, got %d: %v", count, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = store.RelayOutbox(ctx, 10*time.Millisecond)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(publisher.published()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	events := publisher.published()
+	if len(events) != 1 || events[0].Type != mongostore.SessionCreated || events[0].SessionID != session.ID {
+		t.Fatalf("expected the create event, got %+v", events)
+	}
+}
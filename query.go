@@ -0,0 +1,144 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OwnedBy selects the sessions of a user, for FindSessions and DeleteWhere.
+func OwnedBy(userID string) bson.M {
+	return bson.M{"user_id": userID}
+}
+
+// CreatedBefore selects the sessions created before t.
+func CreatedBefore(t time.Time) bson.M {
+	return bson.M{"created_at": bson.M{"$lt": primitive.NewDateTimeFromTime(t)}}
+}
+
+// IdleSince selects the sessions not saved since t.
+func IdleSince(t time.Time) bson.M {
+	return bson.M{"modified_at": bson.M{"$lt": primitive.NewDateTimeFromTime(t)}}
+}
+
+// AllOf selects the sessions matching every filter.
+func AllOf(filters ...bson.M) bson.M {
+	all := make(bson.A, len(filters))
+	for i, filter := range filters {
+		all[i] = filter
+	}
+	return bson.M{"$and": all}
+}
+
+// FindOptions are the options of FindSessions.
+type FindOptions struct {
+	// Sort orders the sessions, newest first by default.
+	Sort bson.D
+
+	// Limit is the maximum number of sessions returned, zero means no
+	// limit.
+	Limit int64
+}
+
+// SessionCursor iterates over the sessions found by FindSessions. It must
+// be closed.
+type SessionCursor struct {
+	cursor  *mongo.Cursor
+	session *MongoSession
+	err     error
+}
+
+// Next decodes the next session, it returns false once there are no more
+// sessions or an error occurred, see Err.
+func (c *SessionCursor) Next(ctx context.Context) bool {
+	if c.err != nil || !c.cursor.Next(ctx) {
+		return false
+	}
+
+	session := &MongoSession{}
+	err := c.cursor.Decode(session)
+	if err != nil {
+		c.err = fmt.Errorf("mongostore: decoding session: %w", err)
+		return false
+	}
+	c.session = session
+
+	return true
+}
+
+// Session returns the session decoded by the last call to Next. Its Data is
+// empty when it was moved to the overflow collection.
+func (c *SessionCursor) Session() *MongoSession {
+	return c.session
+}
+
+// Err returns the error that stopped Next, if any.
+func (c *SessionCursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+	return c.cursor.Err()
+}
+
+// Close closes the cursor.
+func (c *SessionCursor) Close(ctx context.Context) error {
+	return c.cursor.Close(ctx)
+}
+
+// FindSessions returns a cursor over the sessions matching the filter, such
+// as AllOf(OwnedBy(id), IdleSince(t)), for investigations and operational
+// cleanups. The filter is a mongo query on the session documents, it never
+// matches tombstoned sessions, nor with Options.TenantFunc the sessions of
+// another tenant than the one set on ctx with WithTenant.
+func (s *Store) FindSessions(ctx context.Context, filter bson.M, opts FindOptions) (*SessionCursor, error) {
+	filter = s.queryScope(ctx, filter)
+
+	sort := opts.Sort
+	if sort == nil {
+		sort = bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+	}
+	findOptions := options.Find().SetSort(sort)
+	if opts.Limit > 0 {
+		findOptions.SetLimit(opts.Limit)
+	}
+
+	cursor, err := s.readCollection().Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: finding sessions: %w", err)
+	}
+
+	return &SessionCursor{cursor: cursor}, nil
+}
+
+// DeleteWhere deletes the sessions matching the filter, and returns how many
+// were deleted. The filter is required, a nil or empty filter returns an
+// error instead of deleting every session. It is scoped like the filter of
+// FindSessions.
+func (s *Store) DeleteWhere(ctx context.Context, filter bson.M) (int64, error) {
+	if len(filter) == 0 {
+		return 0, errors.New("mongostore: deleting sessions: empty filter")
+	}
+	filter = s.queryScope(ctx, filter)
+
+	s.archive(ctx, filter)
+	res, err := s.deleteCollection().DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("mongostore: deleting sessions: %w", err)
+	}
+
+	s.replicate(func(col *mongo.Collection) error {
+		_, err := col.DeleteMany(ctx, filter)
+		return err
+	})
+
+	s.log(ctx, slog.LevelInfo, "sessions deleted", slog.String("op", "delete_where"), slog.Int64("count", res.DeletedCount))
+
+	return res.DeletedCount, nil
+}
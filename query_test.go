@@ -0,0 +1,65 @@
+package mongostore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestFindSessionsAndDeleteWhere(t *testing.T) {
+	store := newTestStore(t, "sessions_query_test")
+
+	for _, userID := range []string{"query-user", "query-user", "other-user"} {
+		err := login(t, store, userID)
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+
+	cursor, err := store.FindSessions(context.TODO(), mongostore.AllOf(
+		mongostore.OwnedBy("query-user"),
+		mongostore.CreatedBefore(time.Now().Add(time.Minute)),
+	), mongostore.FindOptions{})
+	if err != nil {
+		t.Fatalf("failed to find sessions: %v\n", err)
+	}
+	found := 0
+	for cursor.Next(context.TODO()) {
+		if cursor.Session().UserID != "query-user" {
+			t.Fatalf("expected a session of query-user, got %s", cursor.Session().UserID)
+		}
+		found++
+	}
+	if err := cursor.Err(); err != nil {
+		t.Fatalf("failed to iterate sessions: %v\n", err)
+	}
+	cursor.Close(context.TODO())
+	if found != 2 {
+		t.Fatalf("expected 2 sessions, got %d", found)
+	}
+
+	// nothing is idle yet
+	deleted, err := store.DeleteWhere(context.TODO(), mongostore.IdleSince(time.Now().Add(-time.Hour)))
+	if err != nil || deleted != 0 {
+		t.Fatalf("expected no session deleted, got %d %v", deleted, err)
+	}
+
+	deleted, err = store.DeleteWhere(context.TODO(), mongostore.OwnedBy("query-user"))
+	if err != nil || deleted != 2 {
+		t.Fatalf("expected 2 sessions deleted, got %d %v", deleted, err)
+	}
+
+	_, err = store.DeleteWhere(context.TODO(), bson.M{})
+	if err == nil {
+		t.Fatal("expected an empty filter to be rejected")
+	}
+
+	count, err := store.CountSessions(context.TODO())
+	if err != nil || count != 1 {
+		t.Fatalf("expected 1 session left, got %d %v", count, err)
+	}
+}
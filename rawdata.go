@@ -0,0 +1,61 @@
+package mongostore
+
+import (
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sessionDocument is a session document as findOne reads it, with
+// Options.RawData the Data field is kept encoded.
+type sessionDocument struct {
+	MongoSession `bson:",inline"`
+
+	Data bson.Raw `bson:"data,omitempty"`
+}
+
+// decodeRawData decodes each value of the encoded data into session.Values
+// with the registry of the store.
+func (s *Store) decodeRawData(session *sessions.Session, raw bson.Raw) error {
+	if len(raw) == 0 {
+		return nil
+	}
+
+	elements, err := raw.Elements()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range elements {
+		var v interface{}
+		err = e.Value().UnmarshalWithRegistry(s.registry(), &v)
+		if err != nil {
+			return err
+		}
+		v, ok := s.openSensitive(e.Key(), v)
+		if !ok {
+			continue
+		}
+		session.Values[s.decodeKey(e.Key())] = s.decodeValue(v)
+	}
+
+	return nil
+}
+
+// documentMap returns the fields of a decoded document, which is a
+// primitive.D with Options.RawData.
+func documentMap(value interface{}) (primitive.M, bool) {
+	switch doc := value.(type) {
+	case primitive.M:
+		return doc, true
+	case primitive.D:
+		m := make(primitive.M, len(doc))
+		for _, e := range doc {
+			m[e.Key] = e.Value
+		}
+		return m, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,55 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestRawData(t *testing.T) {
+	store := newTestStore(t, "sessions_rawdata_test")
+	store.MongoStore.RawData = true
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["doc"] = primitive.D{{Key: "z", Value: int64(1)}, {Key: "a", Value: int32(2)}}
+	session.Values["count"] = int64(3)
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	doc, ok := session.Values["doc"].(primitive.D)
+	if !ok || len(doc) != 2 || doc[0].Key != "z" || doc[1].Key != "a" {
+		t.Fatalf("expected the document to keep its order, got %#v", session.Values["doc"])
+	}
+	if _, ok := doc[0].Value.(int64); !ok {
+		t.Fatalf("expected an int64, got %T", doc[0].Value)
+	}
+	if _, ok := doc[1].Value.(int32); !ok {
+		t.Fatalf("expected an int32, got %T", doc[1].Value)
+	}
+	if count, ok := session.Values["count"].(int64); !ok || count != 3 {
+		t.Fatalf("expected count 3, got %#v", session.Values["count"])
+	}
+
+	m, err := mongostore.Get[map[string]int](session, "doc")
+	if err != nil || m["z"] != 1 || m["a"] != 2 {
+		t.Fatalf("expected the document as a map, got %v, %v", m, err)
+	}
+}
@@ -0,0 +1,72 @@
+package mongostore
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// recentWrites remembers when this instance last wrote each session with
+// Options.ReadYourWrites.
+type recentWrites struct {
+	mu      sync.Mutex
+	written map[string]time.Time // by session id
+}
+
+// markWritten remembers that the session was just written or deleted.
+func (s *Store) markWritten(id string) {
+	window := s.MongoStore.ReadYourWrites
+	if window <= 0 || id == "" {
+		return
+	}
+
+	now := s.now()
+	rw := &s.recentWrites
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.written == nil {
+		rw.written = make(map[string]time.Time)
+	}
+	if len(rw.written) >= coalesceSweep {
+		for id, at := range rw.written {
+			if now.Sub(at) >= window {
+				delete(rw.written, id)
+			}
+		}
+	}
+	rw.written[id] = now
+}
+
+// sessionReadCollection returns the collection used to read the session,
+// the primary when it was written less than Options.ReadYourWrites ago so a
+// lagging secondary does not return the session as it was before.
+func (s *Store) sessionReadCollection(id string) *mongo.Collection {
+	window := s.MongoStore.ReadYourWrites
+	if window <= 0 || id == "" {
+		return s.readCollection()
+	}
+
+	rw := &s.recentWrites
+	rw.mu.Lock()
+	at, ok := rw.written[id]
+	rw.mu.Unlock()
+	if !ok || s.now().Sub(at) >= window {
+		return s.readCollection()
+	}
+
+	return s.primaryCollection()
+}
+
+// primaryCollection returns the collection used to read sessions, reading
+// from the primary whatever Options.ReadPreference.
+func (s *Store) primaryCollection() *mongo.Collection {
+	return s.cloneCollection(
+		options.Collection().
+			SetReadPreference(readpref.Primary()).
+			SetReadConcern(s.MongoStore.ReadConcern),
+	)
+}
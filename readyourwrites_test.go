@@ -0,0 +1,50 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	store := newTestStore(t, "sessions_read_your_writes_test")
+	store.MongoStore.ReadPreference = readpref.SecondaryPreferred()
+	store.MongoStore.ReadYourWrites = 5 * time.Second
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	// each update is read back right away from the primary
+	for i := 0; i < 10; i++ {
+		session, err = store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to get session: %v\n", err)
+		}
+		if session.IsNew {
+			t.Fatalf("expected the session written by save %d", i)
+		}
+		if i > 0 && session.Values["count"] != i-1 {
+			t.Fatalf("expected count %d, got %v", i-1, session.Values["count"])
+		}
+
+		session.Values["count"] = i
+		err = store.Save(req, httptest.NewRecorder(), session)
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+}
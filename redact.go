@@ -0,0 +1,75 @@
+package mongostore
+
+import (
+	"fmt"
+	"path"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RedactedValue replaces the values of Data redacted by Options.Redact.
+const RedactedValue = "[REDACTED]"
+
+// checkRedact returns an error if a glob of Options.Redact is malformed.
+func checkRedact(globs []string) error {
+	for _, glob := range globs {
+		_, err := path.Match(glob, "")
+		if err != nil {
+			return fmt.Errorf("mongostore: redaction rule %q: %w", glob, err)
+		}
+	}
+	return nil
+}
+
+// Redact returns a copy of the session with the values of Data whose key
+// matches a glob of Options.Redact replaced with RedactedValue, to show it
+// to operators. The keys of namespaces are matched as namespace.key. The
+// stored session is left intact.
+func (s *Store) Redact(mongoSession *MongoSession) *MongoSession {
+	if len(s.MongoStore.Redact) == 0 || mongoSession == nil {
+		return mongoSession
+	}
+
+	redacted := *mongoSession
+	redacted.Data = s.redactData("", mongoSession.Data)
+	if mongoSession.Namespaces != nil {
+		redacted.Namespaces = make(map[string]primitive.M, len(mongoSession.Namespaces))
+		for name, ns := range mongoSession.Namespaces {
+			redacted.Namespaces[name] = s.redactData(name+".", ns)
+		}
+	}
+	return &redacted
+}
+
+// redactData returns a copy of the data with the redacted values replaced,
+// the keys being under the prefix.
+func (s *Store) redactData(prefix string, data primitive.M) primitive.M {
+	if data == nil {
+		return nil
+	}
+
+	redacted := make(primitive.M, len(data))
+	for k, v := range data {
+		key := prefix + k
+		switch {
+		case s.redacted(key):
+			redacted[k] = RedactedValue
+		default:
+			if doc, ok := documentMap(v); ok {
+				v = s.redactData(key+".", doc)
+			}
+			redacted[k] = v
+		}
+	}
+	return redacted
+}
+
+// redacted reports if the key matches a glob of Options.Redact.
+func (s *Store) redacted(key string) bool {
+	for _, glob := range s.MongoStore.Redact {
+		if ok, _ := path.Match(glob, key); ok {
+			return true
+		}
+	}
+	return false
+}
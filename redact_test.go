@@ -0,0 +1,89 @@
+package mongostore_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestRedact(t *testing.T) {
+	store := newTestStore(t, "sessions_redact_test")
+	store.MongoStore.Redact = []string{"*_token", "oauth.secret", "auth.password"}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(session, "redact-user")
+	session.Values["name"] = "alice"
+	session.Values["access_token"] = "secret-token"
+	session.Values["oauth"] = map[string]interface{}{"secret": "secret-value", "scope": "email"}
+	auth, err := store.Namespace(session, "auth")
+	if err != nil {
+		t.Fatalf("failed to get namespace: %v\n", err)
+	}
+	auth.Set("user", "gopher")
+	auth.Set("password", "hunter2")
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	sessions, err := store.ListSessions(context.TODO(), mongostore.ListOptions{})
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v\n", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("expected 1 session, got %d", len(sessions))
+	}
+
+	redacted := store.Redact(sessions[0])
+	if redacted.Data["access_token"] != mongostore.RedactedValue {
+		t.Fatalf("expected the access token redacted, got %v", redacted.Data["access_token"])
+	}
+	if redacted.Data["name"] != "alice" {
+		t.Fatalf("expected the name, got %v", redacted.Data["name"])
+	}
+	oauth, _ := redacted.Data["oauth"].(primitive.M)
+	if oauth["secret"] != mongostore.RedactedValue || oauth["scope"] != "email" {
+		t.Fatalf("expected the nested secret redacted, got %v", oauth)
+	}
+
+	// the keys of namespaces are matched as namespace.key
+	ns := redacted.Namespaces["auth"]
+	if ns["password"] != mongostore.RedactedValue || ns["user"] != "gopher" {
+		t.Fatalf("expected the namespaced password redacted, got %v", ns)
+	}
+
+	// the stored session is left intact
+	if sessions[0].Data["access_token"] != "secret-token" {
+		t.Fatalf("expected the stored access token, got %v", sessions[0].Data["access_token"])
+	}
+
+	// the admin views are redacted
+	req = httptest.NewRequest("GET", "/sessions", nil)
+	res = httptest.NewRecorder()
+	store.AdminHandler().ServeHTTP(res, req)
+
+	var page struct {
+		Sessions []struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"sessions"`
+	}
+	err = json.Unmarshal(res.Body.Bytes(), &page)
+	if err != nil {
+		t.Fatalf("failed to decode response: %v\n", err)
+	}
+	if len(page.Sessions) != 1 || page.Sessions[0].Data["access_token"] != mongostore.RedactedValue {
+		t.Fatalf("expected the listed access token redacted, got %+v", page.Sessions)
+	}
+}
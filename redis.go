@@ -0,0 +1,146 @@
+package mongostore
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RedisReader is the part of a Redis client MigrateRedistore needs, adapt
+// the client of the application to it.
+type RedisReader interface {
+	// Keys returns the keys matching the pattern, with KEYS or SCAN.
+	Keys(ctx context.Context, pattern string) ([]string, error)
+
+	// Get returns the value of the key.
+	Get(ctx context.Context, key string) ([]byte, error)
+
+	// TTL returns the time to live of the key, a negative duration when the
+	// key has no expiry.
+	TTL(ctx context.Context, key string) (time.Duration, error)
+}
+
+// RedisSerializer is the serializer redistore was configured with.
+type RedisSerializer int
+
+const (
+	// RedisGob is the redistore GobSerializer, the default.
+	RedisGob RedisSerializer = iota
+
+	// RedisJSON is the redistore JSONSerializer.
+	RedisJSON
+)
+
+// MigrateRedistore copies the sessions stored by gorilla redistore under
+// keyPrefix ("session_" by default in redistore) to the collection of the
+// store, so users stay logged in when switching stores.
+//
+// The cookies hold the redistore session ids, which are kept when the
+// IDGenerator of the store accepts them: use Base32IDGenerator, and the same
+// keys. Sessions keep their remaining time to live. Running the migration
+// again overwrites the migrated sessions.
+func (s *Store) MigrateRedistore(ctx context.Context, client RedisReader, keyPrefix string, serializer RedisSerializer) (MigrationResult, error) {
+	var result MigrationResult
+
+	keys, err := client.Keys(ctx, keyPrefix+"*")
+	if err != nil {
+		return result, fmt.Errorf("mongostore: listing redis sessions: %w", err)
+	}
+
+	for _, key := range keys {
+		sessionID := strings.TrimPrefix(key, keyPrefix)
+
+		id, err := s.documentID(sessionID)
+		if err != nil {
+			s.log(ctx, slog.LevelWarn, "session id not valid for the id generator", s.sessionIDAttr(sessionID), errorAttr(err))
+			result.Failed++
+			continue
+		}
+
+		b, err := client.Get(ctx, key)
+		if err != nil {
+			return result, fmt.Errorf("mongostore: reading redis session %s: %w", key, err)
+		}
+
+		// the key expired since it was listed
+		if b == nil {
+			result.Expired++
+			continue
+		}
+
+		ttl, err := client.TTL(ctx, key)
+		if err != nil {
+			return result, fmt.Errorf("mongostore: reading time to live of %s: %w", key, err)
+		}
+		if ttl < 0 {
+			ttl = time.Duration(s.ttlSeconds()) * time.Second
+		}
+
+		data, err := s.decodeRedisValues(b, serializer)
+		if err != nil {
+			s.log(ctx, slog.LevelWarn, "decoding values of session", s.sessionIDAttr(sessionID), errorAttr(err))
+			result.Failed++
+			continue
+		}
+
+		now := s.now()
+		expires := now.Add(ttl)
+		mongoSession := &MongoSession{
+			ID:       id,
+			Data:     data,
+			Modified: primitive.NewDateTimeFromTime(now),
+			Expires:  primitive.NewDateTimeFromTime(expires),
+			TTL:      primitive.NewDateTimeFromTime(expires.Add(-time.Duration(s.ttlSeconds()) * time.Second)),
+			Created:  primitive.NewDateTimeFromTime(now),
+		}
+
+		err = s.migrateSession(ctx, mongoSession)
+		if err != nil {
+			return result, err
+		}
+		result.Migrated++
+	}
+
+	return result, nil
+}
+
+// decodeRedisValues decodes the session values serialized by redistore.
+func (s *Store) decodeRedisValues(b []byte, serializer RedisSerializer) (primitive.M, error) {
+	data := make(primitive.M)
+
+	switch serializer {
+	case RedisJSON:
+		var values map[string]interface{}
+		err := json.Unmarshal(b, &values)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range values {
+			data[k] = v
+		}
+
+	default:
+		var values map[interface{}]interface{}
+		err := gob.NewDecoder(bytes.NewReader(b)).Decode(&values)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range values {
+			key, ok := s.encodeKey(k)
+			if !ok {
+				s.log(s.MongoStore.Context, slog.LevelWarn, "skipping session key, register its type to store it", slog.Any("key", k), slog.String("type", fmt.Sprintf("%T", k)))
+				continue
+			}
+			data[key] = s.encodeValue(v)
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,22 @@
+package mongostore
+
+import (
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// replicate runs a write against the secondary collection, if there is one.
+//
+// The primary collection is the source of truth: a failed write to the
+// secondary is logged and does not fail the request.
+func (s *Store) replicate(write func(col *mongo.Collection) error) {
+	if s.MongoStore.Secondary == nil {
+		return
+	}
+
+	err := write(s.MongoStore.Secondary)
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelError, "writing to secondary collection", errorAttr(err))
+	}
+}
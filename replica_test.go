@@ -0,0 +1,59 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSecondaryCollection(t *testing.T) {
+	store := newTestStore(t, "sessions_primary_test")
+	secondary := mongoclient.Database("test-database").Collection("sessions_secondary_test")
+	err := secondary.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+	store.MongoStore.Secondary = secondary
+
+	// writes go to both collections
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	count, err := secondary.CountDocuments(context.TODO(), bson.M{"data.test": "testdata"})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected the session in the secondary collection, got %d sessions", count)
+	}
+
+	// reads fall back to the secondary collection
+	_, err = store.MongoStore.Collection.DeleteMany(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to delete sessions: %v\n", err)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Add("Cookie", res.Header()["Set-Cookie"][0])
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the session from the secondary collection, got %v", session.Values)
+	}
+}
@@ -0,0 +1,112 @@
+package mongostore
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// defaultBreakerCooldown is how long the circuit breaker stays open when
+// Options.BreakerCooldown is zero.
+const defaultBreakerCooldown = 30 * time.Second
+
+// breaker is a circuit breaker counting consecutive failed operations.
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// open reports if operations should fail fast.
+func (b *breaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return time.Now().Before(b.openUntil)
+}
+
+// success closes the breaker.
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// failure counts a failed operation and opens the breaker for cooldown once
+// threshold consecutive operations failed.
+func (b *breaker) failure(threshold int, cooldown time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if threshold > 0 && b.failures >= threshold {
+		b.openUntil = time.Now().Add(cooldown)
+	}
+}
+
+// isRetryable reports if an operation that failed with err may succeed when
+// tried again.
+func isRetryable(err error) bool {
+	if isUnavailable(err) {
+		return true
+	}
+
+	var labeled interface{ HasErrorLabel(string) bool }
+	return errors.As(err, &labeled) && labeled.HasErrorLabel("RetryableWriteError")
+}
+
+// retry runs a mongo operation, retrying transient errors with jittered
+// exponential backoff, and fails fast with ErrStoreUnavailable while the
+// circuit breaker is open.
+func (s *Store) retry(op func() error) error {
+	if s.MongoStore.BreakerThreshold > 0 && s.breaker.open() {
+		return ErrStoreUnavailable
+	}
+
+	backoff := s.MongoStore.RetryBackoff
+	if backoff <= 0 {
+		backoff = 50 * time.Millisecond
+	}
+	maxBackoff := s.MongoStore.RetryMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 2 * time.Second
+	}
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = op()
+		if err == nil {
+			s.breaker.success()
+			return nil
+		}
+
+		// not a mongo outage, the operation itself failed
+		if !isRetryable(err) {
+			return err
+		}
+
+		// out of retries, or the caller gave up
+		if attempt >= s.MongoStore.MaxRetries || s.MongoStore.Context.Err() != nil {
+			break
+		}
+
+		// full jitter: sleep a random duration up to the exponential backoff
+		sleep := backoff << uint(attempt)
+		if sleep <= 0 || sleep > maxBackoff {
+			sleep = maxBackoff
+		}
+		time.Sleep(time.Duration(rand.Int63n(int64(sleep) + 1)))
+		s.count(&s.counters.Retries)
+	}
+
+	cooldown := s.MongoStore.BreakerCooldown
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	s.breaker.failure(s.MongoStore.BreakerThreshold, cooldown)
+
+	return wrapError(ErrStoreUnavailable, err)
+}
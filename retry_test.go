@@ -0,0 +1,43 @@
+package mongostore_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestRetryAndBreaker(t *testing.T) {
+	store := newTestStore(t, "sessions_retry_test")
+	store.MongoStore.Collection = unavailableCollection(t)
+	store.MongoStore.MaxRetries = 2
+	store.MongoStore.RetryBackoff = time.Millisecond
+	// the breaker stays open for the default cooldown
+	store.MongoStore.BreakerThreshold = 1
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	// the retries fail
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if !errors.Is(err, mongostore.ErrStoreUnavailable) {
+		t.Fatalf("expected ErrStoreUnavailable, got %v", err)
+	}
+
+	// the breaker is open, the store fails fast
+	start := time.Now()
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if !errors.Is(err, mongostore.ErrStoreUnavailable) {
+		t.Fatalf("expected ErrStoreUnavailable, got %v", err)
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Fatalf("expected the open breaker to fail fast, took %v", time.Since(start))
+	}
+}
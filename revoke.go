@@ -0,0 +1,110 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// revocation is the revocation epoch of a user or tenant, the sessions it
+// created before RevokedAt are no longer loaded.
+type revocation struct {
+	ID        string             `bson:"_id"`
+	RevokedAt primitive.DateTime `bson:"revoked_at"`
+}
+
+// RevokeUser invalidates the sessions a user logged in to until now, they are
+// no longer loaded even by instances reading a lagging secondary or a cached
+// copy of the session documents. It needs Options.CheckRevocations, the
+// documents themselves are left to expire, see DeleteUserSessions.
+func (s *Store) RevokeUser(ctx context.Context, userID string) error {
+	return s.revoke(ctx, "user:"+userID)
+}
+
+// RevokeTenant invalidates the sessions a tenant created until now, like
+// RevokeUser.
+func (s *Store) RevokeTenant(ctx context.Context, tenantID string) error {
+	return s.revoke(ctx, "tenant:"+tenantID)
+}
+
+// revoke moves the revocation epoch of id to now.
+func (s *Store) revoke(ctx context.Context, id string) error {
+	if !s.MongoStore.CheckRevocations {
+		return errors.New("mongostore: revocations are not checked")
+	}
+
+	_, err := s.revocationCollection().UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"revoked_at": primitive.NewDateTimeFromTime(s.now())}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("mongostore: revoking %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// revoked reports if the user of the stored session revoked their sessions
+// after it was given its owner, or its tenant after it was created. Sessions
+// stored without these times are not revoked.
+func (s *Store) revoked(mongoSession *MongoSession) (bool, error) {
+	if !s.MongoStore.CheckRevocations {
+		return false, nil
+	}
+
+	// sessions saved before owned_at was recorded were owned when created
+	owned := mongoSession.Owned
+	if owned == 0 {
+		owned = mongoSession.Created
+	}
+
+	epochs := make(bson.A, 0, 2)
+	if mongoSession.UserID != "" && owned != 0 {
+		epochs = append(epochs, bson.M{
+			"_id":        "user:" + mongoSession.UserID,
+			"revoked_at": bson.M{"$gte": owned},
+		})
+	}
+	if mongoSession.TenantID != "" && mongoSession.Created != 0 {
+		epochs = append(epochs, bson.M{
+			"_id":        "tenant:" + mongoSession.TenantID,
+			"revoked_at": bson.M{"$gte": mongoSession.Created},
+		})
+	}
+	if len(epochs) == 0 {
+		return false, nil
+	}
+
+	var rev revocation
+	err := s.revocationCollection().FindOne(
+		s.MongoStore.Context,
+		bson.M{"$or": epochs},
+	).Decode(&rev)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("mongostore: checking revocations: %w", err)
+	}
+
+	return true, nil
+}
+
+// revocationCollection returns the collection of the revocation epochs, it
+// is always read from the primary so a revocation applies right away.
+func (s *Store) revocationCollection() *mongo.Collection {
+	col := s.MongoStore.RevocationCollection
+	if col == nil {
+		col = s.MongoStore.Collection.Database().Collection(s.MongoStore.Collection.Name() + ".revocations")
+	}
+
+	return col.Database().Collection(col.Name(), options.Collection().SetReadPreference(readpref.Primary()))
+}
@@ -0,0 +1,116 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRevokeUser(t *testing.T) {
+	store := newTestStore(t, "sessions_revoke_test")
+	store.MongoStore.CheckRevocations = true
+	err := store.MongoStore.Collection.Database().Collection("sessions_revoke_test.revocations").Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop revocations: %v\n", err)
+	}
+
+	// logs in and returns the cookie
+	login := func() string {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		res := httptest.NewRecorder()
+
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to create new session: %v\n", err)
+		}
+		store.SetOwner(session, "user-1")
+		err = store.Save(req, res, session)
+		if err != nil {
+			t.Fatalf("failed to insert session: %v\n", err)
+		}
+		return res.Header().Get("Set-Cookie")
+	}
+
+	// loads the session of the cookie
+	load := func(cookie string) bool {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		req.Header.Set("Cookie", cookie)
+
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to get session: %v\n", err)
+		}
+		return !session.IsNew
+	}
+
+	revoked := login()
+
+	// security revokes the sessions of the user
+	time.Sleep(10 * time.Millisecond)
+	err = store.RevokeUser(context.TODO(), "user-1")
+	if err != nil {
+		t.Fatalf("failed to revoke user: %v\n", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if load(revoked) {
+		t.Fatal("expected the revoked session not to be loaded")
+	}
+
+	// the sessions created after the revocation are loaded
+	if !load(login()) {
+		t.Fatal("expected the new session to be loaded")
+	}
+}
+
+func TestRevokeUserLaterLogin(t *testing.T) {
+	store := newTestStore(t, "sessions_revoke_login_test")
+	store.MongoStore.CheckRevocations = true
+	err := store.MongoStore.Collection.Database().Collection("sessions_revoke_login_test.revocations").Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop revocations: %v\n", err)
+	}
+
+	// an anonymous session created before the revocation
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["cart"] = "book"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	err = store.RevokeUser(context.TODO(), "user-1")
+	if err != nil {
+		t.Fatalf("failed to revoke user: %v\n", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	// the user logs in on it after the revocation
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	store.SetOwner(session, "user-1")
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v\n", err)
+	}
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || store.Owner(session) != "user-1" {
+		t.Fatal("expected the login after the revocation to be loaded")
+	}
+}
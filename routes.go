@@ -0,0 +1,124 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SessionCollection is where the sessions of a name are stored, see
+// Options.SessionCollections.
+type SessionCollection struct {
+	Collection *mongo.Collection
+
+	// MaxAge is the lifetime in seconds of the sessions of the name, and
+	// the expireAfterSeconds of the time to live index of the collection.
+	// The MaxAge of the store is used when it is zero.
+	MaxAge int
+}
+
+// addRoutes creates a store for each of Options.SessionCollections, with
+// the keys of s.
+func (s *Store) addRoutes(keyPairs [][]byte) error {
+	if len(s.MongoStore.SessionCollections) == 0 {
+		return nil
+	}
+
+	s.routes = make(map[string]*Store, len(s.MongoStore.SessionCollections))
+	for name, sc := range s.MongoStore.SessionCollections {
+		if sc.Collection == nil {
+			return fmt.Errorf("mongostore: no collection for sessions %s", name)
+		}
+
+		opts := *s.MongoStore.Options
+		opts.Collection = sc.Collection
+		opts.Secondary = nil
+		opts.SessionCollections = nil
+		opts.KeyProvider = nil
+		opts.GenerateKeys = false
+
+		cookie := s.defaultCookie
+		if sc.MaxAge != 0 {
+			cookie.MaxAge = sc.MaxAge
+		}
+
+		// each store configures the max age of its own codecs, the keys of
+		// a provider are set by the first RefreshKeys
+		var codecs []securecookie.Codec
+		if s.keyProvider() == nil && (opts.Encoder == nil || len(keyPairs) > 0) {
+			var err error
+			codecs, err = codecsFromPairs(keyPairs)
+			if err != nil {
+				return err
+			}
+		}
+
+		route := newStore(&opts, cookie, codecs)
+		route.backend = s.backend
+		route.capabilities = s.capabilities
+		if !opts.SkipIndexCreation {
+			err := route.EnsureIndexes(opts.Context)
+			if err != nil {
+				return err
+			}
+		}
+		route.startJanitor()
+		s.routes[name] = route
+	}
+
+	return nil
+}
+
+// route returns the store of the sessions of the given name.
+func (s *Store) route(name string) *Store {
+	if route, ok := s.routes[name]; ok {
+		return route
+	}
+	return s
+}
+
+// saveRoutes saves the sessions stored by another store with that store,
+// and returns the sessions of s.
+func (s *Store) saveRoutes(r *http.Request, w http.ResponseWriter, list []*sessions.Session) ([]*sessions.Session, error) {
+	if len(s.routes) == 0 {
+		return list, nil
+	}
+
+	var own []*sessions.Session
+	routed := make(map[*Store][]*sessions.Session)
+	for _, session := range list {
+		route := s.route(session.Name())
+		if route == s {
+			own = append(own, session)
+			continue
+		}
+		routed[route] = append(routed[route], session)
+	}
+
+	var firstErr error
+	for route, list := range routed {
+		err := route.SaveAll(r, w, list...)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return own, firstErr
+}
+
+// closeRoutes closes the stores of Options.SessionCollections.
+func (s *Store) closeRoutes(ctx context.Context) error {
+	for _, route := range s.routes {
+		err := route.Close(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
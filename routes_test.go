@@ -0,0 +1,71 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestSessionCollections(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_routes_test")
+	prefs := mongoclient.Database("test-database").Collection("sessions_routes_prefs_test")
+	for _, c := range []interface{ Drop(context.Context) error }{col, prefs} {
+		err := c.Drop(context.TODO())
+		if err != nil {
+			t.Fatalf("failed to drop test collection: %v\n", err)
+		}
+	}
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection: col,
+			SessionCollections: map[string]mongostore.SessionCollection{
+				"prefs": {Collection: prefs, MaxAge: 3600},
+			},
+		},
+		http.Cookie{Path: "/", MaxAge: 240, HttpOnly: true},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create test store: %v\n", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "prefs")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["theme"] = "dark"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	if !strings.Contains(res.Header().Get("Set-Cookie"), "Max-Age=3600") {
+		t.Fatalf("expected the lifetime of the prefs sessions, got %s", res.Header().Get("Set-Cookie"))
+	}
+	if n, _ := prefs.CountDocuments(context.TODO(), bson.M{}); n != 1 {
+		t.Fatalf("expected the session in the prefs collection, got %d", n)
+	}
+	if n, _ := col.CountDocuments(context.TODO(), bson.M{}); n != 0 {
+		t.Fatalf("expected no session in the default collection, got %d", n)
+	}
+
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "prefs")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.Values["theme"] != "dark" {
+		t.Fatalf("expected the stored session, got %v", session.Values)
+	}
+}
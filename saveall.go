@@ -0,0 +1,260 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sessionWrite is the write of a session in the bulk write of SaveAll.
+type sessionWrite struct {
+	session *sessions.Session
+	op      AuditOp
+
+	model   mongo.WriteModel
+	replica mongo.WriteModel // the write of the secondary collection
+
+	id       string             // the cookie id of an inserted session
+	overflow primitive.ObjectID // the overflow chunks of the data
+}
+
+// SaveAll saves several sessions of a request, such as sessions of different
+// names, writing them to mongo with a single bulk write instead of one round
+// trip each. It behaves like calling Save for each session.
+//
+// With Options.ConflictResolver the sessions are saved one by one, a bulk
+// write can not tell which update conflicted.
+func (s *Store) SaveAll(r *http.Request, w http.ResponseWriter, list ...*sessions.Session) error {
+	list, routeErr := s.saveRoutes(r, w, list)
+
+	if s.MongoStore.ConflictResolver != nil {
+		var firstErr error
+		for _, session := range list {
+			err := s.Save(r, w, session)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		if firstErr == nil {
+			firstErr = routeErr
+		}
+		return firstErr
+	}
+
+	var writes []*sessionWrite
+	for _, session := range list {
+		write, err := s.prepareSave(r, w, session, &SaveResult{})
+		if err != nil {
+			return err
+		}
+		if !write {
+			continue
+		}
+
+		sw, err := s.sessionWrite(r, session)
+		if err != nil {
+			return s.finishSave(w, session, err, &SaveResult{})
+		}
+		writes = append(writes, sw)
+	}
+
+	models := make([]mongo.WriteModel, 0, len(writes))
+	replicas := make([]mongo.WriteModel, 0, len(writes))
+	for _, sw := range writes {
+		if sw.model != nil {
+			models = append(models, sw.model)
+			replicas = append(replicas, sw.replica)
+		}
+	}
+
+	var err error
+	if len(models) > 0 {
+		start := time.Now()
+		err = s.writeEvents(r, func(ctx context.Context) ([]SessionEvent, error) {
+			err := s.retry(func() error {
+				_, err := s.writeCollection().BulkWrite(
+					ctx,
+					models,
+					options.BulkWrite().SetOrdered(false),
+				)
+				return err
+			})
+			if err != nil || !s.publishes() {
+				return nil, err
+			}
+			return s.writeSessionEvents(r, writes), nil
+		})
+		if err != nil {
+			err = fmt.Errorf("mongostore: writing sessions: %w", err)
+		} else {
+			s.log(r.Context(), slog.LevelInfo, "sessions written", slog.String("op", "save_all"), slog.Int("count", len(models)), slog.Duration("duration", time.Since(start)))
+			s.replicate(func(col *mongo.Collection) error {
+				_, err := col.BulkWrite(s.MongoStore.Context, replicas, options.BulkWrite().SetOrdered(false))
+				return err
+			})
+		}
+	}
+
+	var firstErr error
+	for _, sw := range writes {
+		saveErr := err
+		if saveErr == nil {
+			saveErr = s.sessionWritten(r, sw)
+		}
+
+		saveErr = s.finishSave(w, sw.session, saveErr, &SaveResult{})
+		if saveErr != nil && firstErr == nil {
+			firstErr = saveErr
+		}
+	}
+
+	if firstErr == nil {
+		firstErr = routeErr
+	}
+
+	return firstErr
+}
+
+// sessionWrite returns the write persisting the session, like persist. The
+// model is nil when there is nothing to write, or the update was queued
+// with Options.WriteBehind.
+func (s *Store) sessionWrite(r *http.Request, session *sessions.Session) (*sessionWrite, error) {
+	sw := &sessionWrite{session: session}
+
+	// a client side session has no id, it is inserted when it outgrows the
+	// cookie
+	isNew := session.IsNew || session.ID == ""
+
+	switch {
+	case session.Options.MaxAge == -1:
+		if session.ID == "" {
+			return sw, nil
+		}
+		s.dropQueued(session.ID)
+		s.forgetWrite(session.ID)
+
+		filter, err := s.sessionFilter(session)
+		if err != nil {
+			return nil, err
+		}
+		sw.op = AuditDelete
+		s.archive(s.MongoStore.Context, filter)
+		if s.MongoStore.SoftDelete > 0 {
+			sw.model = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(s.softDeleteUpdate())
+		} else {
+			sw.model = mongo.NewDeleteOneModel().SetFilter(filter)
+		}
+		sw.replica = sw.model
+
+	case isNew:
+		mongoSession, id, err := s.insertDocument(r, session)
+		if err != nil {
+			return nil, fmt.Errorf("mongostore: inserting session: %w", err)
+		}
+		sw.op = AuditCreate
+		sw.model = mongo.NewInsertOneModel().SetDocument(mongoSession)
+		sw.replica = sw.model
+		sw.id = id
+		sw.overflow = mongoSession.Overflow
+
+	default:
+		// the same session was written moments ago
+		if s.coalesced(session) {
+			return sw, nil
+		}
+
+		queued, err := s.queueUpdate(session)
+		if err != nil {
+			return nil, fmt.Errorf("mongostore: queueing session: %w", err)
+		}
+		sw.op = AuditUpdate
+		if queued {
+			return sw, nil
+		}
+
+		filter, update, overflow, err := s.sessionUpdate(session)
+		if err != nil {
+			return nil, fmt.Errorf("mongostore: updating session: %w", err)
+		}
+		// a session read from the secondary collection is copied back to the primary
+		sw.model = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(s.MongoStore.Secondary != nil)
+		sw.replica = mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true)
+		sw.overflow = overflow
+	}
+
+	return sw, nil
+}
+
+// writeSessionEvents returns the events of the sessions inserted and deleted
+// by the bulk write.
+func (s *Store) writeSessionEvents(r *http.Request, writes []*sessionWrite) []SessionEvent {
+	var events []SessionEvent
+	for _, sw := range writes {
+		if sw.model == nil {
+			continue
+		}
+
+		switch sw.op {
+		case AuditCreate:
+			// the id is set on the session once the write succeeded
+			event := s.sessionEvent(r, sw.session, SessionCreated)
+			event.SessionID = s.auditTokenID(sw.id)
+			events = append(events, event)
+		case AuditDelete:
+			events = append(events, s.sessionEvent(r, sw.session, SessionDestroyed))
+		}
+	}
+	return events
+}
+
+// sessionWritten completes the write of a session once the bulk write
+// succeeded, like persist does after each write.
+func (s *Store) sessionWritten(r *http.Request, sw *sessionWrite) error {
+	session := sw.session
+
+	switch sw.op {
+	case AuditDelete:
+		s.releaseOverflow(session)
+		s.countSave(SaveDelete)
+
+	case AuditCreate:
+		session.ID = sw.id
+		s.deleteOverflow(session, sw.overflow)
+		s.recordShardKey(session)
+		s.recordWrite(session)
+		s.countSave(SaveInsert)
+
+	case AuditUpdate:
+		if sw.model != nil {
+			s.deleteOverflow(session, sw.overflow)
+			s.recordShardKey(session)
+			s.recordWrite(session)
+			s.countSave(SaveUpdate)
+		} else {
+			s.countSave(SaveQueued)
+		}
+
+	default:
+		return nil
+	}
+	s.audit(r, session, sw.op)
+
+	// a new session of a user, or an existing session that was just given an
+	// owner, can push the user over the session limit
+	if (sw.op == AuditCreate && s.Owner(session) != "") || (sw.op == AuditUpdate && ownerChanged(session)) {
+		err := s.enforceSessionLimit(session)
+		if err != nil {
+			return fmt.Errorf("mongostore: enforcing session limit: %w", err)
+		}
+	}
+
+	return nil
+}
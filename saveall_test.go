@@ -0,0 +1,74 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestSaveAll(t *testing.T) {
+	store := newTestStore(t, "sessions_saveall_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	auth, err := store.New(req, "auth")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(auth, "saveall-user")
+	prefs, err := store.New(req, "prefs")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	prefs.Values["theme"] = "dark"
+
+	res := httptest.NewRecorder()
+	err = store.SaveAll(req, res, auth, prefs)
+	if err != nil {
+		t.Fatalf("failed to save sessions: %v\n", err)
+	}
+
+	count, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 2 || len(res.Result().Cookies()) != 2 {
+		t.Fatalf("expected 2 sessions and cookies, got %d and %d", count, len(res.Result().Cookies()))
+	}
+
+	// update one and delete the other
+	for _, cookie := range res.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	auth, err = store.New(req, "auth")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	auth.Options.MaxAge = -1
+	prefs, err = store.New(req, "prefs")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if prefs.Values["theme"] != "dark" {
+		t.Fatalf("expected the saved value, got %v", prefs.Values["theme"])
+	}
+	prefs.Values["theme"] = "light"
+
+	err = store.SaveAll(req, httptest.NewRecorder(), auth, prefs)
+	if err != nil {
+		t.Fatalf("failed to save sessions: %v\n", err)
+	}
+
+	mongoSession := &mongostore.MongoSession{}
+	err = store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Decode(mongoSession)
+	if err != nil {
+		t.Fatalf("failed to find session: %v\n", err)
+	}
+	if mongoSession.UserID != "" || mongoSession.Data["theme"] != "light" {
+		t.Fatalf("expected only the updated prefs session, got %+v", mongoSession)
+	}
+}
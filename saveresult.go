@@ -0,0 +1,75 @@
+package mongostore
+
+import (
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// SaveOp is the write SaveWithResult performed for a session.
+type SaveOp string
+
+const (
+	// SaveInsert inserted a new session.
+	SaveInsert SaveOp = "insert"
+
+	// SaveUpdate updated an existing session.
+	SaveUpdate SaveOp = "update"
+
+	// SaveDelete deleted a session with a MaxAge of -1.
+	SaveDelete SaveOp = "delete"
+
+	// SaveQueued queued the update with Options.WriteBehind.
+	SaveQueued SaveOp = "queued"
+
+	// SaveClientSide kept the whole session in the cookie, with
+	// Options.HybridThreshold.
+	SaveClientSide SaveOp = "client_side"
+
+	// SaveSkipped wrote nothing, the session was unchanged or written
+	// moments ago.
+	SaveSkipped SaveOp = "skipped"
+)
+
+// SaveResult describes what SaveWithResult did.
+type SaveResult struct {
+	Op SaveOp
+
+	// the counts of the mongo write
+	MatchedCount  int64
+	ModifiedCount int64
+	DeletedCount  int64
+
+	// SessionID is the id of the session once saved, empty for a client
+	// side session
+	SessionID string
+
+	// Cookie is the encoded value sent to the client, empty when no cookie
+	// was sent
+	Cookie string
+}
+
+// SaveWithResult saves the session like Save, and returns what was
+// written.
+func (s *Store) SaveWithResult(r *http.Request, w http.ResponseWriter, session *sessions.Session) (*SaveResult, error) {
+	if route := s.route(session.Name()); route != s {
+		return route.SaveWithResult(r, w, session)
+	}
+
+	res := &SaveResult{}
+	write, err := s.prepareSave(r, w, session, res)
+	if err != nil || !write {
+		if err == nil {
+			s.countSave(res.Op)
+		}
+		return res, err
+	}
+
+	err = s.persist(r, session, res)
+	err = s.finishSave(w, session, err, res)
+	if err == nil {
+		s.countSave(res.Op)
+	}
+
+	return res, err
+}
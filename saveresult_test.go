@@ -0,0 +1,52 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestSaveWithResult(t *testing.T) {
+	store := newTestStore(t, "sessions_saveresult_test")
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	result, err := store.SaveWithResult(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if result.Op != mongostore.SaveInsert || result.SessionID != session.ID || result.Cookie == "" {
+		t.Fatalf("expected an insert, got %+v", result)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	session.Values["test"] = "changed"
+	result, err = store.SaveWithResult(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if result.Op != mongostore.SaveUpdate || result.MatchedCount != 1 || result.ModifiedCount != 1 {
+		t.Fatalf("expected an update, got %+v", result)
+	}
+
+	session.Options.MaxAge = -1
+	result, err = store.SaveWithResult(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v\n", err)
+	}
+	if result.Op != mongostore.SaveDelete || result.DeletedCount != 1 {
+		t.Fatalf("expected a delete, got %+v", result)
+	}
+}
@@ -0,0 +1,99 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// JSONSchema returns the $jsonSchema of the session documents, matching
+// MongoSession and the options of the store. Fields the store does not know,
+// such as a shard key, are allowed. The _id is an ObjectId or a string, an
+// IDGenerator storing other types needs its own validator.
+func (s *Store) JSONSchema() bson.M {
+	date := bson.M{"bsonType": "date"}
+	str := bson.M{"bsonType": "string"}
+	object := bson.M{"bsonType": "object"}
+
+	required := bson.A{"_id", "ttl"}
+	if s.MongoStore.OpaqueTokens {
+		required = append(required, "token_hash")
+	}
+
+	return bson.M{
+		"bsonType": "object",
+		"required": required,
+		"properties": bson.M{
+			"_id":         bson.M{"bsonType": bson.A{"objectId", "string"}},
+			"data":        object,
+			"modified_at": date,
+			"expires_at":  date,
+			"ttl":         date,
+			"created_at":  date,
+			"user_id":     str,
+			"owned_at":    date,
+			"tenant_id":   str,
+			"token_hash":  str,
+			"persistent":  bson.M{"bsonType": "bool"},
+			"cookie":      object,
+			"overflow":    bson.M{"bsonType": "objectId"},
+			"ip":          str,
+			"user_agent":  str,
+			"last_seen":   date,
+			"deleted_at":  date,
+			"csrf_token":  str,
+			"oidc_sid":    str,
+			"binding": bson.M{
+				"bsonType": "object",
+				"properties": bson.M{
+					"ip":         str,
+					"user_agent": str,
+				},
+			},
+			"key_expires": bson.M{
+				"bsonType":             "object",
+				"additionalProperties": date,
+			},
+			"ns": bson.M{
+				"bsonType":             "object",
+				"additionalProperties": object,
+			},
+			"version": bson.M{"bsonType": bson.A{"int", "long"}},
+			"key_modified": bson.M{
+				"bsonType":             "object",
+				"additionalProperties": date,
+			},
+		},
+	}
+}
+
+// EnsureSchema creates the session collection with a validator of the
+// JSONSchema and Options.CollectionOptions, or sets the validator of the
+// existing collection, so writes of malformed sessions by other tools are
+// rejected. It needs the createCollection and collMod privileges.
+//
+// EnsureCollections calls it when Options.SchemaValidation is set.
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	validator := bson.M{"$jsonSchema": s.JSONSchema()}
+	opts := []*options.CreateCollectionOptions{options.CreateCollection().SetValidator(validator)}
+	if s.MongoStore.CollectionOptions != nil {
+		opts = []*options.CreateCollectionOptions{s.MongoStore.CollectionOptions, opts[0]}
+	}
+
+	for _, col := range s.sessionCollections() {
+		created, err := createCollection(ctx, col, opts...)
+		if err == nil && !created {
+			err = col.Database().RunCommand(ctx, bson.D{
+				{Key: "collMod", Value: col.Name()},
+				{Key: "validator", Value: validator},
+			}).Err()
+		}
+		if err != nil {
+			return fmt.Errorf("mongostore: setting the schema of %s: %w", col.Name(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestSchemaValidation(t *testing.T) {
+	col := mongoclient.Database("test-database").Collection("sessions_schema_test")
+	err := col.Drop(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to drop test collection: %v\n", err)
+	}
+
+	store, err := mongostore.NewStoreWithOptions(
+		&mongostore.Options{
+			Collection:       col,
+			SchemaValidation: true,
+		},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	// the sessions of the store are valid
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(session, "schema-user")
+	session.Values["test"] = "testdata"
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	// malformed writes of other tools are rejected
+	_, err = col.InsertOne(context.TODO(), bson.M{
+		"_id":     primitive.NewObjectID(),
+		"ttl":     primitive.NewDateTimeFromTime(time.Now()),
+		"user_id": 42,
+	})
+	if err == nil {
+		t.Fatal("expected the malformed session to be rejected")
+	}
+
+	// the validator is set on an existing collection too
+	err = store.EnsureSchema(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to set the schema again: %v\n", err)
+	}
+}
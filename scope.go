@@ -0,0 +1,77 @@
+package mongostore
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// tenantContextKey is the context key of the tenant set with WithTenant.
+type tenantContextKey struct{}
+
+// WithTenant returns a copy of ctx carrying a tenant, the tenant whose
+// sessions SessionsByOwner, FindSessions and DeleteWhere see when
+// Options.TenantFunc is set.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// setTenant records the tenant of the request in the session, when
+// Options.TenantFunc is set.
+func (s *Store) setTenant(r *http.Request, session *sessions.Session) {
+	if s.MongoStore.TenantFunc == nil {
+		return
+	}
+	session.Values[tenantKey] = s.MongoStore.TenantFunc(r)
+}
+
+// tenant returns the tenant of the session, or an empty string.
+func tenant(session *sessions.Session) string {
+	tenantID, _ := session.Values[tenantKey].(string)
+	return tenantID
+}
+
+// scope restricts the filter to the tenant of the session, so a session id
+// of another tenant never matches. Sessions without a tenant only match
+// documents without a tenant_id.
+func (s *Store) scope(session *sessions.Session, filter bson.M) bson.M {
+	return s.scopeTenant(tenant(session), filter)
+}
+
+// scopeTenant restricts the filter to the tenant, like scope.
+func (s *Store) scopeTenant(tenantID string, filter bson.M) bson.M {
+	if s.MongoStore.TenantFunc == nil {
+		return filter
+	}
+
+	if tenantID != "" {
+		filter["tenant_id"] = tenantID
+	} else {
+		filter["tenant_id"] = bson.M{"$exists": false}
+	}
+
+	return filter
+}
+
+// queryScope restricts the filter of a query on the sessions to the live
+// sessions of the tenant of ctx, see WithTenant, like the reads of a single
+// session ignore tombstoned sessions and the sessions of other tenants.
+func (s *Store) queryScope(ctx context.Context, filter bson.M) bson.M {
+	tenantID, _ := ctx.Value(tenantContextKey{}).(string)
+	scope := s.scopeTenant(tenantID, live(bson.M{}))
+
+	if len(filter) == 0 {
+		return scope
+	}
+	return AllOf(filter, scope)
+}
+
+// live restricts the filter to the sessions not tombstoned with
+// Options.SoftDelete.
+func live(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": false}
+	return filter
+}
@@ -0,0 +1,117 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestTenantScope(t *testing.T) {
+	store := newTestStore(t, "sessions_scope_test")
+	store.MongoStore.TenantFunc = func(r *http.Request) string {
+		return r.Header.Get("X-Tenant")
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("X-Tenant", "a")
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	count, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{"tenant_id": "a"})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 session of tenant a, got %d", count)
+	}
+
+	// the session is found for its tenant
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew {
+		t.Fatal("expected the session of tenant a")
+	}
+
+	// but not for another tenant, even with a valid cookie
+	req.Header.Set("X-Tenant", "b")
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatal("expected a new session for tenant b")
+	}
+}
+
+func TestTenantScopeQueries(t *testing.T) {
+	store := newTestStore(t, "sessions_scope_query_test")
+	store.MongoStore.TenantFunc = func(r *http.Request) string {
+		return r.Header.Get("X-Tenant")
+	}
+	store.MongoStore.SoftDelete = 10 * time.Minute
+
+	for _, tenantID := range []string{"a", "a", "b"} {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		req.Header.Set("X-Tenant", tenantID)
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to create new session: %v\n", err)
+		}
+		store.SetOwner(session, "scope-user")
+		err = store.Save(req, httptest.NewRecorder(), session)
+		if err != nil {
+			t.Fatalf("failed to insert session: %v\n", err)
+		}
+	}
+
+	// a tombstoned session of tenant a is not returned
+	_, err := store.MongoStore.Collection.UpdateOne(
+		context.TODO(),
+		bson.M{"tenant_id": "a"},
+		bson.M{"$set": bson.M{"deleted_at": primitive.NewDateTimeFromTime(time.Now())}},
+	)
+	if err != nil {
+		t.Fatalf("failed to tombstone session: %v\n", err)
+	}
+
+	ctx := mongostore.WithTenant(context.TODO(), "a")
+	mongoSessions, err := store.SessionsByOwner(ctx, "scope-user")
+	if err != nil {
+		t.Fatalf("failed to find sessions: %v\n", err)
+	}
+	if len(mongoSessions) != 1 || mongoSessions[0].TenantID != "a" {
+		t.Fatalf("expected the live session of tenant a, got %d sessions", len(mongoSessions))
+	}
+
+	// the sessions of tenant b are left
+	deleted, err := store.DeleteWhere(ctx, mongostore.OwnedBy("scope-user"))
+	if err != nil || deleted != 1 {
+		t.Fatalf("expected 1 session deleted, got %d %v", deleted, err)
+	}
+	cursor, err := store.FindSessions(mongostore.WithTenant(context.TODO(), "b"), mongostore.OwnedBy("scope-user"), mongostore.FindOptions{})
+	if err != nil {
+		t.Fatalf("failed to find sessions: %v\n", err)
+	}
+	defer cursor.Close(context.TODO())
+	if !cursor.Next(context.TODO()) || cursor.Session().TenantID != "b" {
+		t.Fatalf("expected the session of tenant b: %v", cursor.Err())
+	}
+}
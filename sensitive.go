@@ -0,0 +1,174 @@
+package mongostore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sensitiveField holds the encrypted value of a sensitive key in Data.
+const sensitiveField = "_enc"
+
+// MarkSensitive encrypts the values of the given keys of session.Values at
+// rest with Options.SensitiveKeys, using AES-GCM. The other values stay in
+// clear in mongo, to be queried and debugged. Keys of namespaces are not
+// encrypted.
+//
+// Encrypted values are decrypted by any store with the key, whether their
+// key is marked or not. Sessions holding a sensitive value are always
+// stored in mongo, never in the cookie with Options.HybridThreshold. Mark
+// the keys before the store is used, it returns an error if
+// Options.SensitiveKeys is empty.
+func (s *Store) MarkSensitive(keys ...string) error {
+	if len(s.MongoStore.SensitiveKeys) == 0 {
+		return errors.New("mongostore: marking sensitive keys without Options.SensitiveKeys")
+	}
+
+	if s.sensitive == nil {
+		s.sensitive = make(map[string]bool)
+	}
+	for _, key := range keys {
+		// the keys are looked up as they are written to Data
+		k, _ := s.encodeKey(key)
+		s.sensitive[k] = true
+	}
+
+	for _, route := range s.routes {
+		err := route.MarkSensitive(keys...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSensitiveKeys returns ErrWeakKey if a key of Options.SensitiveKeys
+// is not an AES key.
+func checkSensitiveKeys(keys [][]byte) error {
+	for i, key := range keys {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return wrapError(ErrWeakKey, fmt.Errorf(
+				"sensitive key %d is %d bytes, 16, 24 or 32 are required", i+1, len(key),
+			))
+		}
+	}
+	return nil
+}
+
+// sensitiveAEAD returns the AES-GCM cipher of a key of
+// Options.SensitiveKeys.
+func sensitiveAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealSensitive returns the data with the values of the sensitive keys
+// encrypted with the first key of Options.SensitiveKeys. A value that can
+// not be encrypted is left out rather than stored in clear.
+func (s *Store) sealSensitive(data primitive.M) primitive.M {
+	if len(s.sensitive) == 0 {
+		return data
+	}
+
+	sealed := make(primitive.M, len(data))
+	for k, v := range data {
+		if !s.sensitive[k] {
+			sealed[k] = v
+			continue
+		}
+
+		v, err := s.seal(k, v)
+		if err != nil {
+			s.log(s.MongoStore.Context, slog.LevelError, "encrypting sensitive value", slog.String("key", k), errorAttr(err))
+			continue
+		}
+		sealed[k] = v
+	}
+
+	return sealed
+}
+
+// seal encrypts a value, bound to its key so it can not be moved to another
+// key.
+func (s *Store) seal(key string, value interface{}) (primitive.M, error) {
+	t, b, err := bson.MarshalValueWithRegistry(s.registry(), value)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := append([]byte{byte(t)}, b...)
+
+	aead, err := sensitiveAEAD(s.MongoStore.SensitiveKeys[0])
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return primitive.M{
+		sensitiveField: primitive.Binary{Data: aead.Seal(nonce, nonce, plaintext, []byte(key))},
+	}, nil
+}
+
+// openSensitive returns the value of a key of Data, decrypted if it was
+// encrypted as a sensitive value. It reports false if the value can not be
+// decrypted with any key of Options.SensitiveKeys.
+func (s *Store) openSensitive(key string, value interface{}) (interface{}, bool) {
+	doc, ok := documentMap(value)
+	if !ok || len(doc) != 1 {
+		return value, true
+	}
+	sealed, ok := doc[sensitiveField].(primitive.Binary)
+	if !ok {
+		return value, true
+	}
+
+	v, err := s.open(key, sealed.Data)
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelWarn, "decrypting sensitive value", slog.String("key", key), errorAttr(err))
+		return nil, false
+	}
+	return v, true
+}
+
+// open decrypts a sealed value with the keys of Options.SensitiveKeys.
+func (s *Store) open(key string, sealed []byte) (interface{}, error) {
+	for _, k := range s.MongoStore.SensitiveKeys {
+		aead, err := sensitiveAEAD(k)
+		if err != nil {
+			return nil, err
+		}
+		if len(sealed) < aead.NonceSize() {
+			return nil, errors.New("sealed value too short")
+		}
+
+		plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+		if err != nil || len(plaintext) == 0 {
+			continue
+		}
+
+		var v interface{}
+		err = bson.RawValue{Type: bsontype.Type(plaintext[0]), Value: plaintext[1:]}.UnmarshalWithRegistry(s.registry(), &v)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+
+	return nil, errors.New("no key decrypts the value")
+}
@@ -0,0 +1,138 @@
+package mongostore_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMarkSensitive(t *testing.T) {
+	store := newTestStore(t, "sessions_sensitive_test")
+	oldKey := securecookie.GenerateRandomKey(32)
+	store.MongoStore.SensitiveKeys = [][]byte{oldKey}
+	err := store.MarkSensitive("access_token")
+	if err != nil {
+		t.Fatalf("failed to mark sensitive keys: %v\n", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["name"] = "alice"
+	session.Values["access_token"] = "secret-token"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	// only the marked key is encrypted at rest
+	var doc struct {
+		Data bson.M `bson:"data"`
+	}
+	err = store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Decode(&doc)
+	if err != nil {
+		t.Fatalf("failed to find session: %v\n", err)
+	}
+	if doc.Data["name"] != "alice" {
+		t.Fatalf("expected the name in clear, got %v", doc.Data["name"])
+	}
+	sealed, ok := doc.Data["access_token"].(bson.M)
+	if !ok {
+		t.Fatalf("expected the access token encrypted, got %v", doc.Data["access_token"])
+	}
+	if _, ok := sealed["_enc"].(primitive.Binary); !ok {
+		t.Fatalf("expected the encrypted access token, got %v", sealed)
+	}
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.Values["access_token"] != "secret-token" {
+		t.Fatalf("expected the access token decrypted, got %v", session.Values["access_token"])
+	}
+
+	// the values encrypted with the old key are read after a rotation
+	store.MongoStore.SensitiveKeys = [][]byte{securecookie.GenerateRandomKey(32), oldKey}
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.Values["access_token"] != "secret-token" {
+		t.Fatalf("expected the access token decrypted with the old key, got %v", session.Values["access_token"])
+	}
+
+	// a value no key decrypts is left out
+	store.MongoStore.SensitiveKeys = [][]byte{securecookie.GenerateRandomKey(32)}
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if _, ok := session.Values["access_token"]; ok {
+		t.Fatal("expected the access token left out without its key")
+	}
+	if session.Values["name"] != "alice" {
+		t.Fatalf("expected the name, got %v", session.Values["name"])
+	}
+}
+
+func TestMarkSensitiveEscapedKey(t *testing.T) {
+	store := newTestStore(t, "sessions_sensitive_escaped_test")
+
+	// marking needs a key
+	err := store.MarkSensitive("user.ssn")
+	if err == nil {
+		t.Fatal("expected an error without sensitive keys")
+	}
+
+	store.MongoStore.SensitiveKeys = [][]byte{securecookie.GenerateRandomKey(32)}
+	store.MongoStore.HybridThreshold = 4096
+	err = store.MarkSensitive("user.ssn")
+	if err != nil {
+		t.Fatalf("failed to mark sensitive keys: %v\n", err)
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["user.ssn"] = "123-45-6789"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the small session is stored in mongo rather than in the cookie, with
+	// the value of the escaped key encrypted
+	raw, err := store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Raw()
+	if err != nil {
+		t.Fatalf("failed to find session: %v\n", err)
+	}
+	if bytes.Contains(raw, []byte("123-45-6789")) {
+		t.Fatal("expected no cleartext value in the stored session")
+	}
+
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.Values["user.ssn"] != "123-45-6789" {
+		t.Fatalf("expected the value decrypted, got %v", session.Values["user.ssn"])
+	}
+}
@@ -0,0 +1,32 @@
+package mongostore
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+)
+
+// SessionStore is the surface of Store that applications use to load, save
+// and manage sessions. Depend on it instead of *Store to swap in the
+// memstore package in tests, or another backend.
+type SessionStore interface {
+	sessions.Store
+
+	SetOwner(session *sessions.Session, userID string)
+	Owner(session *sessions.Session) string
+	SetPersistent(session *sessions.Session, persistent bool)
+	IsPersistent(session *sessions.Session) bool
+
+	Middleware(name string) func(http.Handler) http.Handler
+
+	ListSessions(ctx context.Context, opts ListOptions) ([]*MongoSession, error)
+	FindSession(ctx context.Context, id string) (*MongoSession, error)
+	DeleteSession(ctx context.Context, id string) error
+	DeleteUserSessions(ctx context.Context, userID string) (int64, error)
+	PurgeExpired(ctx context.Context) (int64, error)
+	CountSessions(ctx context.Context) (int64, error)
+	Stats(ctx context.Context) (Stats, error)
+}
+
+var _ SessionStore = (*Store)(nil)
@@ -0,0 +1,113 @@
+package mongostore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ShardKey is the shard key of a sharded session collection.
+type ShardKey struct {
+	// Field is "_id", "user_id" or "tenant_id".
+	Field string
+
+	// Hashed uses a hashed shard key, which spreads sessions evenly.
+	Hashed bool
+}
+
+// shardKeyValue is the value the shard key had when the session was loaded
+// or last written.
+type shardKeyValue struct {
+	value interface{}
+}
+
+// keys returns the index keys of the shard key.
+func (k ShardKey) keys() bson.D {
+	if k.Hashed {
+		return bson.D{{Key: k.Field, Value: "hashed"}}
+	}
+	return bson.D{{Key: k.Field, Value: 1}}
+}
+
+// shardFilter adds the shard key to the filter of a session, so the
+// operation is sent to a single shard instead of all of them. Reads of a
+// session sharded by user_id go to all shards, as the owner is only known
+// once the session is loaded.
+func (s *Store) shardFilter(session *sessions.Session, filter bson.M) bson.M {
+	switch s.MongoStore.ShardKey.Field {
+	case "user_id":
+		if v, ok := session.Values[shardKeyKey].(shardKeyValue); ok {
+			filter["user_id"] = v.value
+		}
+	case "tenant_id":
+		// the tenant is already in the filter when TenantFunc is set
+		if _, ok := filter["tenant_id"]; !ok {
+			if tenantID := tenant(session); tenantID != "" {
+				filter["tenant_id"] = tenantID
+			}
+		}
+	}
+
+	return filter
+}
+
+// recordShardKey remembers the value of a user_id shard key as it is stored
+// in mongo, a session without an owner has a null shard key.
+func (s *Store) recordShardKey(session *sessions.Session) {
+	if s.MongoStore.ShardKey.Field != "user_id" {
+		return
+	}
+
+	var value interface{}
+	if owner := s.Owner(session); owner != "" {
+		value = owner
+	}
+	session.Values[shardKeyKey] = shardKeyValue{value: value}
+}
+
+// ShardCollection shards the session collection on Options.ShardKey, after
+// creating the shard key index. It needs a sharded cluster and the
+// privileges to run shardCollection.
+//
+// A user_id shard key makes the documents move between shards when the owner
+// of a session changes, which requires MongoDB 4.2 or later.
+func (s *Store) ShardCollection(ctx context.Context) error {
+	key := s.MongoStore.ShardKey
+	if key.Field == "" {
+		return fmt.Errorf("mongostore: no shard key")
+	}
+
+	col := s.MongoStore.Collection
+	err := insertShardKeyIndex(ctx, col, key)
+	if err != nil {
+		return fmt.Errorf("mongostore: adding shard key index: %w", err)
+	}
+
+	err = col.Database().Client().Database("admin").RunCommand(
+		ctx,
+		bson.D{
+			{Key: "shardCollection", Value: col.Database().Name() + "." + col.Name()},
+			{Key: "key", Value: key.keys()},
+		},
+	).Err()
+	if err != nil {
+		return fmt.Errorf("mongostore: sharding %s: %w", col.Name(), err)
+	}
+
+	return nil
+}
+
+// insertShardKeyIndex adds the index backing the shard key, the _id index
+// always exists unless the key is hashed.
+func insertShardKeyIndex(ctx context.Context, col *mongo.Collection, key ShardKey) error {
+	if key.Field == "_id" && !key.Hashed {
+		return nil
+	}
+
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: key.keys()})
+	return err
+}
@@ -0,0 +1,68 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestShardKey(t *testing.T) {
+	store := newTestStore(t, "sessions_shard_test")
+	store.MongoStore.ShardKey = mongostore.ShardKey{Field: "user_id", Hashed: true}
+
+	err := store.EnsureIndexes(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to ensure indexes: %v\n", err)
+	}
+	if !indexNames(t, store)["user_id_hashed"] {
+		t.Fatal("expected the hashed shard key index")
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// the updates find the session by its stored shard key, before and after
+	// a login changes it
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	store.SetOwner(session, "shard-user")
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v\n", err)
+	}
+
+	session.Values["after"] = "login"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v\n", err)
+	}
+
+	count, err := store.MongoStore.Collection.CountDocuments(
+		context.TODO(),
+		bson.M{"user_id": "shard-user", "data.after": "login"},
+	)
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected the updated session, got %d", count)
+	}
+}
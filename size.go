@@ -0,0 +1,76 @@
+package mongostore
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// registry returns the registry used to encode and decode session data.
+func (s *Store) registry() *bsoncodec.Registry {
+	if s.MongoStore.Registry != nil {
+		return s.MongoStore.Registry
+	}
+	return bson.DefaultRegistry
+}
+
+// marshalData encodes the data of a session as it is stored in mongo.
+func (s *Store) marshalData(data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	vw, err := bsonrw.NewBSONValueWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := bson.NewEncoder(vw)
+	if err != nil {
+		return nil, err
+	}
+	err = enc.SetRegistry(s.registry())
+	if err != nil {
+		return nil, err
+	}
+	err = enc.Encode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// documentData returns the Data to store in the session document, and the id
+// of the overflow chunks when Data was moved out of the document. It returns
+// ErrSessionTooLarge when the encoded Data is over Options.MaxDataSize.
+func (s *Store) documentData(session *sessions.Session, expires primitive.DateTime) (primitive.M, primitive.ObjectID, error) {
+	data := s.sealSensitive(s.sessionData(session))
+	if s.MongoStore.MaxDataSize <= 0 && s.MongoStore.OverflowThreshold <= 0 {
+		return data, primitive.NilObjectID, nil
+	}
+
+	raw, err := s.marshalData(data)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+
+	if s.MongoStore.MaxDataSize > 0 && len(raw) > s.MongoStore.MaxDataSize {
+		return nil, primitive.NilObjectID, wrapError(
+			ErrSessionTooLarge,
+			fmt.Errorf("%d bytes, the maximum is %d", len(raw), s.MongoStore.MaxDataSize),
+		)
+	}
+
+	if s.MongoStore.OverflowThreshold > 0 && len(raw) > s.MongoStore.OverflowThreshold {
+		id, err := s.writeOverflow(raw, expires)
+		if err != nil {
+			return nil, primitive.NilObjectID, err
+		}
+		return nil, id, nil
+	}
+
+	return data, primitive.NilObjectID, nil
+}
@@ -0,0 +1,47 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestMaxDataSize(t *testing.T) {
+	store := newTestStore(t, "sessions_size_test")
+	store.MongoStore.MaxDataSize = 1024
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	session.Values["upload"] = strings.Repeat("x", 2048)
+	err = store.Save(req, res, session)
+	if !errors.Is(err, mongostore.ErrSessionTooLarge) {
+		t.Fatalf("expected ErrSessionTooLarge, got %v", err)
+	}
+
+	count, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no session, got %d", count)
+	}
+
+	session.Values["upload"] = "small"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+}
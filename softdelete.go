@@ -0,0 +1,44 @@
+package mongostore
+
+import (
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// softDeleteUpdate returns the update tombstoning a session with
+// Options.SoftDelete, the time to live index removes it once the grace
+// window is over.
+func (s *Store) softDeleteUpdate() bson.M {
+	now := s.now()
+	grace := s.MongoStore.SoftDelete
+
+	// the TTL index removes documents MaxAge seconds after their ttl field
+	ttl := now.Add(grace - time.Duration(s.ttlSeconds())*time.Second)
+
+	set := bson.M{
+		"deleted_at": primitive.NewDateTimeFromTime(now),
+		"ttl":        primitive.NewDateTimeFromTime(ttl),
+	}
+	// the TTL index removes documents at their expires_at
+	if s.MongoStore.ExpireAt {
+		set["expires_at"] = primitive.NewDateTimeFromTime(now.Add(grace))
+	}
+
+	return bson.M{"$set": set}
+}
+
+// releaseOverflow forgets the overflow chunks of a deleted session. They are
+// deleted, unless the session is only tombstoned with Options.SoftDelete,
+// then they stay for inspection and expire with the session.
+func (s *Store) releaseOverflow(session *sessions.Session) {
+	if s.MongoStore.SoftDelete > 0 {
+		delete(session.Values, overflowKey)
+		return
+	}
+
+	s.deleteOverflow(session, primitive.NilObjectID)
+}
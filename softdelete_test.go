@@ -0,0 +1,70 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestSoftDelete(t *testing.T) {
+	store := newTestStore(t, "sessions_softdelete_test")
+	store.MongoStore.SoftDelete = 10 * time.Minute
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	cookie := res.Header().Get("Set-Cookie")
+
+	// logs out
+	session.Options.MaxAge = -1
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to delete session: %v\n", err)
+	}
+
+	// the tombstone is kept
+	count, err := store.MongoStore.Collection.CountDocuments(context.TODO(), bson.M{"deleted_at": bson.M{"$exists": true}})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 tombstoned session, got %d", count)
+	}
+
+	// nor counted as live
+	count, err = store.CountSessions(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	stats, err := store.Stats(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to get stats: %v\n", err)
+	}
+	if count != 0 || stats.Sessions != 0 {
+		t.Fatalf("expected no live session, got %d and %d", count, stats.Sessions)
+	}
+
+	// but not loaded
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Cookie", cookie)
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatal("expected the deleted session not to be loaded")
+	}
+}
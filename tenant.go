@@ -0,0 +1,141 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// CollectionResolver returns the collection holding the sessions of the
+// request, for example the collection of the tenant of the request.
+type CollectionResolver func(r *http.Request) *mongo.Collection
+
+// TenantStore routes sessions to a Store per collection, chosen by a
+// CollectionResolver for each request. The stores are created the first time
+// a collection is used, which creates its indexes. Close closes them.
+type TenantStore struct {
+	resolve  CollectionResolver
+	options  Options
+	cookie   http.Cookie
+	keyPairs [][]byte
+
+	mu     sync.Mutex
+	stores map[string]*tenantStore // by database and collection name
+	closed bool
+}
+
+// tenantStore is the store of a collection of a TenantStore, created under
+// its own lock so creating it does not hold up the other collections.
+type tenantStore struct {
+	mu    sync.Mutex
+	store *Store
+}
+
+// NewTenantStore returns a TenantStore, the stores it creates use a copy of
+// opts with the collection returned by resolve. opts.Collection is ignored.
+func NewTenantStore(resolve CollectionResolver, opts *Options, cookie http.Cookie, keyPairs ...[]byte) *TenantStore {
+	return &TenantStore{
+		resolve:  resolve,
+		options:  *opts,
+		cookie:   cookie,
+		keyPairs: keyPairs,
+		stores:   make(map[string]*tenantStore),
+	}
+}
+
+// Store returns the store of the collection of the request, creating it and
+// its indexes if needed.
+func (t *TenantStore) Store(r *http.Request) (*Store, error) {
+	col := t.resolve(r)
+	if col == nil {
+		return nil, errors.New("mongostore: no collection for the request")
+	}
+
+	name := col.Database().Name() + "." + col.Name()
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, errors.New("mongostore: tenant store closed")
+	}
+	ts, ok := t.stores[name]
+	if !ok {
+		ts = &tenantStore{}
+		t.stores[name] = ts
+	}
+	t.mu.Unlock()
+
+	// only the requests of this collection wait for its store
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.store != nil {
+		return ts.store, nil
+	}
+
+	// a store that failed to create its indexes is created again on the
+	// next request
+	opts := t.options
+	opts.Collection = col
+	s, err := NewStoreWithOptions(&opts, t.cookie, t.keyPairs...)
+	if err != nil {
+		return nil, err
+	}
+	ts.store = s
+
+	return s, nil
+}
+
+// Close closes the stores created so far, see Store.Close. Store returns an
+// error once the tenant store is closed.
+func (t *TenantStore) Close(ctx context.Context) error {
+	t.mu.Lock()
+	t.closed = true
+	stores := make([]*tenantStore, 0, len(t.stores))
+	for _, ts := range t.stores {
+		stores = append(stores, ts)
+	}
+	t.mu.Unlock()
+
+	var errs []error
+	for _, ts := range stores {
+		ts.mu.Lock()
+		if ts.store != nil {
+			err := ts.store.Close(ctx)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		ts.mu.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (t *TenantStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(t, name)
+}
+
+// New returns a session for the given name from the store of the request.
+func (t *TenantStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	s, err := t.Store(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.New(r, name)
+}
+
+// Save saves the session in the store of the request.
+func (t *TenantStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	s, err := t.Store(r)
+	if err != nil {
+		return err
+	}
+	return s.Save(r, w, session)
+}
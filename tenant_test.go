@@ -0,0 +1,113 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestTenantStore(t *testing.T) {
+	db := mongoclient.Database("test-database")
+	for _, name := range []string{"sessions_tenant_a", "sessions_tenant_b"} {
+		err := db.Collection(name).Drop(context.TODO())
+		if err != nil {
+			t.Fatalf("failed to drop test collection: %v\n", err)
+		}
+	}
+
+	tenants := mongostore.NewTenantStore(
+		func(r *http.Request) *mongo.Collection {
+			return db.Collection("sessions_tenant_" + r.Header.Get("X-Tenant"))
+		},
+		&mongostore.Options{},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("X-Tenant", "a")
+	res := httptest.NewRecorder()
+
+	session, err := tenants.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = tenants.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	count, err := db.Collection("sessions_tenant_a").CountDocuments(context.TODO(), bson.M{})
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v\n", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 session for tenant a, got %d", count)
+	}
+
+	// the session of tenant a does not exist for tenant b
+	req.Header.Set("X-Tenant", "b")
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = tenants.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	if !session.IsNew {
+		t.Fatal("expected a new session for tenant b")
+	}
+
+	// the indexes of tenant b were created on first use
+	store, err := tenants.Store(req)
+	if err != nil {
+		t.Fatalf("failed to get store: %v\n", err)
+	}
+	names := indexNames(t, store)
+	if len(names) < 2 {
+		t.Fatalf("expected the indexes of tenant b, got %v", names)
+	}
+}
+
+func TestTenantStoreClose(t *testing.T) {
+	db := mongoclient.Database("test-database")
+
+	tenants := mongostore.NewTenantStore(
+		func(r *http.Request) *mongo.Collection {
+			return db.Collection("sessions_tenant_" + r.Header.Get("X-Tenant"))
+		},
+		&mongostore.Options{},
+		http.Cookie{
+			Path:   "/",
+			MaxAge: 240,
+		},
+		securecookie.GenerateRandomKey(32),
+	)
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("X-Tenant", "a")
+	_, err := tenants.Store(req)
+	if err != nil {
+		t.Fatalf("failed to create store: %v\n", err)
+	}
+
+	err = tenants.Close(context.TODO())
+	if err != nil {
+		t.Fatalf("failed to close tenant store: %v\n", err)
+	}
+
+	// no store is created once closed
+	req.Header.Set("X-Tenant", "b")
+	_, err = tenants.Store(req)
+	if err == nil {
+		t.Fatal("expected an error from a closed tenant store")
+	}
+}
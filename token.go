@@ -0,0 +1,72 @@
+package mongostore
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newToken returns a random 256-bit token, encoded for use in a cookie.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash of a token, as stored in the token_hash field.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionFilter returns the mongo filter that matches the session.
+//
+// With Options.OpaqueTokens session.ID holds the token and the session is
+// found by the hash of the token, otherwise session.ID maps to _id. The filter
+// is scoped to the tenant of the session when Options.TenantFunc is set, and
+// holds the shard key when Options.ShardKey is set.
+func (s *Store) sessionFilter(session *sessions.Session) (bson.M, error) {
+	if s.MongoStore.OpaqueTokens {
+		return s.shardFilter(session, s.scope(session, bson.M{
+			"token_hash": hashToken(session.ID),
+		})), nil
+	}
+
+	id, err := s.documentID(session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.shardFilter(session, s.scope(session, bson.M{
+		"_id": id,
+	})), nil
+}
+
+// insertTokenIndex adds a unique index on token_hash.
+func (s *Store) insertTokenIndex(ctx context.Context, col *mongo.Collection) error {
+	_, err := col.Indexes().CreateOne(
+		ctx,
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "token_hash", Value: 1},
+			},
+			Options: options.Index().
+				SetUnique(true).
+				SetSparse(true),
+		},
+	)
+
+	return err
+}
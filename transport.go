@@ -0,0 +1,204 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/sessions"
+)
+
+// Transport carries the encoded session id between the client and the store.
+type Transport interface {
+	// Get returns the encoded session id sent by the client, if any.
+	Get(r *http.Request, name string) (value string, ok bool)
+
+	// Set sends the encoded session id to the client, an options.MaxAge of -1
+	// means the session was deleted.
+	Set(w http.ResponseWriter, name string, value string, options *sessions.Options)
+}
+
+// CookieTransport carries the session id in a cookie, the default.
+type CookieTransport struct {
+	// Partitioned adds the Partitioned attribute of CHIPS to the cookie, so
+	// a widget embedded in other sites keeps its session once browsers block
+	// third-party cookies. Partitioned cookies are always Secure, and
+	// SameSite=None unless the session options set it.
+	Partitioned bool
+}
+
+// Get returns the value of the cookie called name.
+func (CookieTransport) Get(r *http.Request, name string) (string, bool) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	return c.Value, true
+}
+
+// Set adds a Set-Cookie header to the response.
+func (t CookieTransport) Set(w http.ResponseWriter, name string, value string, options *sessions.Options) {
+	cookie := sessions.NewCookie(name, value, options)
+	if !t.Partitioned {
+		http.SetCookie(w, cookie)
+		return
+	}
+
+	// net/http does not write the Partitioned attribute
+	cookie.Secure = true
+	if cookie.SameSite == 0 {
+		cookie.SameSite = http.SameSiteNoneMode
+	}
+	if v := cookie.String(); v != "" {
+		w.Header().Add("Set-Cookie", v+"; Partitioned")
+	}
+}
+
+// HeaderTransport carries the session id in a custom header, for example
+// X-Session-Token, in both the request and the response.
+type HeaderTransport struct {
+	Header string
+}
+
+// Get returns the value of the header.
+func (t HeaderTransport) Get(r *http.Request, name string) (string, bool) {
+	value := r.Header.Get(t.Header)
+	return value, value != ""
+}
+
+// Set adds the header to the response, it is empty if the session was deleted.
+func (t HeaderTransport) Set(w http.ResponseWriter, name string, value string, options *sessions.Options) {
+	if options.MaxAge < 0 {
+		value = ""
+	}
+	w.Header().Set(t.Header, value)
+}
+
+// BearerTransport reads the session id from an Authorization: Bearer header
+// and sends it back in the ResponseHeader of the response, X-Session-Token
+// if it is not set.
+type BearerTransport struct {
+	ResponseHeader string
+}
+
+// Get returns the bearer token of the request.
+func (t BearerTransport) Get(r *http.Request, name string) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return "", false
+	}
+
+	value := strings.TrimSpace(auth[7:])
+	return value, value != ""
+}
+
+// Set adds the response header, it is empty if the session was deleted.
+func (t BearerTransport) Set(w http.ResponseWriter, name string, value string, options *sessions.Options) {
+	header := t.ResponseHeader
+	if header == "" {
+		header = "X-Session-Token"
+	}
+
+	HeaderTransport{Header: header}.Set(w, name, value, options)
+}
+
+// QueryTransport reads the session id from a query parameter, for signed
+// download links and other URLs opened without the client's headers. It
+// never answers, build the links with Store.Token.
+type QueryTransport struct {
+	// Param is the query parameter, "session_token" if it is not set.
+	Param string
+}
+
+// Get returns the value of the query parameter.
+func (t QueryTransport) Get(r *http.Request, name string) (string, bool) {
+	param := t.Param
+	if param == "" {
+		param = "session_token"
+	}
+
+	value := r.URL.Query().Get(param)
+	return value, value != ""
+}
+
+// Set does nothing, a URL can not be changed by the response.
+func (QueryTransport) Set(w http.ResponseWriter, name string, value string, options *sessions.Options) {
+}
+
+// tokenContextKey is the context key of the token given with WithToken.
+type tokenContextKey struct{}
+
+// WithToken returns a copy of ctx carrying the encoded session id for a
+// ManualTransport.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+// ManualTransport leaves the session id to the caller, for API gateways and
+// mobile backends that carry it in their own protocol. The caller passes it
+// in the request context with WithToken and reads it after Save with
+// Store.Token.
+type ManualTransport struct{}
+
+// Get returns the token given with WithToken.
+func (ManualTransport) Get(r *http.Request, name string) (string, bool) {
+	value, _ := r.Context().Value(tokenContextKey{}).(string)
+	return value, value != ""
+}
+
+// Set does nothing, the caller reads the token with Store.Token.
+func (ManualTransport) Set(w http.ResponseWriter, name string, value string, options *sessions.Options) {
+}
+
+// Token returns the encoded session id of a saved session, as a transport
+// would send it, for a ManualTransport or to build links for a
+// QueryTransport. The token expires with the session's MaxAge.
+func (s *Store) Token(session *sessions.Session) (string, error) {
+	if session.ID == "" {
+		return "", errors.New("mongostore: session not saved")
+	}
+
+	encoded, err := s.encodeID(session.Name(), session.ID, session.Options.MaxAge)
+	if err != nil {
+		return "", fmt.Errorf("mongostore: encoding token: %w", err)
+	}
+	return encoded, nil
+}
+
+// transports returns the configured transports, or the cookie transport.
+func (s *Store) transports() []Transport {
+	if len(s.MongoStore.Transports) > 0 {
+		return s.MongoStore.Transports
+	}
+	return []Transport{CookieTransport{}}
+}
+
+// readTransport returns the encoded session id from the first transport that
+// has one, and remembers that transport in the session so Save answers
+// through it.
+func (s *Store) readTransport(r *http.Request, session *sessions.Session) (string, bool) {
+	for i, t := range s.transports() {
+		value, ok := t.Get(r, session.Name())
+		if ok {
+			session.Values[transportKey] = i
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// writeTransport sends the encoded session id through the transport the
+// session came in on, new sessions use the first transport.
+func (s *Store) writeTransport(w http.ResponseWriter, session *sessions.Session, value string, options *sessions.Options) {
+	transports := s.transports()
+
+	i, _ := session.Values[transportKey].(int)
+	if i >= len(transports) {
+		i = 0
+	}
+
+	transports[i].Set(w, session.Name(), value, options)
+}
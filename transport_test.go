@@ -0,0 +1,142 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestBearerTransport(t *testing.T) {
+	store := newTestStore(t, "sessions_transport_test")
+	store.MongoStore.Transports = []mongostore.Transport{
+		mongostore.CookieTransport{},
+		mongostore.BearerTransport{},
+	}
+
+	// a browser gets a cookie
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	if len(res.Header()["Set-Cookie"]) != 1 {
+		t.Fatal("no cookies. header:", res.Header())
+	}
+
+	// an API client sends the session id as a bearer token
+	value := res.Result().Cookies()[0].Value
+
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req.Header.Set("Authorization", "Bearer "+value)
+	res = httptest.NewRecorder()
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+
+	// and gets it back in a header instead of a cookie
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to update session: %v\n", err)
+	}
+	if len(res.Header()["Set-Cookie"]) != 0 {
+		t.Fatal("expected no cookies. header:", res.Header())
+	}
+	if res.Header().Get("X-Session-Token") == "" {
+		t.Fatal("expected a session token header. header:", res.Header())
+	}
+}
+
+func TestCookieless(t *testing.T) {
+	store := newTestStore(t, "sessions_transport_test")
+	store.MongoStore.Transports = []mongostore.Transport{
+		mongostore.ManualTransport{},
+		mongostore.QueryTransport{Param: "token"},
+	}
+
+	// the session is created without a cookie
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["test"] = "testdata"
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+	if len(res.Header()["Set-Cookie"]) != 0 {
+		t.Fatal("expected no cookies. header:", res.Header())
+	}
+
+	token, err := store.Token(session)
+	if err != nil {
+		t.Fatalf("failed to get token: %v\n", err)
+	}
+
+	// the caller passes the token back
+	req, _ = http.NewRequest("GET", "http://localhost:8080/", nil)
+	req = req.WithContext(mongostore.WithToken(req.Context(), token))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+
+	// or in a download link
+	req, _ = http.NewRequest("GET", "http://localhost:8080/download?token="+url.QueryEscape(token), nil)
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+	if session.IsNew || session.Values["test"] != "testdata" {
+		t.Fatalf("expected the saved session, got %v", session.Values)
+	}
+}
+
+func TestPartitionedCookie(t *testing.T) {
+	store := newTestStore(t, "sessions_transport_test")
+	store.MongoStore.Transports = []mongostore.Transport{
+		mongostore.CookieTransport{Partitioned: true},
+	}
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	header := res.Header().Get("Set-Cookie")
+	for _, attr := range []string{"; Secure", "; SameSite=None", "; Partitioned"} {
+		if !strings.Contains(header, attr) {
+			t.Fatalf("expected %q in the cookie, got %s", attr, header)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package mongostore
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// typed values are stored in Data as a document holding the registered name
+// of the type and the value.
+const (
+	typeField  = "_type"
+	valueField = "_value"
+)
+
+// RegisterType makes the values of the type of value keep their Go type
+// across a save and load, instead of coming back as primitive.M,
+// primitive.DateTime or primitive.A. Keys of session.Values of the type are
+// stored too, as JSON. The name is stored with the value, so it must not
+// change once sessions were saved.
+//
+// The values are encoded and decoded with Options.Registry, which can hold
+// codecs for types the default registry does not handle. Register the types
+// before the store is used, like gob.Register it panics if a name or a type
+// is registered twice.
+func (s *Store) RegisterType(name string, value interface{}) {
+	t := reflect.TypeOf(value)
+	if t == nil {
+		panic("mongostore: registering type of nil value")
+	}
+
+	if s.types == nil {
+		s.types = make(map[string]reflect.Type)
+		s.typeNames = make(map[reflect.Type]string)
+	}
+
+	if registered, ok := s.types[name]; ok && registered != t {
+		panic(fmt.Sprintf("mongostore: registering duplicate types for %q: %s != %s", name, registered, t))
+	}
+	if registered, ok := s.typeNames[t]; ok && registered != name {
+		panic(fmt.Sprintf("mongostore: registering duplicate names for %s: %q != %q", t, registered, name))
+	}
+
+	s.types[name] = t
+	s.typeNames[t] = name
+
+	for _, route := range s.routes {
+		route.RegisterType(name, value)
+	}
+}
+
+// encodeValue wraps the values of registered types with their type name.
+func (s *Store) encodeValue(value interface{}) interface{} {
+	name, ok := s.typeNames[reflect.TypeOf(value)]
+	if !ok {
+		return value
+	}
+
+	return primitive.M{
+		typeField:  name,
+		valueField: value,
+	}
+}
+
+// decodeValue converts the values of registered types back to their Go type,
+// other values are returned as they came from mongo.
+func (s *Store) decodeValue(value interface{}) interface{} {
+	doc, ok := documentMap(value)
+	if !ok || len(doc) != 2 {
+		return value
+	}
+
+	name, _ := doc[typeField].(string)
+	t, ok := s.types[name]
+	if !ok {
+		return value
+	}
+
+	raw, ok := doc[valueField]
+	if !ok {
+		return value
+	}
+
+	bsonType, data, err := bson.MarshalValue(raw)
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelWarn, "encoding value", slog.String("type", name), errorAttr(err))
+		return value
+	}
+
+	typed := reflect.New(t)
+	err = bson.RawValue{Type: bsonType, Value: data}.UnmarshalWithRegistry(s.registry(), typed.Interface())
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelWarn, "decoding value", slog.String("type", name), errorAttr(err))
+		return value
+	}
+
+	return typed.Elem().Interface()
+}
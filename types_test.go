@@ -0,0 +1,69 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type cart struct {
+	Items []string
+	Total int
+}
+
+func TestRegisterType(t *testing.T) {
+	store := newTestStore(t, "sessions_types_test")
+	store.RegisterType("cart", cart{})
+	store.RegisterType("time", time.Time{})
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	session.Values["cart"] = cart{Items: []string{"book"}, Total: 12}
+	session.Values["seen"] = now
+	session.Values["plain"] = "value"
+
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	c, ok := session.Values["cart"].(cart)
+	if !ok || c.Total != 12 || len(c.Items) != 1 || c.Items[0] != "book" {
+		t.Fatalf("expected cart, got %#v", session.Values["cart"])
+	}
+
+	seen, ok := session.Values["seen"].(time.Time)
+	if !ok || !seen.Equal(now) {
+		t.Fatalf("expected %v, got %#v", now, session.Values["seen"])
+	}
+
+	if session.Values["plain"] != "value" {
+		t.Fatalf("expected value, got %#v", session.Values["plain"])
+	}
+}
+
+func TestRegisterTypeDuplicate(t *testing.T) {
+	store := newTestStore(t, "sessions_types_test")
+	store.RegisterType("cart", cart{})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic registering a duplicate name")
+		}
+	}()
+	store.RegisterType("cart", time.Time{})
+}
@@ -0,0 +1,103 @@
+package mongostore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fingerprint returns a hash of everything Save writes for the session, so
+// Save can tell if the session changed since it was loaded.
+func (s *Store) fingerprint(session *sessions.Session) (string, error) {
+	expiries := make(primitive.M)
+	for k, v := range s.keyExpiries(session) {
+		expiries[k] = v
+	}
+
+	raw, err := s.marshalData(bson.D{
+		{Key: "id", Value: session.ID},
+		{Key: "data", Value: canonical(s.sessionData(session))},
+		{Key: "owner", Value: s.Owner(session)},
+		{Key: "persistent", Value: s.IsPersistent(session)},
+		{Key: "cookie", Value: s.cookieAttributes(session)},
+		{Key: "max_age", Value: session.Options.MaxAge},
+		{Key: "csrf_token", Value: csrfToken(session)},
+		{Key: "key_expires", Value: canonical(expiries)},
+		{Key: "ns", Value: canonical(namespaceDocs(s.namespaceData(session)))},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonical sorts the keys of the documents in v, so equal values always
+// encode to the same bytes.
+func canonical(v interface{}) interface{} {
+	var m map[string]interface{}
+	switch doc := v.(type) {
+	case primitive.M:
+		m = doc
+	case map[string]interface{}:
+		m = doc
+	case primitive.A:
+		a := make(primitive.A, len(doc))
+		for i, e := range doc {
+			a[i] = canonical(e)
+		}
+		return a
+	default:
+		return v
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	d := make(bson.D, 0, len(keys))
+	for _, k := range keys {
+		d = append(d, bson.E{Key: k, Value: canonical(m[k])})
+	}
+
+	return d
+}
+
+// markUnchanged records the state of the session, when
+// Options.SkipUnchanged is set.
+func (s *Store) markUnchanged(session *sessions.Session) {
+	if !s.MongoStore.SkipUnchanged {
+		return
+	}
+
+	fp, err := s.fingerprint(session)
+	if err != nil {
+		delete(session.Values, fingerprintKey)
+		return
+	}
+	session.Values[fingerprintKey] = fp
+}
+
+// unchanged reports if the session is the same as when it was loaded or last
+// saved, when Options.SkipUnchanged is set.
+func (s *Store) unchanged(session *sessions.Session) bool {
+	if !s.MongoStore.SkipUnchanged || session.IsNew || mustReissue(session) {
+		return false
+	}
+
+	previous, ok := session.Values[fingerprintKey].(string)
+	if !ok {
+		return false
+	}
+
+	fp, err := s.fingerprint(session)
+	return err == nil && fp == previous
+}
@@ -0,0 +1,52 @@
+package mongostore_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSkipUnchanged(t *testing.T) {
+	store := newTestStore(t, "sessions_unchanged_test")
+	store.MongoStore.SkipUnchanged = true
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	res := httptest.NewRecorder()
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["cart"] = map[string]interface{}{"a": 1, "b": 2}
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to insert session: %v\n", err)
+	}
+
+	// a request only reading the session
+	req.Header.Add("Cookie", res.Header().Get("Set-Cookie"))
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to get session: %v\n", err)
+	}
+
+	res = httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if res.Header().Get("Set-Cookie") != "" {
+		t.Fatal("expected no cookie for an unchanged session")
+	}
+
+	// a request changing the session
+	session.Values["flash"] = "saved"
+	res = httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	if res.Header().Get("Set-Cookie") == "" {
+		t.Fatal("expected a cookie for a changed session")
+	}
+}
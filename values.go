@@ -0,0 +1,148 @@
+package mongostore
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Get returns the session value stored under key as a T.
+//
+// Values loaded from mongo come back with BSON types: numbers as int32, int64
+// or float64, times as primitive.DateTime, documents as primitive.M (or
+// primitive.D with Options.RawData) and arrays as primitive.A. Get converts
+// them to T when it can be done without losing information, so
+// Get[int](session, "count") works whatever the number was decoded as.
+//
+// It returns ErrValueNotFound if there is no value for key, and ErrValueType
+// if the value can not be converted to T.
+func Get[T any](session *sessions.Session, key string) (T, error) {
+	var zero T
+
+	v, ok := session.Values[key]
+	if !ok {
+		return zero, fmt.Errorf("%w: %s", ErrValueNotFound, key)
+	}
+
+	if t, ok := v.(T); ok {
+		return t, nil
+	}
+
+	// T is an interface the value does not implement, or the value is nil
+	target := reflect.TypeOf(&zero).Elem()
+	if v == nil || target.Kind() == reflect.Interface {
+		return zero, fmt.Errorf("%w: %s is %T, not %v", ErrValueType, key, v, target)
+	}
+
+	converted, ok := convertValue(reflect.ValueOf(v), target)
+	if !ok {
+		return zero, fmt.Errorf("%w: %s is %T, not %v", ErrValueType, key, v, target)
+	}
+
+	return converted.Interface().(T), nil
+}
+
+// Set stores value under key in the session.
+func Set[T any](session *sessions.Session, key string, value T) {
+	session.Values[key] = value
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	dateTimeType = reflect.TypeOf(primitive.DateTime(0))
+	documentType = reflect.TypeOf(primitive.D{})
+)
+
+// convertValue converts a BSON decoded value to the target type, it reports
+// false if the conversion would lose information.
+func convertValue(v reflect.Value, target reflect.Type) (reflect.Value, bool) {
+	// unwrap interfaces, the elements of primitive.A and primitive.M
+	for v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	if v.Type() == target {
+		return v, true
+	}
+	if target.Kind() == reflect.Interface {
+		if v.Type().Implements(target) {
+			return v.Convert(target), true
+		}
+		return reflect.Value{}, false
+	}
+
+	switch {
+	// times
+	case v.Type() == dateTimeType && target == timeType:
+		return reflect.ValueOf(v.Interface().(primitive.DateTime).Time()), true
+	case v.Type() == timeType && target == dateTimeType:
+		return reflect.ValueOf(primitive.NewDateTimeFromTime(v.Interface().(time.Time))), true
+
+	// numbers, as long as converting back gives the same number
+	case isNumber(v.Kind()) && isNumber(target.Kind()):
+		converted := v.Convert(target)
+		if converted.Convert(v.Type()).Interface() != v.Interface() {
+			return reflect.Value{}, false
+		}
+		// catch sign flips between signed and unsigned types
+		if isSigned(v.Kind()) && v.Int() < 0 && isUnsigned(target.Kind()) {
+			return reflect.Value{}, false
+		}
+		if isUnsigned(v.Kind()) && isSigned(target.Kind()) && converted.Int() < 0 {
+			return reflect.Value{}, false
+		}
+		return converted, true
+
+	// primitive.D to typed maps, as primitive.M
+	case v.Type() == documentType && target.Kind() == reflect.Map && target.Key().Kind() == reflect.String:
+		m, _ := documentMap(v.Interface())
+		return convertValue(reflect.ValueOf(m), target)
+
+	// primitive.A to typed slices
+	case v.Kind() == reflect.Slice && target.Kind() == reflect.Slice:
+		out := reflect.MakeSlice(target, v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, ok := convertValue(v.Index(i), target.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			out.Index(i).Set(elem)
+		}
+		return out, true
+
+	// primitive.M to typed maps
+	case v.Kind() == reflect.Map && target.Kind() == reflect.Map && v.Type().Key() == target.Key():
+		out := reflect.MakeMapWithSize(target, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, ok := convertValue(iter.Value(), target.Elem())
+			if !ok {
+				return reflect.Value{}, false
+			}
+			out.SetMapIndex(iter.Key(), elem)
+		}
+		return out, true
+	}
+
+	return reflect.Value{}, false
+}
+
+func isNumber(k reflect.Kind) bool {
+	return isSigned(k) || isUnsigned(k) || isFloat(k)
+}
+
+func isSigned(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUnsigned(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
@@ -0,0 +1,66 @@
+package mongostore_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestTypedValues(t *testing.T) {
+	session := sessions.NewSession(nil, "test-session")
+
+	// values as they come back from mongo
+	now := time.Now().Truncate(time.Millisecond)
+	session.Values["count"] = int32(42)
+	session.Values["ratio"] = float64(1.5)
+	session.Values["when"] = primitive.NewDateTimeFromTime(now)
+	session.Values["roles"] = primitive.A{"admin", "user"}
+	session.Values["prefs"] = primitive.M{"theme": "dark"}
+
+	count, err := mongostore.Get[int](session, "count")
+	if err != nil || count != 42 {
+		t.Fatalf("expected 42, got %v %v", count, err)
+	}
+
+	count64, err := mongostore.Get[int64](session, "count")
+	if err != nil || count64 != 42 {
+		t.Fatalf("expected 42, got %v %v", count64, err)
+	}
+
+	when, err := mongostore.Get[time.Time](session, "when")
+	if err != nil || !when.Equal(now) {
+		t.Fatalf("expected %v, got %v %v", now, when, err)
+	}
+
+	roles, err := mongostore.Get[[]string](session, "roles")
+	if err != nil || len(roles) != 2 || roles[0] != "admin" {
+		t.Fatalf("expected roles, got %v %v", roles, err)
+	}
+
+	prefs, err := mongostore.Get[map[string]string](session, "prefs")
+	if err != nil || prefs["theme"] != "dark" {
+		t.Fatalf("expected prefs, got %v %v", prefs, err)
+	}
+
+	// conversions losing information fail
+	_, err = mongostore.Get[int](session, "ratio")
+	if !errors.Is(err, mongostore.ErrValueType) {
+		t.Fatalf("expected ErrValueType, got %v", err)
+	}
+
+	_, err = mongostore.Get[string](session, "missing")
+	if !errors.Is(err, mongostore.ErrValueNotFound) {
+		t.Fatalf("expected ErrValueNotFound, got %v", err)
+	}
+
+	mongostore.Set(session, "name", "gopher")
+	name, err := mongostore.Get[string](session, "name")
+	if err != nil || name != "gopher" {
+		t.Fatalf("expected gopher, got %v %v", name, err)
+	}
+}
@@ -0,0 +1,196 @@
+package mongostore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// WebhookSignatureHeader holds the hex HMAC-SHA256 of the timestamp, a
+	// dot and the body of a webhook request, prefixed with "sha256=".
+	WebhookSignatureHeader = "X-Mongostore-Signature"
+
+	// WebhookTimestampHeader holds the unix time a webhook request was
+	// signed at.
+	WebhookTimestampHeader = "X-Mongostore-Timestamp"
+)
+
+// defaultWebhookRetries is the number of retries of a webhook request when
+// Webhook.Retries is zero.
+const defaultWebhookRetries = 3
+
+// defaultWebhookTimeout is the timeout of a webhook request without
+// Webhook.Client.
+const defaultWebhookTimeout = 10 * time.Second
+
+// Webhook posts the sessions deleted by any instance of the store to a URL,
+// see WatchWebhook. Sessions removed by the time to live index when they
+// expire are deleted like logouts, downstream systems learn about both.
+type Webhook struct {
+	// URL receives a POST with a WebhookEvent as JSON for each session.
+	URL string
+
+	// Secret signs the requests with HMAC-SHA256, the receiver checks them
+	// with VerifyWebhook. Requests are not signed when it is empty.
+	Secret []byte
+
+	// Client sends the requests, a client with a 10 second timeout when
+	// nil.
+	Client *http.Client
+
+	// Retries is the number of times a failed request is retried, with an
+	// exponential backoff. It is 3 when zero, negative disables retries.
+	Retries int
+}
+
+// WebhookEvent is the body of a webhook request.
+type WebhookEvent struct {
+	// Event is "session.deleted", or "session.soft_deleted" for sessions
+	// tombstoned with Options.SoftDelete.
+	Event string `json:"event"`
+
+	// SessionID is the session id as recorded in the audit log, see
+	// DeleteEvent.
+	SessionID string `json:"session_id,omitempty"`
+
+	// At is when the store saw the delete.
+	At time.Time `json:"at"`
+}
+
+// WatchWebhook follows the deletes of sessions like WatchDeletes and
+// notifies the webhook of each one. A request that still fails after the
+// retries is logged and the event is dropped, fn is called for every event
+// like with WatchDeletes and can be nil.
+func (s *Store) WatchWebhook(ctx context.Context, hook *Webhook, fn func(DeleteEvent)) error {
+	return s.WatchDeletes(ctx, func(event DeleteEvent) {
+		err := hook.Notify(ctx, event)
+		if err != nil {
+			s.log(ctx, slog.LevelError, "notifying webhook", s.sessionIDAttr(event.SessionID), errorAttr(err))
+		}
+
+		if fn != nil {
+			fn(event)
+		}
+	})
+}
+
+// Notify posts the delete event to the webhook, retrying failed requests.
+func (h *Webhook) Notify(ctx context.Context, event DeleteEvent) error {
+	name := "session.deleted"
+	if event.Soft {
+		name = "session.soft_deleted"
+	}
+	body, err := json.Marshal(WebhookEvent{
+		Event:     name,
+		SessionID: event.SessionID,
+		At:        time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("mongostore: encoding webhook event: %w", err)
+	}
+
+	retries := h.Retries
+	if retries == 0 {
+		retries = defaultWebhookRetries
+	}
+
+	backoff := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		err = h.post(ctx, body)
+		if err == nil || attempt >= retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one signed webhook request, any status but 2xx fails it.
+func (h *Webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("mongostore: creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(h.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+webhookSignature(h.Secret, timestamp, body))
+	}
+
+	client := h.Client
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("mongostore: posting webhook: %w", err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("mongostore: posting webhook: status %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+// webhookSignature returns the hex HMAC-SHA256 of a webhook request.
+func webhookSignature(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook reads the body of a webhook request and checks that it was
+// signed with the secret less than tolerance ago, to reject forged and
+// replayed requests. It returns ErrWebhookSignature if the check fails.
+func VerifyWebhook(r *http.Request, secret []byte, tolerance time.Duration) (*WebhookEvent, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: reading webhook: %w", err)
+	}
+
+	timestamp := r.Header.Get(WebhookTimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, wrapError(ErrWebhookSignature, fmt.Errorf("bad timestamp %q", timestamp))
+	}
+	age := time.Since(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return nil, wrapError(ErrWebhookSignature, fmt.Errorf("signed %s ago", age.Round(time.Second)))
+	}
+
+	expected := "sha256=" + webhookSignature(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(r.Header.Get(WebhookSignatureHeader))) {
+		return nil, wrapError(ErrWebhookSignature, errors.New("signature mismatch"))
+	}
+
+	event := &WebhookEvent{}
+	err = json.Unmarshal(body, event)
+	if err != nil {
+		return nil, fmt.Errorf("mongostore: decoding webhook: %w", err)
+	}
+
+	return event, nil
+}
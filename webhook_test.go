@@ -0,0 +1,68 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestWebhook(t *testing.T) {
+	secret := []byte("webhook-secret")
+
+	attempts := 0
+	events := make(chan *mongostore.WebhookEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		// the first request fails and is retried
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		event, err := mongostore.VerifyWebhook(r, secret, time.Minute)
+		if err != nil {
+			t.Errorf("failed to verify webhook: %v\n", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		events <- event
+	}))
+	defer server.Close()
+
+	hook := &mongostore.Webhook{URL: server.URL, Secret: secret}
+	err := hook.Notify(context.Background(), mongostore.DeleteEvent{SessionID: "abc"})
+	if err != nil {
+		t.Fatalf("failed to notify webhook: %v\n", err)
+	}
+
+	event := <-events
+	if event.Event != "session.deleted" || event.SessionID != "abc" || attempts != 2 {
+		t.Fatalf("unexpected event %+v after %d attempts", event, attempts)
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := mongostore.VerifyWebhook(r, []byte("other-secret"), time.Minute)
+		if !errors.Is(err, mongostore.ErrWebhookSignature) {
+			t.Errorf("expected ErrWebhookSignature, got %v", err)
+		}
+		received = r
+	}))
+	defer server.Close()
+
+	hook := &mongostore.Webhook{URL: server.URL, Secret: []byte("webhook-secret"), Retries: -1}
+	err := hook.Notify(context.Background(), mongostore.DeleteEvent{SessionID: "abc", Soft: true})
+	if err != nil {
+		t.Fatalf("failed to notify webhook: %v\n", err)
+	}
+	if received == nil {
+		t.Fatal("webhook not called")
+	}
+}
@@ -0,0 +1,112 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// Handle is the session of a long-lived connection, such as a WebSocket.
+// Cookies can not be written once the connection is upgraded, so instead of
+// Save the connection checks with Validate or Watch that the session was not
+// deleted or expired in mongo while it is open.
+type Handle struct {
+	store *Store
+	name  string
+
+	mu      sync.Mutex
+	session *sessions.Session
+}
+
+// Handshake resolves the session of the given name from the handshake
+// request of a long-lived connection, before it is upgraded. It returns
+// ErrSessionNotFound if the request has no stored session.
+func (s *Store) Handshake(r *http.Request, name string) (*Handle, error) {
+	s = s.route(name)
+
+	session, err := s.New(r, name)
+	if err != nil {
+		return nil, err
+	}
+	if session.IsNew {
+		return nil, ErrSessionNotFound
+	}
+
+	return &Handle{store: s, name: name, session: session}, nil
+}
+
+// Session returns the session as of the handshake or the last successful
+// Validate. Changes to it are not saved.
+func (h *Handle) Session() *sessions.Session {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.session
+}
+
+// Validate reloads the session from mongo. It returns ErrSessionNotFound if
+// the session was deleted, by a logout or an admin, and ErrSessionExpired if
+// it expired.
+//
+// Sessions kept in the cookie with Options.HybridThreshold are not in mongo
+// and only expire with the cookie, Validate always succeeds for them.
+func (h *Handle) Validate() error {
+	current := h.Session()
+	if current.ID == "" {
+		return nil
+	}
+
+	session := h.store.storedSession(current)
+	err := h.store.findOne(session)
+	if err == nil {
+		err = h.store.fillOverflow(session)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.session = session
+	h.mu.Unlock()
+
+	return nil
+}
+
+// Watch validates the session every interval until ctx is done. The
+// returned channel receives the error once the session is deleted or
+// expired, and is closed when Watch stops. Mongo being unavailable does not
+// end the session, the next check tries again.
+func (h *Handle) Watch(ctx context.Context, interval time.Duration) <-chan error {
+	revoked := make(chan error, 1)
+
+	go func() {
+		defer close(revoked)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			err := h.Validate()
+			if errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrSessionExpired) {
+				revoked <- err
+				return
+			}
+			if err != nil {
+				h.store.log(ctx, slog.LevelWarn, "validating session", slog.String("name", h.name), errorAttr(err))
+			}
+		}
+	}()
+
+	return revoked
+}
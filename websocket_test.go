@@ -0,0 +1,62 @@
+package mongostore_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestHandshake(t *testing.T) {
+	store := newTestStore(t, "sessions_websocket_test")
+
+	// no session
+	req, _ := http.NewRequest("GET", "http://localhost:8080/ws", nil)
+	_, err := store.Handshake(req, "test-session")
+	if !errors.Is(err, mongostore.ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	store.SetOwner(session, "websocket-user")
+	res := httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+	handle, err := store.Handshake(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to resolve session: %v\n", err)
+	}
+	if store.Owner(handle.Session()) != "websocket-user" {
+		t.Fatalf("expected the session of websocket-user, got %q", store.Owner(handle.Session()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	revoked := handle.Watch(ctx, 10*time.Millisecond)
+
+	// deleting the session from elsewhere ends the connection
+	_, err = store.DeleteUserSessions(context.Background(), "websocket-user")
+	if err != nil {
+		t.Fatalf("failed to delete sessions: %v\n", err)
+	}
+
+	select {
+	case err := <-revoked:
+		if !errors.Is(err, mongostore.ErrSessionNotFound) {
+			t.Fatalf("expected ErrSessionNotFound, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the session to be revoked")
+	}
+}
@@ -0,0 +1,342 @@
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// defaultWriteBehindInterval is how often queued updates are written when
+// Options.WriteBehindInterval is zero.
+const defaultWriteBehindInterval = time.Second
+
+// writeBehindBatch is the number of queued updates written without waiting
+// for the interval.
+const writeBehindBatch = 500
+
+// writeBehind queues the updates of existing sessions with
+// Options.WriteBehind.
+type writeBehind struct {
+	mu        sync.Mutex
+	pending   map[string]queuedUpdate // by session id, the last update wins
+	inflight  map[string]queuedUpdate // being written
+	cancelled map[string]bool         // inflight sessions deleted meanwhile
+	written   *sync.Cond              // signaled when the inflight updates are written
+	started   bool
+	closed    bool
+
+	full    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// queuedUpdate is the update of a session waiting to be written.
+type queuedUpdate struct {
+	filter interface{}
+	update interface{}
+
+	// session is the $set of the update, served to reads until it is
+	// written
+	session *MongoSession
+}
+
+// queueUpdate queues the update of an existing session with
+// Options.WriteBehind, it reports false if the session has to be written
+// now. Sessions that change owner are written now to enforce the session
+// limit, sessions with namespaces are written now as their update sets the
+// namespaces one by one, and so are all sessions when their data can
+// overflow. With Options.ConflictResolver each update is checked, and
+// written now.
+func (s *Store) queueUpdate(session *sessions.Session) (bool, error) {
+	if !s.MongoStore.WriteBehind || s.MongoStore.OverflowThreshold > 0 || s.MongoStore.ConflictResolver != nil || ownerChanged(session) || namespaces(session) != nil {
+		return false, nil
+	}
+
+	filter, update, _, err := s.sessionUpdate(session)
+	if err != nil {
+		return false, err
+	}
+
+	wb := &s.writeBehind
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	if wb.closed {
+		return false, nil
+	}
+	if !wb.started {
+		wb.started = true
+		wb.pending = make(map[string]queuedUpdate)
+		wb.written = sync.NewCond(&wb.mu)
+		wb.full = make(chan struct{}, 1)
+		wb.stop = make(chan struct{})
+		wb.stopped = make(chan struct{})
+		go s.runWriteBehind()
+	}
+
+	wb.pending[session.ID] = queuedUpdate{
+		filter:  filter,
+		update:  update,
+		session: update["$set"].(*MongoSession),
+	}
+	if len(wb.pending) >= writeBehindBatch {
+		select {
+		case wb.full <- struct{}{}:
+		default:
+		}
+	}
+
+	s.recordShardKey(session)
+	s.recordWrite(session)
+
+	return true, nil
+}
+
+// queuedSession returns the session as it will be once its queued update is
+// written, or nil if no update is queued.
+func (s *Store) queuedSession(id string) *MongoSession {
+	wb := &s.writeBehind
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	if q, ok := wb.pending[id]; ok {
+		return q.session
+	}
+	if q, ok := wb.inflight[id]; ok {
+		return q.session
+	}
+	return nil
+}
+
+// dropQueued removes the queued update of a deleted session. An update
+// being written is not queued again if it fails, and dropQueued waits for
+// it so the delete lands after it: with Options.Secondary the update is an
+// upsert that would bring the deleted session back.
+func (s *Store) dropQueued(id string) {
+	wb := &s.writeBehind
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	delete(wb.pending, id)
+	if _, ok := wb.inflight[id]; !ok {
+		return
+	}
+
+	wb.cancelled[id] = true
+	for {
+		if _, ok := wb.inflight[id]; !ok {
+			return
+		}
+		wb.written.Wait()
+	}
+}
+
+// runWriteBehind writes the queued updates every interval, or when the batch
+// is full, until Close.
+func (s *Store) runWriteBehind() {
+	wb := &s.writeBehind
+	defer close(wb.stopped)
+
+	interval := s.MongoStore.WriteBehindInterval
+	if interval <= 0 {
+		interval = defaultWriteBehindInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-wb.full:
+		case <-wb.stop:
+			return
+		}
+
+		_ = s.flushWriteBehind()
+	}
+}
+
+// flushWriteBehind writes the queued updates with a bulk write. The updates
+// that failed with a transient error are queued again, unless a newer one
+// was queued since or the session was deleted, the others are dropped.
+func (s *Store) flushWriteBehind() error {
+	wb := &s.writeBehind
+	wb.mu.Lock()
+	pending := wb.pending
+	wb.pending = make(map[string]queuedUpdate)
+	wb.inflight = pending
+	wb.cancelled = make(map[string]bool)
+	wb.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(pending))
+	models := make([]mongo.WriteModel, 0, len(pending))
+	upserts := make([]mongo.WriteModel, 0, len(pending))
+	for id, q := range pending {
+		ids = append(ids, id)
+		// a session read from the secondary collection is copied back to the primary
+		models = append(models, mongo.NewUpdateOneModel().SetFilter(q.filter).SetUpdate(q.update).SetUpsert(s.MongoStore.Secondary != nil))
+		upserts = append(upserts, mongo.NewUpdateOneModel().SetFilter(q.filter).SetUpdate(q.update).SetUpsert(true))
+	}
+
+	start := time.Now()
+	err := s.retry(func() error {
+		_, err := s.writeCollection().BulkWrite(
+			s.MongoStore.Context,
+			models,
+			options.BulkWrite().SetOrdered(false),
+		)
+		return err
+	})
+
+	retry, dropped := s.failedUpdates(ids, err)
+
+	wb.mu.Lock()
+	for _, id := range retry {
+		if _, ok := wb.pending[id]; !ok && !wb.cancelled[id] {
+			wb.pending[id] = pending[id]
+		}
+	}
+	wb.inflight = nil
+	wb.cancelled = nil
+	wb.written.Broadcast()
+	wb.mu.Unlock()
+
+	for id, dropErr := range dropped {
+		s.log(s.MongoStore.Context, slog.LevelError, "dropping queued session", s.sessionIDAttr(id), errorAttr(dropErr))
+	}
+	if err != nil {
+		s.log(s.MongoStore.Context, slog.LevelError, "writing queued sessions", slog.Int("count", len(models)), slog.Int("retried", len(retry)), errorAttr(err))
+		return fmt.Errorf("mongostore: writing queued sessions: %w", err)
+	}
+	s.log(s.MongoStore.Context, slog.LevelInfo, "queued sessions written", slog.String("op", "write_behind"), slog.Int("count", len(models)), slog.Duration("duration", time.Since(start)))
+
+	// upsert so the secondary catches up on sessions created before it was added
+	s.replicate(func(col *mongo.Collection) error {
+		_, err := col.BulkWrite(s.MongoStore.Context, upserts, options.BulkWrite().SetOrdered(false))
+		return err
+	})
+
+	return nil
+}
+
+// failedUpdates returns the sessions whose queued update failed with a
+// transient error and is written again, and the errors of the updates that
+// failed for good, such as a document too large or failing validation. The
+// ids are in the order of the models of the bulk write.
+func (s *Store) failedUpdates(ids []string, err error) (retry []string, dropped map[string]error) {
+	if err == nil {
+		return nil, nil
+	}
+	dropped = make(map[string]error)
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) {
+		// nothing was written, or the outcome is unknown
+		if isRetryable(err) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return ids, nil
+		}
+		for _, id := range ids {
+			dropped[id] = err
+		}
+		return nil, dropped
+	}
+
+	failed := make(map[int]bool, len(bulkErr.WriteErrors))
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Index < 0 || writeErr.Index >= len(ids) {
+			continue
+		}
+		failed[writeErr.Index] = true
+
+		id := ids[writeErr.Index]
+		if bulkErr.HasErrorLabel("RetryableWriteError") || retryableWriteCodes[writeErr.Code] {
+			retry = append(retry, id)
+		} else {
+			dropped[id] = writeErr
+		}
+	}
+
+	// the updates were applied without the write concern, writing them
+	// again is harmless
+	if bulkErr.WriteConcernError != nil {
+		for i, id := range ids {
+			if !failed[i] {
+				retry = append(retry, id)
+			}
+		}
+	}
+
+	return retry, dropped
+}
+
+// retryableWriteCodes are the server errors of a write that can succeed
+// when tried again, during an election or a shutdown.
+var retryableWriteCodes = map[int]bool{
+	6:     true, // HostUnreachable
+	7:     true, // HostNotFound
+	89:    true, // NetworkTimeout
+	91:    true, // ShutdownInProgress
+	189:   true, // PrimarySteppedDown
+	262:   true, // ExceededTimeLimit
+	9001:  true, // SocketException
+	10107: true, // NotWritablePrimary
+	11600: true, // InterruptedAtShutdown
+	11602: true, // InterruptedDueToReplStateChange
+	13435: true, // NotPrimaryNoSecondaryOk
+	13436: true, // NotPrimaryOrSecondary
+}
+
+// closeFlushes bounds the flushes of Close writing the updates queued again
+// after a transient error.
+const closeFlushes = 3
+
+// closeWriteBehind stops the worker and writes the queued updates, later
+// saves are written synchronously. The updates still failing after
+// closeFlushes flushes are logged and dropped.
+func (s *Store) closeWriteBehind() error {
+	wb := &s.writeBehind
+	wb.mu.Lock()
+	started := wb.started && !wb.closed
+	wb.closed = true
+	wb.mu.Unlock()
+
+	if started {
+		close(wb.stop)
+		<-wb.stopped
+	}
+
+	var err error
+	for i := 0; i < closeFlushes; i++ {
+		err = s.flushWriteBehind()
+
+		wb.mu.Lock()
+		left := len(wb.pending)
+		wb.mu.Unlock()
+		if left == 0 {
+			return err
+		}
+	}
+
+	wb.mu.Lock()
+	pending := wb.pending
+	wb.pending = make(map[string]queuedUpdate)
+	wb.mu.Unlock()
+
+	for id := range pending {
+		s.log(s.MongoStore.Context, slog.LevelError, "dropping queued session", s.sessionIDAttr(id), errorAttr(err))
+	}
+
+	return err
+}
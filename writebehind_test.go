@@ -0,0 +1,140 @@
+package mongostore_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/glezjose/mongostore"
+)
+
+func TestWriteBehind(t *testing.T) {
+	store := newTestStore(t, "sessions_writebehind_test")
+	store.MongoStore.WriteBehind = true
+	store.MongoStore.WriteBehindInterval = time.Hour
+
+	req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+	session, err := store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to create new session: %v\n", err)
+	}
+	session.Values["foo"] = "bar"
+
+	// new sessions are written by Save
+	res := httptest.NewRecorder()
+	err = store.Save(req, res, session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+	req.Header.Set("Cookie", res.Header().Get("Set-Cookie"))
+
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	session.Values["foo"] = "queued"
+	err = store.Save(req, httptest.NewRecorder(), session)
+	if err != nil {
+		t.Fatalf("failed to save session: %v\n", err)
+	}
+
+	stored := func() string {
+		mongoSession := &mongostore.MongoSession{}
+		err := store.MongoStore.Collection.FindOne(context.TODO(), bson.M{}).Decode(mongoSession)
+		if err != nil {
+			t.Fatalf("failed to find session: %v\n", err)
+		}
+		foo, _ := mongoSession.Data["foo"].(string)
+		return foo
+	}
+
+	// the update is queued, but served to reads
+	if stored() != "bar" {
+		t.Fatal("expected the update to be queued")
+	}
+	session, err = store.New(req, "test-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v\n", err)
+	}
+	if session.Values["foo"] != "queued" {
+		t.Fatalf("expected the queued value, got %v", session.Values["foo"])
+	}
+
+	// Close writes the queue
+	err = store.Close(context.Background())
+	if err != nil {
+		t.Fatalf("failed to close store: %v\n", err)
+	}
+	if stored() != "queued" {
+		t.Fatal("expected the queued update to be written")
+	}
+}
+
+func TestWriteBehindDropsFailedUpdates(t *testing.T) {
+	store := newTestStore(t, "sessions_writebehind_test")
+	store.MongoStore.WriteBehind = true
+	store.MongoStore.WriteBehindInterval = 50 * time.Millisecond
+
+	// foo must be a string, an update setting a number fails for good
+	err := store.MongoStore.Collection.Database().RunCommand(context.TODO(), bson.D{
+		{Key: "collMod", Value: "sessions_writebehind_test"},
+		{Key: "validator", Value: bson.M{"$jsonSchema": bson.M{
+			"properties": bson.M{"data": bson.M{"properties": bson.M{"foo": bson.M{"bsonType": "string"}}}},
+		}}},
+	}).Err()
+	if err != nil {
+		t.Fatalf("failed to add validator: %v\n", err)
+	}
+
+	var cookies []string
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to create new session: %v\n", err)
+		}
+		session.Values["foo"] = "bar"
+		res := httptest.NewRecorder()
+		err = store.Save(req, res, session)
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+		cookies = append(cookies, res.Header().Get("Set-Cookie"))
+	}
+
+	load := func(cookie string) *sessions.Session {
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		req.Header.Set("Cookie", cookie)
+		session, err := store.New(req, "test-session")
+		if err != nil {
+			t.Fatalf("failed to load session: %v\n", err)
+		}
+		return session
+	}
+
+	// one update is rejected by the validator, the other is written
+	for i, value := range []interface{}{1, "queued"} {
+		session := load(cookies[i])
+		session.Values["foo"] = value
+		req, _ := http.NewRequest("GET", "http://localhost:8080/", nil)
+		err = store.Save(req, httptest.NewRecorder(), session)
+		if err != nil {
+			t.Fatalf("failed to save session: %v\n", err)
+		}
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	// the rejected update is dropped instead of being queued again
+	if foo := load(cookies[0]).Values["foo"]; foo != "bar" {
+		t.Fatalf("expected the rejected update to be dropped, got %v", foo)
+	}
+	if foo := load(cookies[1]).Values["foo"]; foo != "queued" {
+		t.Fatalf("expected the update to be written, got %v", foo)
+	}
+}